@@ -121,22 +121,23 @@ func AdjustContrast(img image.Image, percentage float64) *image.NRGBA {
 		return Clone(img)
 	}
 
-	percentage = math.Min(math.Max(percentage, -100.0), 100.0)
-	lut := make([]uint8, 256)
+	return adjustLUT(img, makeLUT(contrastCurve(percentage)))
+}
 
+// contrastCurve returns the function adjusting the normalized color values for AdjustContrast.
+func contrastCurve(percentage float64) func(float64) float64 {
+	percentage = math.Min(math.Max(percentage, -100.0), 100.0)
 	v := (100.0 + percentage) / 100.0
-	for i := 0; i < 256; i++ {
+	return func(x float64) float64 {
 		switch {
 		case 0 <= v && v <= 1:
-			lut[i] = clamp((0.5 + (float64(i)/255.0-0.5)*v) * 255.0)
+			return 0.5 + (x-0.5)*v
 		case 1 < v && v < 2:
-			lut[i] = clamp((0.5 + (float64(i)/255.0-0.5)*(1/(2.0-v))) * 255.0)
+			return 0.5 + (x-0.5)*(1/(2.0-v))
 		default:
-			lut[i] = uint8(float64(i)/255.0+0.5) * 255
+			return math.Floor(x + 0.5)
 		}
 	}
-
-	return adjustLUT(img, lut)
 }
 
 // AdjustBrightness changes the brightness of the image using the percentage parameter and returns the adjusted image.
@@ -175,14 +176,15 @@ func AdjustGamma(img image.Image, gamma float64) *image.NRGBA {
 		return Clone(img)
 	}
 
-	e := 1.0 / math.Max(gamma, 0.0001)
-	lut := make([]uint8, 256)
+	return adjustLUT(img, makeLUT(gammaCurve(gamma)))
+}
 
-	for i := 0; i < 256; i++ {
-		lut[i] = clamp(math.Pow(float64(i)/255.0, e) * 255.0)
+// gammaCurve returns the function adjusting the normalized color values for AdjustGamma.
+func gammaCurve(gamma float64) func(float64) float64 {
+	e := 1.0 / math.Max(gamma, 0.0001)
+	return func(x float64) float64 {
+		return math.Pow(x, e)
 	}
-
-	return adjustLUT(img, lut)
 }
 
 // AdjustSigmoid changes the contrast of the image using a sigmoidal function and returns the adjusted image.
@@ -200,7 +202,11 @@ func AdjustSigmoid(img image.Image, midpoint, factor float64) *image.NRGBA {
 		return Clone(img)
 	}
 
-	lut := make([]uint8, 256)
+	return adjustLUT(img, makeLUT(sigmoidCurve(midpoint, factor)))
+}
+
+// sigmoidCurve returns the function adjusting the normalized color values for AdjustSigmoid.
+func sigmoidCurve(midpoint, factor float64) func(float64) float64 {
 	a := math.Min(math.Max(midpoint, 0.0), 1.0)
 	b := math.Abs(factor)
 	sig0 := sigmoid(a, b, 0)
@@ -208,28 +214,30 @@ func AdjustSigmoid(img image.Image, midpoint, factor float64) *image.NRGBA {
 	e := 1.0e-6
 
 	if factor > 0 {
-		for i := 0; i < 256; i++ {
-			x := float64(i) / 255.0
+		return func(x float64) float64 {
 			sigX := sigmoid(a, b, x)
-			f := (sigX - sig0) / (sig1 - sig0)
-			lut[i] = clamp(f * 255.0)
-		}
-	} else {
-		for i := 0; i < 256; i++ {
-			x := float64(i) / 255.0
-			arg := math.Min(math.Max((sig1-sig0)*x+sig0, e), 1.0-e)
-			f := a - math.Log(1.0/arg-1.0)/b
-			lut[i] = clamp(f * 255.0)
+			return (sigX - sig0) / (sig1 - sig0)
 		}
 	}
-
-	return adjustLUT(img, lut)
+	return func(x float64) float64 {
+		arg := math.Min(math.Max((sig1-sig0)*x+sig0, e), 1.0-e)
+		return a - math.Log(1.0/arg-1.0)/b
+	}
 }
 
 func sigmoid(a, b, x float64) float64 {
 	return 1 / (1 + math.Exp(b*(a-x)))
 }
 
+// makeLUT returns the lookup table of the function adjusting the normalized color values.
+func makeLUT(fn func(float64) float64) []uint8 {
+	lut := make([]uint8, 256)
+	for i := range lut {
+		lut[i] = clamp(fn(float64(i)/255.0) * 255.0)
+	}
+	return lut
+}
+
 // adjustLUT applies the given lookup table to the colors of the image.
 func adjustLUT(img image.Image, lut []uint8) *image.NRGBA {
 	src := newScanner(img)
@@ -290,3 +298,153 @@ func AdjustFunc(img image.Image, fn func(c color.NRGBA) color.NRGBA) *image.NRGB
 	})
 	return dst
 }
+
+// AdjustSaturation16 is like AdjustSaturation but processes the image with 16 bits per channel
+// and returns an *image.NRGBA64, preserving the precision of 16-bit images.
+func AdjustSaturation16(img image.Image, percentage float64) *image.NRGBA64 {
+	if percentage == 0 {
+		return Clone16(img)
+	}
+
+	percentage = math.Min(math.Max(percentage, -100), 100)
+	multiplier := 1 + percentage/100
+
+	return AdjustFunc16(img, func(c color.NRGBA64) color.NRGBA64 {
+		h, s, l := rgb16ToHSL(c.R, c.G, c.B)
+		s *= multiplier
+		if s > 1 {
+			s = 1
+		}
+		r, g, b := hslToRGB16(h, s, l)
+		return color.NRGBA64{r, g, b, c.A}
+	})
+}
+
+// AdjustHue16 is like AdjustHue but processes the image with 16 bits per channel
+// and returns an *image.NRGBA64, preserving the precision of 16-bit images.
+func AdjustHue16(img image.Image, shift float64) *image.NRGBA64 {
+	if math.Mod(shift, 360) == 0 {
+		return Clone16(img)
+	}
+
+	summand := shift / 360
+
+	return AdjustFunc16(img, func(c color.NRGBA64) color.NRGBA64 {
+		h, s, l := rgb16ToHSL(c.R, c.G, c.B)
+		h += summand
+		h = math.Mod(h, 1)
+		if h < 0 {
+			h++
+		}
+		r, g, b := hslToRGB16(h, s, l)
+		return color.NRGBA64{r, g, b, c.A}
+	})
+}
+
+// AdjustContrast16 is like AdjustContrast but processes the image with 16 bits per channel
+// and returns an *image.NRGBA64, preserving the precision of 16-bit images.
+func AdjustContrast16(img image.Image, percentage float64) *image.NRGBA64 {
+	if percentage == 0 {
+		return Clone16(img)
+	}
+
+	return adjustLUT16(img, makeLUT16(contrastCurve(percentage)))
+}
+
+// AdjustBrightness16 is like AdjustBrightness but processes the image with 16 bits per channel
+// and returns an *image.NRGBA64, preserving the precision of 16-bit images.
+func AdjustBrightness16(img image.Image, percentage float64) *image.NRGBA64 {
+	if percentage == 0 {
+		return Clone16(img)
+	}
+
+	percentage = math.Min(math.Max(percentage, -100.0), 100.0)
+	lut := make([]uint16, 0x10000)
+
+	shift := 0xffff * percentage / 100.0
+	for i := range lut {
+		lut[i] = clamp16(float64(i) + shift)
+	}
+
+	return adjustLUT16(img, lut)
+}
+
+// AdjustGamma16 is like AdjustGamma but processes the image with 16 bits per channel
+// and returns an *image.NRGBA64, preserving the precision of 16-bit images.
+func AdjustGamma16(img image.Image, gamma float64) *image.NRGBA64 {
+	if gamma == 1 {
+		return Clone16(img)
+	}
+
+	return adjustLUT16(img, makeLUT16(gammaCurve(gamma)))
+}
+
+// AdjustSigmoid16 is like AdjustSigmoid but processes the image with 16 bits per channel
+// and returns an *image.NRGBA64, preserving the precision of 16-bit images.
+func AdjustSigmoid16(img image.Image, midpoint, factor float64) *image.NRGBA64 {
+	if factor == 0 {
+		return Clone16(img)
+	}
+
+	return adjustLUT16(img, makeLUT16(sigmoidCurve(midpoint, factor)))
+}
+
+// makeLUT16 returns the 16-bit lookup table of the function adjusting the normalized color values.
+func makeLUT16(fn func(float64) float64) []uint16 {
+	lut := make([]uint16, 0x10000)
+	for i := range lut {
+		lut[i] = clamp16(fn(float64(i)/0xffff) * 0xffff)
+	}
+	return lut
+}
+
+// adjustLUT16 applies the given 16-bit lookup table to the colors of the image.
+func adjustLUT16(img image.Image, lut []uint16) *image.NRGBA64 {
+	src := newScanner(img)
+	dst := image.NewNRGBA64(image.Rect(0, 0, src.w, src.h))
+	lut = lut[0:0x10000]
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * dst.Stride
+			src.scan16(0, y, src.w, y+1, dst.Pix[i:i+src.w*8])
+			for x := 0; x < src.w; x++ {
+				d := dst.Pix[i : i+8 : i+8]
+				r, g, b, a := pixel16(d)
+				setPixel16(d, lut[r], lut[g], lut[b], a)
+				i += 8
+			}
+		}
+	})
+	return dst
+}
+
+// AdjustFunc16 is like AdjustFunc but applies the fn function to the 16-bit colors
+// of the image and returns an *image.NRGBA64.
+//
+// Example:
+//
+//	dstImage = imaging.AdjustFunc16(
+//		srcImage,
+//		func(c color.NRGBA64) color.NRGBA64 {
+//			// Halve the blue channel.
+//			return color.NRGBA64{c.R, c.G, c.B / 2, c.A}
+//		}
+//	)
+func AdjustFunc16(img image.Image, fn func(c color.NRGBA64) color.NRGBA64) *image.NRGBA64 {
+	src := newScanner(img)
+	dst := image.NewNRGBA64(image.Rect(0, 0, src.w, src.h))
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * dst.Stride
+			src.scan16(0, y, src.w, y+1, dst.Pix[i:i+src.w*8])
+			for x := 0; x < src.w; x++ {
+				d := dst.Pix[i : i+8 : i+8]
+				r, g, b, a := pixel16(d)
+				c := fn(color.NRGBA64{r, g, b, a})
+				setPixel16(d, c.R, c.G, c.B, c.A)
+				i += 8
+			}
+		}
+	})
+	return dst
+}
@@ -1102,3 +1102,108 @@ func BenchmarkAdjustFunc(b *testing.B) {
 		})
 	}
 }
+
+func TestAdjust16(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		fn16  func(image.Image) *image.NRGBA64
+		fn    func(image.Image) *image.NRGBA
+		delta int
+	}{
+		{
+			"AdjustSaturation",
+			func(img image.Image) *image.NRGBA64 { return AdjustSaturation16(img, 30) },
+			func(img image.Image) *image.NRGBA { return AdjustSaturation(img, 30) },
+			1,
+		},
+		{
+			"AdjustHue",
+			func(img image.Image) *image.NRGBA64 { return AdjustHue16(img, -60) },
+			func(img image.Image) *image.NRGBA { return AdjustHue(img, -60) },
+			1,
+		},
+		{
+			"AdjustContrast",
+			func(img image.Image) *image.NRGBA64 { return AdjustContrast16(img, 40) },
+			func(img image.Image) *image.NRGBA { return AdjustContrast(img, 40) },
+			1,
+		},
+		{
+			"AdjustContrast -100",
+			func(img image.Image) *image.NRGBA64 { return AdjustContrast16(img, -100) },
+			func(img image.Image) *image.NRGBA { return AdjustContrast(img, -100) },
+			1,
+		},
+		{
+			"AdjustBrightness",
+			func(img image.Image) *image.NRGBA64 { return AdjustBrightness16(img, -20) },
+			func(img image.Image) *image.NRGBA { return AdjustBrightness(img, -20) },
+			1,
+		},
+		{
+			"AdjustGamma",
+			func(img image.Image) *image.NRGBA64 { return AdjustGamma16(img, 0.7) },
+			func(img image.Image) *image.NRGBA { return AdjustGamma(img, 0.7) },
+			1,
+		},
+		{
+			"AdjustSigmoid",
+			func(img image.Image) *image.NRGBA64 { return AdjustSigmoid16(img, 0.5, 3) },
+			func(img image.Image) *image.NRGBA { return AdjustSigmoid(img, 0.5, 3) },
+			1,
+		},
+		{
+			"AdjustSigmoid negative",
+			func(img image.Image) *image.NRGBA64 { return AdjustSigmoid16(img, 0.5, -3) },
+			func(img image.Image) *image.NRGBA { return AdjustSigmoid(img, 0.5, -3) },
+			1,
+		},
+		{
+			"AdjustFunc",
+			func(img image.Image) *image.NRGBA64 {
+				return AdjustFunc16(img, func(c color.NRGBA64) color.NRGBA64 {
+					return color.NRGBA64{c.B, c.G, c.R, 0xffff - c.A}
+				})
+			},
+			func(img image.Image) *image.NRGBA {
+				return AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+					return color.NRGBA{c.B, c.G, c.R, 0xff - c.A}
+				})
+			},
+			0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			got := Clone(tc.fn16(testdataFlowersSmallPNG))
+			want := tc.fn(testdataFlowersSmallPNG)
+			if !compareNRGBA(got, want, tc.delta) {
+				t.Fatal("16-bit result does not match the 8-bit result")
+			}
+
+			src := makeGradient16(1024, 1)
+			got16 := tc.fn16(src)
+			if got16.Rect != src.Rect {
+				t.Fatalf("got bounds %v want %v", got16.Rect, src.Rect)
+			}
+		})
+	}
+
+	src := makeGradient16(1024, 1)
+	for _, img := range []*image.NRGBA64{
+		AdjustGamma16(src, 1.5),
+		AdjustContrast16(src, 10),
+		AdjustBrightness16(src, 10),
+		AdjustSigmoid16(src, 0.5, 2),
+		AdjustSaturation16(src, 0),
+	} {
+		if levels := countLevels16(img); levels < 1000 {
+			t.Fatalf("got %d levels of the 16-bit gradient, want at least 1000", levels)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// Animation is a sequence of frames decoded from an animated image.
+//
+// Frames are fully composited: every frame is the complete image that is
+// displayed at that point of the animation, so any image processing function
+// can be applied to it directly.
+type Animation struct {
+	// Frames are the composited frames of the animation.
+	Frames []*image.NRGBA
+	// Delays are the successive delay times, one per frame, in 100ths of a second.
+	Delays []int
+	// Disposals are the disposal methods of the source frames, one per frame
+	// (gif.DisposalNone, gif.DisposalBackground or gif.DisposalPrevious).
+	// Because Frames are already composited, they only describe the source:
+	// EncodeAnimation chooses the disposal methods that reproduce Frames.
+	Disposals []byte
+	// LoopCount controls the number of times the animation is restarted during display.
+	// A LoopCount of 0 means to loop forever, a LoopCount of -1 means to show each frame
+	// only once. Otherwise, the animation is looped LoopCount+1 times.
+	LoopCount int
+}
+
+// ErrEmptyAnimation means the animation has no frames to encode.
+var ErrEmptyAnimation = errors.New("imaging: animation has no frames")
+
+// OpenAnimation loads an animated image from file.
+// Animated GIF and PNG (APNG) files are decoded into all their frames. Any other image
+// supported by Open is returned as a single-frame animation.
+//
+// Example:
+//
+//	// Load an animated GIF.
+//	anim, err := imaging.OpenAnimation("test.gif")
+//
+//	// Load an animated PNG.
+//	anim, err := imaging.OpenAnimation("test.png")
+func OpenAnimation(filename string, opts ...DecodeOption) (anim *Animation, err error) {
+	file, err := fs.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	return DecodeAnimation(file, opts...)
+}
+
+// DecodeAnimation reads an animated image from io.Reader.
+// Animated GIF and PNG (APNG) images are decoded into all their frames. Any other image
+// supported by Decode is returned as a single-frame animation.
+// The MaxPixels option limits the size of the animation canvas.
+func DecodeAnimation(r io.Reader, opts ...DecodeOption) (*Animation, error) {
+	cfg := defaultDecodeConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+
+	r, lr := cfg.limitInput(r)
+	anim, err := decodeAnimation(r, &cfg)
+	if err != nil && lr != nil && lr.exceeded {
+		return nil, lr.err()
+	}
+	return anim, err
+}
+
+// decodeAnimation reads an animated image from io.Reader using the decode config.
+func decodeAnimation(r io.Reader, cfg *decodeConfig) (*Animation, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(6)
+	if err == nil && (bytes.Equal(header, []byte("GIF87a")) || bytes.Equal(header, []byte("GIF89a"))) {
+		var src io.Reader = br
+		if cfg.maxPixels > 0 {
+			if src, err = limitPixels(br, cfg.maxPixels); err != nil {
+				return nil, err
+			}
+		}
+		g, err := gif.DecodeAll(src)
+		if err != nil {
+			return nil, err
+		}
+		return animationFromGIF(g), nil
+	}
+
+	var src io.Reader = br
+	if header, err := br.Peek(len(pngSignature)); err == nil && string(header) == pngSignature {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		if chunks, err := readPNGChunks(data); err == nil && isAPNG(chunks) {
+			return decodeAPNG(chunks, cfg.maxPixels)
+		}
+		src = bytes.NewReader(data)
+	}
+
+	img, err := decode(src, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Animation{
+		Frames:    []*image.NRGBA{Clone(img)},
+		Delays:    []int{0},
+		Disposals: []byte{gif.DisposalNone},
+	}, nil
+}
+
+// animationFromGIF composites the frames of the decoded GIF.
+func animationFromGIF(g *gif.GIF) *Animation {
+	anim := &Animation{
+		Frames:    make([]*image.NRGBA, len(g.Image)),
+		Delays:    make([]int, len(g.Image)),
+		Disposals: make([]byte, len(g.Image)),
+		LoopCount: g.LoopCount,
+	}
+	copy(anim.Delays, g.Delay)
+	copy(anim.Disposals, g.Disposal)
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	for i, frame := range g.Image {
+		var previous *image.NRGBA
+		if anim.Disposals[i] == gif.DisposalPrevious {
+			previous = copyNRGBA(canvas)
+		}
+
+		drawPaletted(canvas, frame)
+		anim.Frames[i] = copyNRGBA(canvas)
+
+		switch anim.Disposals[i] {
+		case gif.DisposalBackground:
+			// Browsers restore the background as transparent rather than
+			// using the background color, so do the same.
+			r := frame.Bounds().Intersect(canvas.Rect)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				i := canvas.PixOffset(r.Min.X, y)
+				row := canvas.Pix[i : i+r.Dx()*4]
+				for j := range row {
+					row[j] = 0
+				}
+			}
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return anim
+}
+
+// copyNRGBA returns a copy of the image sharing no pixel data with it.
+func copyNRGBA(img *image.NRGBA) *image.NRGBA {
+	return &image.NRGBA{
+		Pix:    append([]uint8(nil), img.Pix...),
+		Stride: img.Stride,
+		Rect:   img.Rect,
+	}
+}
+
+// drawPaletted draws the opaque pixels of the paletted frame over dst.
+func drawPaletted(dst *image.NRGBA, src *image.Paletted) {
+	colors := make([]color.NRGBA, len(src.Palette))
+	for i, c := range src.Palette {
+		colors[i] = color.NRGBAModel.Convert(c).(color.NRGBA)
+	}
+
+	r := src.Rect.Intersect(dst.Rect)
+	parallel(r.Min.Y, r.Max.Y, func(ys <-chan int) {
+		for y := range ys {
+			i := src.PixOffset(r.Min.X, y)
+			j := dst.PixOffset(r.Min.X, y)
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if idx := int(src.Pix[i]); idx < len(colors) && colors[idx].A != 0 {
+					c := colors[idx]
+					d := dst.Pix[j : j+4 : j+4]
+					d[0] = c.R
+					d[1] = c.G
+					d[2] = c.B
+					d[3] = c.A
+				}
+				i++
+				j += 4
+			}
+		}
+	})
+}
+
+// ProcessFrames applies fn to every frame of the animation and returns the
+// resulting animation. Delays, disposals and loop count are preserved.
+//
+// Example:
+//
+//	// Resize every frame of an animated GIF to width = 100px preserving the aspect ratio.
+//	dst := imaging.ProcessFrames(anim, func(img image.Image) *image.NRGBA {
+//		return imaging.Resize(img, 100, 0, imaging.Lanczos)
+//	})
+func ProcessFrames(anim *Animation, fn func(img image.Image) *image.NRGBA) *Animation {
+	dst := &Animation{
+		Frames:    make([]*image.NRGBA, len(anim.Frames)),
+		Delays:    append([]int(nil), anim.Delays...),
+		Disposals: append([]byte(nil), anim.Disposals...),
+		LoopCount: anim.LoopCount,
+	}
+	for i, frame := range anim.Frames {
+		dst.Frames[i] = fn(frame)
+	}
+	return dst
+}
+
+// EncodeAnimation writes the animation to w in the specified format
+// (GIF or PNG). PNG animations are encoded as APNG images using the
+// PNGCompressionLevel option. GIF frames are quantized using the
+// GIFNumColors, GIFQuantizer and GIFDrawer options.
+func EncodeAnimation(w io.Writer, anim *Animation, format Format, opts ...EncodeOption) error {
+	cfg := defaultEncodeConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+
+	if len(anim.Frames) == 0 {
+		return ErrEmptyAnimation
+	}
+
+	switch format {
+	case GIF:
+		return encodeAnimationGIF(w, anim, &cfg)
+	case PNG:
+		return encodeAnimationPNG(w, anim, cfg.pngCompressionLevel)
+	default:
+		return ErrUnsupportedFormat
+	}
+}
+
+// SaveAnimation saves the animation to file with the specified filename.
+// The format is determined from the filename extension: "gif" and "png" are supported.
+//
+// Example:
+//
+//	// Save the animation as GIF.
+//	err := imaging.SaveAnimation(anim, "out.gif")
+func SaveAnimation(anim *Animation, filename string, opts ...EncodeOption) (err error) {
+	f, err := FormatFromFilename(filename)
+	if err != nil {
+		return err
+	}
+	file, err := fs.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = EncodeAnimation(file, anim, f, opts...)
+	errClose := file.Close()
+	if err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// encodeAnimationGIF writes the animation as an animated GIF. Frames that
+// completely cover the previous one are cropped to the area that changed.
+func encodeAnimationGIF(w io.Writer, anim *Animation, cfg *encodeConfig) error {
+	var width, height int
+	for _, frame := range anim.Frames {
+		if frame.Rect.Dx() > width {
+			width = frame.Rect.Dx()
+		}
+		if frame.Rect.Dy() > height {
+			height = frame.Rect.Dy()
+		}
+	}
+	canvas := image.Rect(0, 0, width, height)
+
+	// Smaller frames are padded with transparent pixels to the size of the canvas,
+	// so that all the frames can be compared with the previous one.
+	frames := make([]*image.NRGBA, len(anim.Frames))
+	opaque := make([]bool, len(anim.Frames))
+	for i, frame := range anim.Frames {
+		if frame.Rect.Size().Eq(canvas.Size()) {
+			frames[i] = toNRGBA(frame)
+		} else {
+			frames[i] = image.NewNRGBA(canvas)
+			copyIntoNRGBA(frames[i], frame, image.Point{})
+		}
+		opaque[i] = frames[i].Opaque()
+	}
+
+	g := &gif.GIF{
+		Image:     make([]*image.Paletted, len(frames)),
+		Delay:     make([]int, len(frames)),
+		Disposal:  make([]byte, len(frames)),
+		LoopCount: anim.LoopCount,
+		Config:    image.Config{Width: width, Height: height},
+	}
+	copy(g.Delay, anim.Delays)
+
+	// A frame with transparent pixels must be drawn on a cleared canvas,
+	// otherwise the previous frame would show through.
+	for i := range frames {
+		if opaque[(i+1)%len(frames)] {
+			g.Disposal[i] = gif.DisposalNone
+		} else {
+			g.Disposal[i] = gif.DisposalBackground
+		}
+	}
+
+	for i, frame := range frames {
+		// Only the area that is cleared by the background disposal is
+		// restored, so such frames must not be cropped.
+		r := frame.Rect
+		if i > 0 && g.Disposal[i-1] == gif.DisposalNone && g.Disposal[i] == gif.DisposalNone {
+			r = changedBounds(frames[i-1], frame)
+		}
+		g.Image[i] = quantizeGIFFrame(frame.SubImage(r), cfg)
+	}
+
+	return gif.EncodeAll(w, g)
+}
+
+// changedBounds returns the smallest rectangle containing all the pixels that
+// differ between two images of the same size. If the images are equal,
+// a single pixel rectangle is returned, since a GIF frame cannot be empty.
+func changedBounds(prev, cur *image.NRGBA) image.Rectangle {
+	r := image.Rectangle{}
+	for y := 0; y < cur.Rect.Dy(); y++ {
+		i := y * prev.Stride
+		j := y * cur.Stride
+		for x := 0; x < cur.Rect.Dx(); x++ {
+			if !bytes.Equal(prev.Pix[i:i+4], cur.Pix[j:j+4]) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+			i += 4
+			j += 4
+		}
+	}
+	if r.Empty() {
+		return image.Rect(0, 0, 1, 1)
+	}
+	return r
+}
+
+// quantizeGIFFrame converts the image to a paletted image with at most
+// cfg.gifNumColors colors, using the configured quantizer and drawer.
+// If the image has transparent pixels, one palette entry is reserved for
+// the transparent color.
+func quantizeGIFFrame(img image.Image, cfg *encodeConfig) *image.Paletted {
+	numColors := cfg.gifNumColors
+	if numColors < 1 || numColors > 256 {
+		numColors = 256
+	}
+	drawer := cfg.gifDrawer
+	if drawer == nil {
+		drawer = draw.FloydSteinberg
+	}
+
+	b := img.Bounds()
+	src := newScanner(img)
+	transparent := false
+	scanLine := make([]uint8, src.w*4)
+	for y := 0; y < src.h && !transparent; y++ {
+		src.scan(0, y, src.w, y+1, scanLine)
+		for i := 3; i < len(scanLine); i += 4 {
+			if scanLine[i] < 0x80 {
+				transparent = true
+				break
+			}
+		}
+	}
+	if transparent && numColors > 1 {
+		numColors--
+	}
+
+	var pal color.Palette
+	if cfg.gifQuantizer != nil {
+		pal = cfg.gifQuantizer.Quantize(make(color.Palette, 0, numColors), img)
+	} else {
+		pal = append(color.Palette(nil), palette.Plan9[:numColors]...)
+	}
+	pm := image.NewPaletted(b, pal)
+	drawer.Draw(pm, b, img, b.Min)
+	if !transparent {
+		return pm
+	}
+
+	// Replace the mostly transparent pixels by the transparent color appended to the palette.
+	pm.Palette = append(pm.Palette, color.NRGBA{})
+	transparentIndex := uint8(len(pm.Palette) - 1)
+	for y := 0; y < src.h; y++ {
+		src.scan(0, y, src.w, y+1, scanLine)
+		i := y * pm.Stride
+		for x := 0; x < src.w; x++ {
+			s := scanLine[x*4 : x*4+4 : x*4+4]
+			if s[3] < 0x80 {
+				pm.Pix[i+x] = transparentIndex
+			}
+		}
+	}
+	return pm
+}
@@ -0,0 +1,502 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	animRed   = color.NRGBA{0xff, 0x00, 0x00, 0xff} //nolint:gochecknoglobals
+	animBlue  = color.NRGBA{0x00, 0x00, 0xff, 0xff} //nolint:gochecknoglobals
+	animGreen = color.NRGBA{0x00, 0xff, 0x00, 0xff} //nolint:gochecknoglobals
+	animNone  = color.NRGBA{}                       //nolint:gochecknoglobals
+)
+
+// makePalettedFrame returns a paletted frame of the given bounds filled with c.
+func makePalettedFrame(r image.Rectangle, c color.Color) *image.Paletted {
+	return image.NewPaletted(r, color.Palette{c, color.Transparent})
+}
+
+// makeAnimationGIF encodes the frames as an animated GIF on a 4x4 canvas.
+func makeAnimationGIF(t *testing.T, frames []*image.Paletted, disposals []byte) []byte {
+	t.Helper()
+	g := &gif.GIF{
+		Image:     frames,
+		Delay:     make([]int, len(frames)),
+		Disposal:  disposals,
+		LoopCount: 3,
+		Config:    image.Config{Width: 4, Height: 4},
+	}
+	for i := range g.Delay {
+		g.Delay[i] = 10 * (i + 1)
+	}
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, g); err != nil {
+		t.Fatalf("failed to encode GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// checkPixels checks the pixels of the 4x4 image against the expected colors,
+// given row by row.
+func checkPixels(t *testing.T, img *image.NRGBA, want [16]color.NRGBA) {
+	t.Helper()
+	if img.Rect != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("got bounds %v want %v", img.Rect, image.Rect(0, 0, 4, 4))
+	}
+	for i, c := range want {
+		if got := img.NRGBAAt(i%4, i/4); got != c {
+			t.Fatalf("pixel (%d, %d): got %v want %v", i%4, i/4, got, c)
+		}
+	}
+}
+
+func TestDecodeAnimation(t *testing.T) {
+	t.Parallel()
+
+	r, b, g, n := animRed, animBlue, animGreen, animNone
+
+	testCases := []struct {
+		name      string
+		frames    []*image.Paletted
+		disposals []byte
+		want      [][16]color.NRGBA
+	}{
+		{
+			name: "disposal none",
+			frames: []*image.Paletted{
+				makePalettedFrame(image.Rect(0, 0, 4, 4), r),
+				makePalettedFrame(image.Rect(1, 1, 3, 3), b),
+				makePalettedFrame(image.Rect(0, 0, 1, 1), g),
+			},
+			disposals: []byte{gif.DisposalNone, gif.DisposalNone, gif.DisposalNone},
+			want: [][16]color.NRGBA{
+				{
+					r, r, r, r,
+					r, r, r, r,
+					r, r, r, r,
+					r, r, r, r,
+				},
+				{
+					r, r, r, r,
+					r, b, b, r,
+					r, b, b, r,
+					r, r, r, r,
+				},
+				{
+					g, r, r, r,
+					r, b, b, r,
+					r, b, b, r,
+					r, r, r, r,
+				},
+			},
+		},
+		{
+			name: "disposal background",
+			frames: []*image.Paletted{
+				makePalettedFrame(image.Rect(0, 0, 4, 4), r),
+				makePalettedFrame(image.Rect(1, 1, 3, 3), b),
+				makePalettedFrame(image.Rect(0, 0, 1, 1), g),
+			},
+			disposals: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone},
+			want: [][16]color.NRGBA{
+				{
+					r, r, r, r,
+					r, r, r, r,
+					r, r, r, r,
+					r, r, r, r,
+				},
+				{
+					r, r, r, r,
+					r, b, b, r,
+					r, b, b, r,
+					r, r, r, r,
+				},
+				{
+					g, r, r, r,
+					r, n, n, r,
+					r, n, n, r,
+					r, r, r, r,
+				},
+			},
+		},
+		{
+			name: "disposal previous",
+			frames: []*image.Paletted{
+				makePalettedFrame(image.Rect(0, 0, 2, 4), r),
+				makePalettedFrame(image.Rect(1, 1, 3, 3), b),
+				makePalettedFrame(image.Rect(3, 3, 4, 4), g),
+			},
+			disposals: []byte{gif.DisposalNone, gif.DisposalPrevious, gif.DisposalNone},
+			want: [][16]color.NRGBA{
+				{
+					r, r, n, n,
+					r, r, n, n,
+					r, r, n, n,
+					r, r, n, n,
+				},
+				{
+					r, r, n, n,
+					r, b, b, n,
+					r, b, b, n,
+					r, r, n, n,
+				},
+				{
+					r, r, n, n,
+					r, r, n, n,
+					r, r, n, n,
+					r, r, n, g,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := makeAnimationGIF(t, tc.frames, tc.disposals)
+			anim, err := DecodeAnimation(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("DecodeAnimation: %v", err)
+			}
+			if len(anim.Frames) != len(tc.want) {
+				t.Fatalf("got %d frames want %d", len(anim.Frames), len(tc.want))
+			}
+			for i, want := range tc.want {
+				checkPixels(t, anim.Frames[i], want)
+				if anim.Delays[i] != 10*(i+1) {
+					t.Fatalf("frame %d: got delay %d want %d", i, anim.Delays[i], 10*(i+1))
+				}
+				if anim.Disposals[i] != tc.disposals[i] {
+					t.Fatalf("frame %d: got disposal %d want %d", i, anim.Disposals[i], tc.disposals[i])
+				}
+			}
+			if anim.LoopCount != 3 {
+				t.Fatalf("got loop count %d want 3", anim.LoopCount)
+			}
+		})
+	}
+}
+
+func TestDecodeAnimationSingleFrame(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, testdataBranchesPNG, PNG); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	anim, err := DecodeAnimation(buf)
+	if err != nil {
+		t.Fatalf("DecodeAnimation: %v", err)
+	}
+	if len(anim.Frames) != 1 || len(anim.Delays) != 1 || len(anim.Disposals) != 1 {
+		t.Fatalf("got %d frames want 1", len(anim.Frames))
+	}
+	if !compareNRGBA(anim.Frames[0], Clone(testdataBranchesPNG), 0) {
+		t.Fatal("decoded frame differs from the original image")
+	}
+
+	if _, err := DecodeAnimation(bytes.NewReader([]byte("GIF89a garbage"))); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
+
+func TestEncodeAnimation(t *testing.T) {
+	t.Parallel()
+
+	r, b, g, n := animRed, animBlue, animGreen, animNone
+
+	want := [][16]color.NRGBA{
+		{
+			r, r, r, r,
+			r, r, r, r,
+			r, r, r, r,
+			r, r, r, r,
+		},
+		{
+			r, r, r, r,
+			r, b, b, r,
+			r, b, b, r,
+			r, r, r, r,
+		},
+		{
+			n, n, n, n,
+			n, g, g, n,
+			n, g, g, n,
+			n, n, n, n,
+		},
+		{
+			b, b, b, b,
+			b, b, b, b,
+			b, b, b, b,
+			b, b, b, g,
+		},
+		{
+			b, b, b, b,
+			b, b, b, b,
+			b, b, b, b,
+			b, b, b, g,
+		},
+		{
+			b, b, b, b,
+			b, b, b, b,
+			b, r, b, b,
+			b, b, b, g,
+		},
+	}
+	anim := &Animation{
+		Delays:    []int{1, 2, 3, 4, 5, 6},
+		LoopCount: -1,
+	}
+	for _, pixels := range want {
+		frame := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+		for i, c := range pixels {
+			frame.SetNRGBA(i%4, i/4, c)
+		}
+		anim.Frames = append(anim.Frames, frame)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := EncodeAnimation(buf, anim, GIF); err != nil {
+		t.Fatalf("EncodeAnimation: %v", err)
+	}
+	data := buf.Bytes()
+
+	decoded, err := DecodeAnimation(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeAnimation: %v", err)
+	}
+	if len(decoded.Frames) != len(want) {
+		t.Fatalf("got %d frames want %d", len(decoded.Frames), len(want))
+	}
+	for i := range want {
+		checkPixels(t, decoded.Frames[i], want[i])
+		if decoded.Delays[i] != anim.Delays[i] {
+			t.Fatalf("frame %d: got delay %d want %d", i, decoded.Delays[i], anim.Delays[i])
+		}
+	}
+	if decoded.LoopCount != -1 {
+		t.Fatalf("got loop count %d want -1", decoded.LoopCount)
+	}
+
+	// Frames are cropped to the changed area unless the next frame is
+	// transparent and they must be cleared.
+	g2, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gif.DecodeAll: %v", err)
+	}
+	wantBounds := []image.Rectangle{
+		image.Rect(0, 0, 4, 4),
+		image.Rect(0, 0, 4, 4),
+		image.Rect(0, 0, 4, 4),
+		image.Rect(0, 0, 4, 4),
+		image.Rect(0, 0, 1, 1),
+		image.Rect(1, 2, 2, 3),
+	}
+	for i, want := range wantBounds {
+		if got := g2.Image[i].Bounds(); got != want {
+			t.Fatalf("frame %d: got bounds %v want %v", i, got, want)
+		}
+	}
+}
+
+func TestEncodeAnimationMixedSizes(t *testing.T) {
+	t.Parallel()
+
+	changed := New(20, 20, animBlue)
+	changed.SetNRGBA(5, 15, animRed)
+	anim := &Animation{
+		Frames: []*image.NRGBA{
+			New(10, 10, animRed),
+			New(20, 20, animBlue),
+			changed,
+		},
+		Delays:    []int{1, 1, 1},
+		Disposals: []byte{gif.DisposalNone, gif.DisposalNone, gif.DisposalNone},
+	}
+	for _, format := range []Format{GIF, PNG} {
+		buf := &bytes.Buffer{}
+		if err := EncodeAnimation(buf, anim, format); err != nil {
+			t.Fatalf("%v: EncodeAnimation: %v", format, err)
+		}
+		decoded, err := DecodeAnimation(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%v: DecodeAnimation: %v", format, err)
+		}
+		if len(decoded.Frames) != 3 {
+			t.Fatalf("%v: got %d frames want 3", format, len(decoded.Frames))
+		}
+
+		// The smaller frame is padded with transparent pixels.
+		first := decoded.Frames[0]
+		if first.Rect != image.Rect(0, 0, 20, 20) {
+			t.Fatalf("%v: got bounds %v want 20x20", format, first.Rect)
+		}
+		if c := first.NRGBAAt(5, 5); c != animRed {
+			t.Fatalf("%v: got color %v inside the first frame want red", format, c)
+		}
+		if c := first.NRGBAAt(15, 15); c.A != 0 {
+			t.Fatalf("%v: got color %v outside the first frame want transparent", format, c)
+		}
+		if !compareNRGBA(decoded.Frames[1], anim.Frames[1], 0) {
+			t.Fatalf("%v: second frame differs from the original", format)
+		}
+		if !compareNRGBA(decoded.Frames[2], anim.Frames[2], 0) {
+			t.Fatalf("%v: third frame differs from the original", format)
+		}
+	}
+}
+
+func TestEncodeAnimationOptions(t *testing.T) {
+	t.Parallel()
+
+	anim := &Animation{
+		Frames: []*image.NRGBA{
+			Clone(testdataFlowersSmallPNG),
+			AdjustBrightness(testdataFlowersSmallPNG, 20),
+		},
+	}
+	pal := []color.Color{animRed, animGreen, animBlue}
+
+	buf := &bytes.Buffer{}
+	err := EncodeAnimation(buf, anim, GIF, GIFNumColors(2), GIFQuantizer(quantizer{pal}), GIFDrawer(nil))
+	if err != nil {
+		t.Fatalf("EncodeAnimation: %v", err)
+	}
+	g, err := gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatalf("gif.DecodeAll: %v", err)
+	}
+	for i, frame := range g.Image {
+		if len(frame.Palette) != 2 {
+			t.Fatalf("frame %d: got %d colors want 2", i, len(frame.Palette))
+		}
+		for j, c := range frame.Palette {
+			if !compareColors(c, pal[j]) {
+				t.Fatalf("frame %d: got color %v want %v", i, c, pal[j])
+			}
+		}
+	}
+}
+
+func TestEncodeAnimationErrors(t *testing.T) {
+	t.Parallel()
+
+	if err := EncodeAnimation(&bytes.Buffer{}, &Animation{}, GIF); !errors.Is(err, ErrEmptyAnimation) {
+		t.Fatalf("got error %v want %v", err, ErrEmptyAnimation)
+	}
+	anim := &Animation{Frames: []*image.NRGBA{Clone(testdataBranchesPNG)}}
+	if err := EncodeAnimation(&bytes.Buffer{}, anim, JPEG); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want %v", err, ErrUnsupportedFormat)
+	}
+}
+
+func TestProcessFrames(t *testing.T) {
+	t.Parallel()
+
+	r, b := animRed, animBlue
+	data := makeAnimationGIF(t, []*image.Paletted{
+		makePalettedFrame(image.Rect(0, 0, 4, 4), r),
+		makePalettedFrame(image.Rect(0, 0, 2, 4), b),
+	}, []byte{gif.DisposalNone, gif.DisposalBackground})
+	anim, err := DecodeAnimation(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeAnimation: %v", err)
+	}
+
+	dst := ProcessFrames(anim, func(img image.Image) *image.NRGBA {
+		return Resize(img, 2, 2, NearestNeighbor)
+	})
+	if len(dst.Frames) != 2 {
+		t.Fatalf("got %d frames want 2", len(dst.Frames))
+	}
+	want := [][]uint8{
+		{
+			0xff, 0x00, 0x00, 0xff, 0xff, 0x00, 0x00, 0xff,
+			0xff, 0x00, 0x00, 0xff, 0xff, 0x00, 0x00, 0xff,
+		},
+		{
+			0x00, 0x00, 0xff, 0xff, 0xff, 0x00, 0x00, 0xff,
+			0x00, 0x00, 0xff, 0xff, 0xff, 0x00, 0x00, 0xff,
+		},
+	}
+	for i, frame := range dst.Frames {
+		if frame.Rect != image.Rect(0, 0, 2, 2) || !compareBytes(frame.Pix, want[i], 0) {
+			t.Fatalf("frame %d: got %v %v want %v", i, frame.Rect, frame.Pix, want[i])
+		}
+	}
+	if dst.Delays[1] != anim.Delays[1] || dst.Disposals[1] != anim.Disposals[1] || dst.LoopCount != anim.LoopCount {
+		t.Fatal("frame timing is not preserved")
+	}
+
+	// The source animation is left unchanged.
+	if anim.Frames[0].Rect != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("got source bounds %v want %v", anim.Frames[0].Rect, image.Rect(0, 0, 4, 4))
+	}
+}
+
+func TestOpenSaveAnimation(t *testing.T) {
+	t.Parallel()
+
+	data := makeAnimationGIF(t, []*image.Paletted{
+		makePalettedFrame(image.Rect(0, 0, 4, 4), animRed),
+		makePalettedFrame(image.Rect(1, 1, 3, 3), animBlue),
+	}, []byte{gif.DisposalNone, gif.DisposalNone})
+	anim, err := DecodeAnimation(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeAnimation: %v", err)
+	}
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "out.gif")
+	if err := SaveAnimation(anim, filename); err != nil {
+		t.Fatalf("SaveAnimation: %v", err)
+	}
+	got, err := OpenAnimation(filename)
+	if err != nil {
+		t.Fatalf("OpenAnimation: %v", err)
+	}
+	if len(got.Frames) != 2 {
+		t.Fatalf("got %d frames want 2", len(got.Frames))
+	}
+	for i := range got.Frames {
+		if !compareNRGBA(got.Frames[i], anim.Frames[i], 0) {
+			t.Fatalf("frame %d differs after round trip", i)
+		}
+	}
+
+	if err := SaveAnimation(anim, filepath.Join(dir, "out.xyz")); err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if _, err := OpenAnimation(filepath.Join(dir, "missing.gif")); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
+
+func BenchmarkEncodeAnimation(b *testing.B) {
+	anim := &Animation{}
+	for i := 0; i < 5; i++ {
+		anim.Frames = append(anim.Frames, AdjustBrightness(testdataFlowersSmallPNG, float64(i*5)))
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := EncodeAnimation(&bytes.Buffer{}, anim, GIF); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// compareColors reports whether the colors are equal regardless of their type.
+func compareColors(c1, c2 color.Color) bool {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
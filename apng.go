@@ -0,0 +1,506 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/gif"
+	"image/png"
+	"io"
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// APNG frame disposal and blending operations.
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+
+	apngBlendSource = 0
+	apngBlendOver   = 1
+)
+
+// errInvalidAPNG is returned when the APNG chunks are malformed.
+var errInvalidAPNG = errors.New("imaging: invalid APNG image")
+
+// pngChunk is a raw PNG chunk.
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// apngFrame is a frame of an APNG image as described by its fcTL chunk.
+type apngFrame struct {
+	rect     image.Rectangle
+	delayNum uint16
+	delayDen uint16
+	dispose  byte
+	blend    byte
+	data     [][]byte
+}
+
+// readPNGChunks reads the chunks of the PNG image following the signature,
+// up to and including IEND.
+func readPNGChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, []byte(pngSignature)) {
+		return nil, errInvalidAPNG
+	}
+	data = data[len(pngSignature):]
+
+	var chunks []pngChunk
+	for {
+		if len(data) < 12 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		n := binary.BigEndian.Uint32(data[:4])
+		if uint64(n) > uint64(len(data)-12) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		typ := data[4:8]
+		chunkData := data[8 : 8+n]
+		crc := binary.BigEndian.Uint32(data[8+n : 12+n])
+		if crc32.Update(crc32.ChecksumIEEE(typ), crc32.IEEETable, chunkData) != crc {
+			return nil, errInvalidAPNG
+		}
+		chunks = append(chunks, pngChunk{typ: string(typ), data: chunkData})
+		data = data[12+n:]
+		if string(typ) == "IEND" {
+			return chunks, nil
+		}
+	}
+}
+
+// isAPNG reports whether the PNG chunks contain an animation control chunk.
+func isAPNG(chunks []pngChunk) bool {
+	for _, c := range chunks {
+		switch c.typ {
+		case "acTL":
+			return true
+		case "IDAT":
+			return false
+		}
+	}
+	return false
+}
+
+// decodeAPNG decodes all the frames of the APNG image and composites them.
+// The default image is skipped if it is not part of the animation.
+// If maxPixels is positive, larger canvases are rejected with ErrImageTooLarge.
+func decodeAPNG(chunks []pngChunk, maxPixels int64) (*Animation, error) {
+	var (
+		ihdr     []byte
+		header   []pngChunk
+		frames   []*apngFrame
+		numPlays uint32
+		seenIDAT bool
+	)
+	for _, c := range chunks {
+		switch c.typ {
+		case "IHDR":
+			if len(c.data) != 13 {
+				return nil, errInvalidAPNG
+			}
+			ihdr = c.data
+		case "acTL":
+			if len(c.data) != 8 {
+				return nil, errInvalidAPNG
+			}
+			numPlays = binary.BigEndian.Uint32(c.data[4:8])
+		case "fcTL":
+			if len(c.data) != 26 {
+				return nil, errInvalidAPNG
+			}
+			d := c.data
+			w := binary.BigEndian.Uint32(d[4:8])
+			h := binary.BigEndian.Uint32(d[8:12])
+			x := binary.BigEndian.Uint32(d[12:16])
+			y := binary.BigEndian.Uint32(d[16:20])
+			frames = append(frames, &apngFrame{
+				rect:     image.Rect(int(x), int(y), int(x+w), int(y+h)),
+				delayNum: binary.BigEndian.Uint16(d[20:22]),
+				delayDen: binary.BigEndian.Uint16(d[22:24]),
+				dispose:  d[24],
+				blend:    d[25],
+			})
+		case "IDAT":
+			seenIDAT = true
+			if len(frames) == 1 {
+				frames[0].data = append(frames[0].data, c.data)
+			}
+		case "fdAT":
+			if len(frames) == 0 || len(c.data) < 4 {
+				return nil, errInvalidAPNG
+			}
+			f := frames[len(frames)-1]
+			f.data = append(f.data, c.data[4:])
+		default:
+			if !seenIDAT && c.typ != "IEND" {
+				header = append(header, c)
+			}
+		}
+	}
+	if ihdr == nil || len(frames) == 0 {
+		return nil, errInvalidAPNG
+	}
+
+	width := binary.BigEndian.Uint32(ihdr[0:4])
+	height := binary.BigEndian.Uint32(ihdr[4:8])
+	if width > 1<<31-1 || height > 1<<31-1 {
+		return nil, errInvalidAPNG
+	}
+	if err := checkDimensions(int(width), int(height), maxPixels); err != nil {
+		return nil, err
+	}
+	canvas := image.NewNRGBA(image.Rect(0, 0, int(width), int(height)))
+
+	anim := &Animation{
+		Frames:    make([]*image.NRGBA, len(frames)),
+		Delays:    make([]int, len(frames)),
+		Disposals: make([]byte, len(frames)),
+	}
+	switch numPlays {
+	case 0:
+		anim.LoopCount = 0
+	case 1:
+		anim.LoopCount = -1
+	default:
+		anim.LoopCount = int(numPlays - 1)
+	}
+
+	for i, f := range frames {
+		if f.rect.Empty() || !f.rect.In(canvas.Rect) || len(f.data) == 0 {
+			return nil, errInvalidAPNG
+		}
+		img, err := decodeAPNGFrame(ihdr, header, f)
+		if err != nil {
+			return nil, err
+		}
+
+		dispose := f.dispose
+		if i == 0 && dispose == apngDisposePrevious {
+			dispose = apngDisposeBackground
+		}
+		var previous *image.NRGBA
+		if dispose == apngDisposePrevious {
+			previous = copyNRGBA(canvas)
+		}
+
+		if f.blend == apngBlendOver {
+			blendOverNRGBA(canvas, img, f.rect.Min)
+		} else {
+			copyIntoNRGBA(canvas, img, f.rect.Min)
+		}
+		anim.Frames[i] = copyNRGBA(canvas)
+
+		delayDen := int(f.delayDen)
+		if delayDen == 0 {
+			delayDen = 100
+		}
+		anim.Delays[i] = (int(f.delayNum)*100 + delayDen/2) / delayDen
+
+		switch dispose {
+		case apngDisposeBackground:
+			anim.Disposals[i] = gif.DisposalBackground
+			copyIntoNRGBA(canvas, image.NewNRGBA(image.Rect(0, 0, f.rect.Dx(), f.rect.Dy())), f.rect.Min)
+		case apngDisposePrevious:
+			anim.Disposals[i] = gif.DisposalPrevious
+			canvas = previous
+		default:
+			anim.Disposals[i] = gif.DisposalNone
+		}
+	}
+	return anim, nil
+}
+
+// decodeAPNGFrame decodes the frame data as a standalone PNG image
+// sharing the header chunks of the APNG image.
+func decodeAPNGFrame(ihdr []byte, header []pngChunk, f *apngFrame) (*image.NRGBA, error) {
+	frameIHDR := append([]byte(nil), ihdr...)
+	binary.BigEndian.PutUint32(frameIHDR[0:4], uint32(f.rect.Dx()))
+	binary.BigEndian.PutUint32(frameIHDR[4:8], uint32(f.rect.Dy()))
+
+	buf := []byte(pngSignature)
+	buf = appendPNGChunk(buf, "IHDR", frameIHDR)
+	for _, c := range header {
+		buf = appendPNGChunk(buf, c.typ, c.data)
+	}
+	buf = appendPNGChunk(buf, "IDAT", bytes.Join(f.data, nil))
+	buf = appendPNGChunk(buf, "IEND", nil)
+
+	img, err := png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	return toNRGBA(img), nil
+}
+
+// copyIntoNRGBA replaces the pixels of dst at pos by the pixels of src.
+func copyIntoNRGBA(dst, src *image.NRGBA, pos image.Point) {
+	r := image.Rectangle{Min: pos, Max: pos.Add(src.Rect.Size())}.Intersect(dst.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := dst.PixOffset(r.Min.X, y)
+		j := src.PixOffset(src.Rect.Min.X+r.Min.X-pos.X, src.Rect.Min.Y+y-pos.Y)
+		copy(dst.Pix[i:i+r.Dx()*4], src.Pix[j:j+r.Dx()*4])
+	}
+}
+
+// blendOverNRGBA composites the pixels of src over the pixels of dst at pos.
+func blendOverNRGBA(dst, src *image.NRGBA, pos image.Point) {
+	r := image.Rectangle{Min: pos, Max: pos.Add(src.Rect.Size())}.Intersect(dst.Rect)
+	parallel(r.Min.Y, r.Max.Y, func(ys <-chan int) {
+		for y := range ys {
+			i := dst.PixOffset(r.Min.X, y)
+			j := src.PixOffset(src.Rect.Min.X+r.Min.X-pos.X, src.Rect.Min.Y+y-pos.Y)
+			for x := r.Min.X; x < r.Max.X; x++ {
+				s := src.Pix[j : j+4 : j+4]
+				d := dst.Pix[i : i+4 : i+4]
+				switch s[3] {
+				case 0:
+				case 0xff:
+					copy(d, s)
+				default:
+					sa := uint32(s[3])
+					da := uint32(d[3]) * (0xff - sa) / 0xff
+					a := sa + da
+					d[0] = uint8((uint32(s[0])*sa + uint32(d[0])*da + a/2) / a)
+					d[1] = uint8((uint32(s[1])*sa + uint32(d[1])*da + a/2) / a)
+					d[2] = uint8((uint32(s[2])*sa + uint32(d[2])*da + a/2) / a)
+					d[3] = uint8(a)
+				}
+				i += 4
+				j += 4
+			}
+		}
+	})
+}
+
+// appendPNGChunk appends a PNG chunk with the given type and data to buf.
+func appendPNGChunk(buf []byte, typ string, data []byte) []byte {
+	start := len(buf)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+	buf = append(buf, typ...)
+	buf = append(buf, data...)
+	return binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf[start+4:]))
+}
+
+// writePNGChunk writes a PNG chunk with the given type and data.
+func writePNGChunk(w io.Writer, typ string, data []byte) error {
+	_, err := w.Write(appendPNGChunk(nil, typ, data))
+	return err
+}
+
+// encodeAnimationPNG writes the animation as an APNG image. All the frames
+// but the first one are cropped to the area that changed.
+func encodeAnimationPNG(w io.Writer, anim *Animation, level png.CompressionLevel) error {
+	var width, height int
+	for _, frame := range anim.Frames {
+		if frame.Rect.Dx() > width {
+			width = frame.Rect.Dx()
+		}
+		if frame.Rect.Dy() > height {
+			height = frame.Rect.Dy()
+		}
+	}
+	canvas := image.Rect(0, 0, width, height)
+
+	frames := make([]*image.NRGBA, len(anim.Frames))
+	opaque := true
+	for i, frame := range anim.Frames {
+		if frame.Rect.Size().Eq(canvas.Size()) {
+			frames[i] = toNRGBA(frame)
+		} else {
+			frames[i] = image.NewNRGBA(canvas)
+			copyIntoNRGBA(frames[i], frame, image.Point{})
+		}
+		opaque = opaque && frames[i].Opaque()
+	}
+
+	// Color type 2 is truecolor, 6 is truecolor with alpha.
+	colorType := byte(6)
+	if opaque {
+		colorType = 2
+	}
+
+	numPlays := uint32(0)
+	switch {
+	case anim.LoopCount < 0:
+		numPlays = 1
+	case anim.LoopCount > 0:
+		numPlays = uint32(anim.LoopCount) + 1
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+	ihdr[8] = 8
+	ihdr[9] = colorType
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:4], uint32(len(frames)))
+	binary.BigEndian.PutUint32(actl[4:8], numPlays)
+
+	if _, err := io.WriteString(w, pngSignature); err != nil {
+		return err
+	}
+	if err := writePNGChunk(w, "IHDR", ihdr); err != nil {
+		return err
+	}
+	if err := writePNGChunk(w, "acTL", actl); err != nil {
+		return err
+	}
+
+	seq := uint32(0)
+	for i, frame := range frames {
+		r := frame.Rect
+		if i > 0 {
+			r = changedBounds(frames[i-1], frame)
+		}
+
+		delay := 0
+		if i < len(anim.Delays) {
+			delay = anim.Delays[i]
+			if delay < 0 {
+				delay = 0
+			} else if delay > 0xffff {
+				delay = 0xffff
+			}
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:4], seq)
+		binary.BigEndian.PutUint32(fctl[4:8], uint32(r.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:12], uint32(r.Dy()))
+		binary.BigEndian.PutUint32(fctl[12:16], uint32(r.Min.X))
+		binary.BigEndian.PutUint32(fctl[16:20], uint32(r.Min.Y))
+		binary.BigEndian.PutUint16(fctl[20:22], uint16(delay))
+		binary.BigEndian.PutUint16(fctl[22:24], 100)
+		fctl[24] = apngDisposeNone
+		fctl[25] = apngBlendSource
+		if err := writePNGChunk(w, "fcTL", fctl); err != nil {
+			return err
+		}
+		seq++
+
+		data, err := pngImageData(frame.SubImage(r).(*image.NRGBA), colorType, level)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			err = writePNGChunk(w, "IDAT", data)
+		} else {
+			err = writePNGChunk(w, "fdAT", append(binary.BigEndian.AppendUint32(nil, seq), data...))
+			seq++
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writePNGChunk(w, "IEND", nil)
+}
+
+// pngImageData returns the filtered and compressed 8-bit truecolor data of
+// the image, with alpha if colorType is 6.
+func pngImageData(img *image.NRGBA, colorType byte, level png.CompressionLevel) ([]byte, error) {
+	zlibLevel := zlib.DefaultCompression
+	switch level {
+	case png.NoCompression:
+		zlibLevel = zlib.NoCompression
+	case png.BestSpeed:
+		zlibLevel = zlib.BestSpeed
+	case png.BestCompression:
+		zlibLevel = zlib.BestCompression
+	}
+
+	buf := &bytes.Buffer{}
+	zw, err := zlib.NewWriterLevel(buf, zlibLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	bpp := 4
+	if colorType == 2 {
+		bpp = 3
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	prev := make([]uint8, w*bpp)
+	cur := make([]uint8, w*bpp)
+	var filtered [5][]uint8
+	for i := range filtered {
+		filtered[i] = make([]uint8, 1+w*bpp)
+		filtered[i][0] = uint8(i)
+	}
+
+	for y := 0; y < h; y++ {
+		i := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y)
+		if bpp == 4 {
+			copy(cur, img.Pix[i:i+w*4])
+		} else {
+			for x := 0; x < w; x++ {
+				copy(cur[x*3:x*3+3], img.Pix[i+x*4:i+x*4+3])
+			}
+		}
+
+		row := filtered[0]
+		copy(row[1:], cur)
+		if zlibLevel != zlib.NoCompression {
+			row = pngFilter(cur, prev, bpp, &filtered)
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+		prev, cur = cur, prev
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pngFilter applies the PNG filters to the row and returns the filtered row
+// with the smallest sum of absolute differences, prefixed by the filter type.
+func pngFilter(cur, prev []uint8, bpp int, filtered *[5][]uint8) []uint8 {
+	none, sub, up, avg, paeth := filtered[0][1:], filtered[1][1:], filtered[2][1:], filtered[3][1:], filtered[4][1:]
+	copy(none, cur)
+	for i := range cur {
+		var a, c uint8
+		if i >= bpp {
+			a = cur[i-bpp]
+			c = prev[i-bpp]
+		}
+		b := prev[i]
+		sub[i] = cur[i] - a
+		up[i] = cur[i] - b
+		avg[i] = cur[i] - uint8((int(a)+int(b))/2)
+		paeth[i] = cur[i] - pngPaeth(a, b, c)
+	}
+
+	best, bestSum := 0, -1
+	for i, row := range filtered {
+		sum := 0
+		for _, v := range row[1:] {
+			sum += absInt(int(int8(v)))
+		}
+		if bestSum < 0 || sum < bestSum {
+			best, bestSum = i, sum
+		}
+	}
+	return filtered[best]
+}
+
+// pngPaeth implements the Paeth predictor of the PNG specification.
+func pngPaeth(a, b, c uint8) uint8 {
+	p := int(a) + int(b) - int(c)
+	pa := absInt(p - int(a))
+	pb := absInt(p - int(b))
+	pc := absInt(p - int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	} else if pb <= pc {
+		return b
+	}
+	return c
+}
@@ -0,0 +1,408 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+// apngTestFrame describes a frame of a test APNG image.
+type apngTestFrame struct {
+	img      *image.NRGBA
+	pos      image.Point
+	delayNum uint16
+	delayDen uint16
+	dispose  byte
+	blend    byte
+}
+
+// makeAPNG builds an APNG image with a truecolor with alpha 4x4 canvas.
+// If defaultImage is not nil, it is stored as a default image that is not
+// part of the animation.
+func makeAPNG(t *testing.T, frames []apngTestFrame, numPlays uint32, defaultImage *image.NRGBA) []byte {
+	t.Helper()
+
+	buf := []byte(pngSignature)
+	buf = appendPNGChunk(buf, "IHDR", []byte{0, 0, 0, 4, 0, 0, 0, 4, 8, 6, 0, 0, 0})
+	actl := binary.BigEndian.AppendUint32(nil, uint32(len(frames)))
+	actl = binary.BigEndian.AppendUint32(actl, numPlays)
+	buf = appendPNGChunk(buf, "acTL", actl)
+
+	if defaultImage != nil {
+		data, err := pngImageData(defaultImage, 6, png.DefaultCompression)
+		if err != nil {
+			t.Fatalf("pngImageData: %v", err)
+		}
+		buf = appendPNGChunk(buf, "IDAT", data)
+	}
+
+	seq := uint32(0)
+	for i, f := range frames {
+		fctl := binary.BigEndian.AppendUint32(nil, seq)
+		fctl = binary.BigEndian.AppendUint32(fctl, uint32(f.img.Rect.Dx()))
+		fctl = binary.BigEndian.AppendUint32(fctl, uint32(f.img.Rect.Dy()))
+		fctl = binary.BigEndian.AppendUint32(fctl, uint32(f.pos.X))
+		fctl = binary.BigEndian.AppendUint32(fctl, uint32(f.pos.Y))
+		fctl = binary.BigEndian.AppendUint16(fctl, f.delayNum)
+		fctl = binary.BigEndian.AppendUint16(fctl, f.delayDen)
+		fctl = append(fctl, f.dispose, f.blend)
+		buf = appendPNGChunk(buf, "fcTL", fctl)
+		seq++
+
+		data, err := pngImageData(f.img, 6, png.DefaultCompression)
+		if err != nil {
+			t.Fatalf("pngImageData: %v", err)
+		}
+		if i == 0 && defaultImage == nil {
+			buf = appendPNGChunk(buf, "IDAT", data)
+		} else {
+			buf = appendPNGChunk(buf, "fdAT", append(binary.BigEndian.AppendUint32(nil, seq), data...))
+			seq++
+		}
+	}
+	return appendPNGChunk(buf, "IEND", nil)
+}
+
+func TestDecodeAnimationAPNG(t *testing.T) {
+	t.Parallel()
+
+	r, b, n := animRed, animBlue, animNone
+	h := color.NRGBA{0x00, 0xff, 0x00, 0x80}
+	// Green with alpha 0x80 over blue.
+	o := color.NRGBA{0x00, 0x80, 0x7f, 0xff}
+
+	testCases := []struct {
+		name         string
+		frames       []apngTestFrame
+		defaultImage *image.NRGBA
+		want         [][16]color.NRGBA
+		delays       []int
+		disposals    []byte
+	}{
+		{
+			name: "dispose none",
+			frames: []apngTestFrame{
+				{img: New(4, 4, r), delayNum: 1, delayDen: 10},
+				{img: New(2, 2, b), pos: image.Pt(1, 1), delayNum: 50, delayDen: 1000},
+			},
+			want: [][16]color.NRGBA{
+				{
+					r, r, r, r,
+					r, r, r, r,
+					r, r, r, r,
+					r, r, r, r,
+				},
+				{
+					r, r, r, r,
+					r, b, b, r,
+					r, b, b, r,
+					r, r, r, r,
+				},
+			},
+			delays:    []int{10, 5},
+			disposals: []byte{gif.DisposalNone, gif.DisposalNone},
+		},
+		{
+			name: "dispose background",
+			frames: []apngTestFrame{
+				{img: New(4, 4, r), dispose: apngDisposeBackground, delayNum: 3},
+				{img: New(2, 2, b), pos: image.Pt(2, 2), dispose: apngDisposeBackground, delayNum: 4},
+				{img: New(1, 1, b), delayNum: 5},
+			},
+			want: [][16]color.NRGBA{
+				{
+					r, r, r, r,
+					r, r, r, r,
+					r, r, r, r,
+					r, r, r, r,
+				},
+				{
+					n, n, n, n,
+					n, n, n, n,
+					n, n, b, b,
+					n, n, b, b,
+				},
+				{
+					b, n, n, n,
+					n, n, n, n,
+					n, n, n, n,
+					n, n, n, n,
+				},
+			},
+			delays:    []int{3, 4, 5},
+			disposals: []byte{gif.DisposalBackground, gif.DisposalBackground, gif.DisposalNone},
+		},
+		{
+			name: "dispose previous",
+			frames: []apngTestFrame{
+				{img: New(2, 4, r)},
+				{img: New(2, 2, b), pos: image.Pt(1, 1), dispose: apngDisposePrevious},
+				{img: New(1, 1, b), pos: image.Pt(3, 3)},
+			},
+			want: [][16]color.NRGBA{
+				{
+					r, r, n, n,
+					r, r, n, n,
+					r, r, n, n,
+					r, r, n, n,
+				},
+				{
+					r, r, n, n,
+					r, b, b, n,
+					r, b, b, n,
+					r, r, n, n,
+				},
+				{
+					r, r, n, n,
+					r, r, n, n,
+					r, r, n, n,
+					r, r, n, b,
+				},
+			},
+			delays:    []int{0, 0, 0},
+			disposals: []byte{gif.DisposalNone, gif.DisposalPrevious, gif.DisposalNone},
+		},
+		{
+			name: "blend",
+			frames: []apngTestFrame{
+				{img: New(4, 4, b)},
+				{img: New(2, 1, h), pos: image.Pt(0, 0), blend: apngBlendOver},
+				{img: New(2, 1, h), pos: image.Pt(2, 0), blend: apngBlendSource},
+			},
+			want: [][16]color.NRGBA{
+				{
+					b, b, b, b,
+					b, b, b, b,
+					b, b, b, b,
+					b, b, b, b,
+				},
+				{
+					o, o, b, b,
+					b, b, b, b,
+					b, b, b, b,
+					b, b, b, b,
+				},
+				{
+					o, o, h, h,
+					b, b, b, b,
+					b, b, b, b,
+					b, b, b, b,
+				},
+			},
+			delays:    []int{0, 0, 0},
+			disposals: []byte{gif.DisposalNone, gif.DisposalNone, gif.DisposalNone},
+		},
+		{
+			name: "default image",
+			frames: []apngTestFrame{
+				{img: New(4, 4, b)},
+			},
+			defaultImage: New(4, 4, r),
+			want: [][16]color.NRGBA{
+				{
+					b, b, b, b,
+					b, b, b, b,
+					b, b, b, b,
+					b, b, b, b,
+				},
+			},
+			delays:    []int{0},
+			disposals: []byte{gif.DisposalNone},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := makeAPNG(t, tc.frames, 2, tc.defaultImage)
+			anim, err := DecodeAnimation(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("DecodeAnimation: %v", err)
+			}
+			if len(anim.Frames) != len(tc.want) {
+				t.Fatalf("got %d frames want %d", len(anim.Frames), len(tc.want))
+			}
+			for i, want := range tc.want {
+				checkPixels(t, anim.Frames[i], want)
+				if anim.Delays[i] != tc.delays[i] {
+					t.Fatalf("frame %d: got delay %d want %d", i, anim.Delays[i], tc.delays[i])
+				}
+				if anim.Disposals[i] != tc.disposals[i] {
+					t.Fatalf("frame %d: got disposal %d want %d", i, anim.Disposals[i], tc.disposals[i])
+				}
+			}
+			if anim.LoopCount != 1 {
+				t.Fatalf("got loop count %d want 1", anim.LoopCount)
+			}
+		})
+	}
+}
+
+func TestDecodeAnimationAPNGInvalid(t *testing.T) {
+	t.Parallel()
+
+	outside := makeAPNG(t, []apngTestFrame{{img: New(2, 2, animRed), pos: image.Pt(3, 3)}}, 0, nil)
+	if _, err := DecodeAnimation(bytes.NewReader(outside)); !errors.Is(err, errInvalidAPNG) {
+		t.Fatalf("got error %v want %v", err, errInvalidAPNG)
+	}
+
+	truncated := makeAPNG(t, []apngTestFrame{{img: New(4, 4, animRed)}}, 0, nil)
+	truncated = truncated[:len(truncated)-20]
+	if _, err := DecodeAnimation(bytes.NewReader(truncated)); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
+
+func TestEncodeAnimationPNG(t *testing.T) {
+	t.Parallel()
+
+	frames := []*image.NRGBA{
+		Clone(testdataFlowersSmallPNG),
+		AdjustBrightness(testdataFlowersSmallPNG, 30),
+		Clone(testdataFlowersSmallPNG),
+		Clone(testdataFlowersSmallPNG),
+	}
+	frames[2].SetNRGBA(10, 20, color.NRGBA{1, 2, 3, 4})
+	frames[2].SetNRGBA(30, 5, color.NRGBA{})
+
+	testCases := []struct {
+		name      string
+		frames    []*image.NRGBA
+		loopCount int
+		level     png.CompressionLevel
+		colorType byte
+	}{
+		{
+			name:      "transparent",
+			frames:    frames,
+			loopCount: 0,
+			level:     png.DefaultCompression,
+			colorType: 6,
+		},
+		{
+			name:      "opaque",
+			frames:    []*image.NRGBA{frames[0], frames[1]},
+			loopCount: -1,
+			level:     png.BestSpeed,
+			colorType: 2,
+		},
+		{
+			name:      "no compression",
+			frames:    frames,
+			loopCount: 5,
+			level:     png.NoCompression,
+			colorType: 6,
+		},
+		{
+			name:      "best compression",
+			frames:    []*image.NRGBA{frames[1]},
+			loopCount: 0,
+			level:     png.BestCompression,
+			colorType: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			anim := &Animation{Frames: tc.frames, LoopCount: tc.loopCount}
+			for i := range tc.frames {
+				anim.Delays = append(anim.Delays, i*7)
+			}
+
+			buf := &bytes.Buffer{}
+			if err := EncodeAnimation(buf, anim, PNG, PNGCompressionLevel(tc.level)); err != nil {
+				t.Fatalf("EncodeAnimation: %v", err)
+			}
+			data := buf.Bytes()
+			if data[25] != tc.colorType {
+				t.Fatalf("got color type %d want %d", data[25], tc.colorType)
+			}
+
+			decoded, err := DecodeAnimation(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("DecodeAnimation: %v", err)
+			}
+			if len(decoded.Frames) != len(tc.frames) {
+				t.Fatalf("got %d frames want %d", len(decoded.Frames), len(tc.frames))
+			}
+			for i := range tc.frames {
+				if !compareNRGBA(decoded.Frames[i], tc.frames[i], 0) {
+					t.Fatalf("frame %d differs after round trip", i)
+				}
+				if decoded.Delays[i] != anim.Delays[i] {
+					t.Fatalf("frame %d: got delay %d want %d", i, decoded.Delays[i], anim.Delays[i])
+				}
+			}
+			if decoded.LoopCount != tc.loopCount {
+				t.Fatalf("got loop count %d want %d", decoded.LoopCount, tc.loopCount)
+			}
+
+			// Decoders without APNG support see the first frame.
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("png.Decode: %v", err)
+			}
+			if !compareNRGBA(Clone(img), tc.frames[0], 0) {
+				t.Fatal("default image differs from the first frame")
+			}
+		})
+	}
+}
+
+func TestEncodeAnimationPNGFrameSize(t *testing.T) {
+	t.Parallel()
+
+	anim := &Animation{
+		Frames: []*image.NRGBA{
+			New(4, 4, animRed),
+			New(2, 3, animBlue),
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := EncodeAnimation(buf, anim, PNG); err != nil {
+		t.Fatalf("EncodeAnimation: %v", err)
+	}
+	decoded, err := DecodeAnimation(buf)
+	if err != nil {
+		t.Fatalf("DecodeAnimation: %v", err)
+	}
+
+	r, b, n := animRed, animBlue, animNone
+	checkPixels(t, decoded.Frames[1], [16]color.NRGBA{
+		b, b, n, n,
+		b, b, n, n,
+		b, b, n, n,
+		n, n, n, n,
+	})
+	checkPixels(t, decoded.Frames[0], [16]color.NRGBA{
+		r, r, r, r,
+		r, r, r, r,
+		r, r, r, r,
+		r, r, r, r,
+	})
+}
+
+func BenchmarkEncodeAnimationPNG(b *testing.B) {
+	anim := &Animation{}
+	for i := 0; i < 5; i++ {
+		anim.Frames = append(anim.Frames, AdjustBrightness(testdataFlowersSmallPNG, float64(i*5)))
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := EncodeAnimation(&bytes.Buffer{}, anim, PNG); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package imaging
 
 import (
 	"image"
+	"math"
 )
 
 // ConvolveOptions are convolution parameters.
@@ -14,6 +15,17 @@ type ConvolveOptions struct {
 
 	// Bias is added to each color channel value after convolution.
 	Bias int
+
+	// If PremultiplyAlpha is true the color channels are weighted by alpha during convolution,
+	// so that the colors of transparent pixels don't affect the result. The colors are then
+	// divided by the alpha-weighted sum of the kernel if the kernel is normalized and has no
+	// negative values, and by the mean alpha weighted by the absolute kernel values otherwise,
+	// as for edge detection kernels summing to zero.
+	PremultiplyAlpha bool
+
+	// If ConvolveAlpha is true the alpha channel is convolved too, otherwise it's copied
+	// from the source image. Abs is applied to the convolved alpha channel, Bias isn't.
+	ConvolveAlpha bool
 }
 
 // Convolve3x3 convolves the image with the specified 3x3 convolution kernel.
@@ -60,20 +72,29 @@ func convolve(img image.Image, kernel []float64, options *ConvolveOptions) *imag
 		m = 2
 	}
 
+	var sum, absSum float64
+	negative := false
 	i := 0
 	for y := -m; y <= m; y++ {
 		for x := -m; x <= m; x++ {
 			if kernel[i] != 0 {
 				coefs = append(coefs, coef{x: x, y: y, k: kernel[i]})
+				sum += kernel[i]
+				absSum += math.Abs(kernel[i])
+				negative = negative || kernel[i] < 0
 			}
 			i++
 		}
 	}
+	// Premultiplied colors are normalized by the alpha-weighted kernel sum only if
+	// the kernel computes a weighted average of the colors. For other kernels the sum
+	// can be close to zero or negative.
+	weightedAverage := options.Normalize && sum > 0 && !negative
 
 	parallel(0, h, func(ys <-chan int) {
 		for y := range ys {
 			for x := 0; x < w; x++ {
-				var r, g, b float64
+				var r, g, b, a, absA float64
 				for _, c := range coefs {
 					ix := x + c.x
 					if ix < 0 {
@@ -90,10 +111,50 @@ func convolve(img image.Image, kernel []float64, options *ConvolveOptions) *imag
 					}
 
 					off := iy*src.Stride + ix*4
-					s := src.Pix[off : off+3 : off+3]
-					r += float64(s[0]) * c.k
-					g += float64(s[1]) * c.k
-					b += float64(s[2]) * c.k
+					s := src.Pix[off : off+4 : off+4]
+					k := c.k
+					if options.PremultiplyAlpha {
+						k *= float64(s[3]) / 255
+					}
+					r += float64(s[0]) * k
+					g += float64(s[1]) * k
+					b += float64(s[2]) * k
+					a += float64(s[3]) * c.k
+					absA += float64(s[3]) * math.Abs(c.k)
+				}
+
+				srcOff := y*src.Stride + x*4
+				dstOff := y*dst.Stride + x*4
+				d := dst.Pix[dstOff : dstOff+4 : dstOff+4]
+
+				alpha := src.Pix[srcOff+3]
+				// The alpha by which the premultiplied colors are normalized.
+				weightAlpha := absA / absSum
+				if weightedAverage {
+					weightAlpha = a
+				}
+				if options.ConvolveAlpha {
+					if options.Abs && a < 0 {
+						a = -a
+					}
+					alpha = clamp(a)
+				}
+
+				if options.PremultiplyAlpha {
+					if alpha == 0 {
+						d[0] = 0
+						d[1] = 0
+						d[2] = 0
+						d[3] = 0
+						continue
+					}
+					// The colors are zero if all the weighted pixels are transparent.
+					if weightAlpha > 0 {
+						aInv := 255 / weightAlpha
+						r *= aInv
+						g *= aInv
+						b *= aInv
+					}
 				}
 
 				if options.Abs {
@@ -114,13 +175,10 @@ func convolve(img image.Image, kernel []float64, options *ConvolveOptions) *imag
 					b += float64(options.Bias)
 				}
 
-				srcOff := y*src.Stride + x*4
-				dstOff := y*dst.Stride + x*4
-				d := dst.Pix[dstOff : dstOff+4 : dstOff+4]
 				d[0] = clamp(r)
 				d[1] = clamp(g)
 				d[2] = clamp(b)
-				d[3] = src.Pix[srcOff+3]
+				d[3] = alpha
 			}
 		}
 	})
@@ -2,6 +2,7 @@ package imaging
 
 import (
 	"image"
+	"image/color"
 	"testing"
 )
 
@@ -242,6 +243,142 @@ func TestConvolve5x5(t *testing.T) {
 	}
 }
 
+func TestConvolveAlpha(t *testing.T) {
+	t.Parallel()
+
+	// A red pixel, a semi-transparent blue pixel and an invisible green pixel.
+	src := &image.NRGBA{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0xff, 0x00, 0x00, 0xff, 0x00, 0x00, 0xff, 0x80, 0x00, 0xff, 0x00, 0x00,
+		},
+	}
+	box := [9]float64{
+		1, 1, 1,
+		1, 1, 1,
+		1, 1, 1,
+	}
+	testCases := []struct {
+		name    string
+		options *ConvolveOptions
+		want    []uint8
+	}{
+		{
+			"default",
+			&ConvolveOptions{Normalize: true},
+			[]uint8{0xaa, 0x00, 0x55, 0xff, 0x55, 0x55, 0x55, 0x80, 0x00, 0xaa, 0x55, 0x00},
+		},
+		{
+			"premultiply alpha",
+			&ConvolveOptions{Normalize: true, PremultiplyAlpha: true},
+			[]uint8{0xcc, 0x00, 0x33, 0xff, 0xaa, 0x00, 0x55, 0x80, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			"convolve alpha",
+			&ConvolveOptions{Normalize: true, ConvolveAlpha: true},
+			[]uint8{0xaa, 0x00, 0x55, 0xd5, 0x55, 0x55, 0x55, 0x80, 0x00, 0xaa, 0x55, 0x2b},
+		},
+		{
+			"premultiply and convolve alpha",
+			&ConvolveOptions{Normalize: true, PremultiplyAlpha: true, ConvolveAlpha: true},
+			[]uint8{0xcc, 0x00, 0x33, 0xd5, 0xaa, 0x00, 0x55, 0x80, 0x00, 0x00, 0xff, 0x2b},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			got := Convolve3x3(src, box, tc.options)
+			if !compareBytes(got.Pix, tc.want, 0) {
+				t.Fatalf("got %#v want %#v", got.Pix, tc.want)
+			}
+		})
+	}
+}
+
+func TestConvolveAlphaEdges(t *testing.T) {
+	t.Parallel()
+
+	// A semi-transparent gray square on a transparent background with the given color.
+	makeImage := func(bg color.NRGBA) *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				c := bg
+				if x >= 2 && x < 6 && y >= 2 && y < 6 {
+					c = color.NRGBA{0x80, 0x80, 0x80, 0xc0}
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+		return img
+	}
+	src1 := makeImage(color.NRGBA{0xff, 0x00, 0x00, 0x00})
+	src2 := makeImage(color.NRGBA{0x00, 0xff, 0xff, 0x00})
+	laplacian := [9]float64{
+		-1, -1, -1,
+		-1, 8, -1,
+		-1, -1, -1,
+	}
+
+	options := &ConvolveOptions{Abs: true}
+	if compareNRGBA(Convolve3x3(src1, laplacian, options), Convolve3x3(src2, laplacian, options), 0) {
+		t.Fatal("expected the colors of transparent pixels to affect the result")
+	}
+
+	options = &ConvolveOptions{Abs: true, PremultiplyAlpha: true, ConvolveAlpha: true}
+	got1 := Convolve3x3(src1, laplacian, options)
+	got2 := Convolve3x3(src2, laplacian, options)
+	if !compareNRGBA(got1, got2, 0) {
+		t.Fatal("the colors of transparent pixels affected the result")
+	}
+	// The outline of the square is detected in the alpha channel, the inside and
+	// the far background stay transparent.
+	for _, tc := range []struct {
+		x, y  int
+		alpha uint8
+	}{
+		{0, 0, 0x00},
+		{1, 1, 0xc0},
+		{2, 2, 0xff},
+		{3, 3, 0x00},
+	} {
+		if a := got1.NRGBAAt(tc.x, tc.y).A; a != tc.alpha {
+			t.Fatalf("(%d, %d): got alpha %#x want %#x", tc.x, tc.y, a, tc.alpha)
+		}
+	}
+}
+
+func TestConvolveAlphaZeroSum(t *testing.T) {
+	t.Parallel()
+
+	laplacian := [9]float64{
+		-1, -1, -1,
+		-1, 8, -1,
+		-1, -1, -1,
+	}
+	// An opaque gray pixel of 100 surrounded by pixels of 90 with the given alpha.
+	// Without premultiplication the center is 8*100 - 8*90 = 80 for any alpha.
+	testCases := []struct {
+		alpha uint8
+		want  uint8
+	}{
+		{0xff, 80},
+		{0xfe, 83},
+		{0xfa, 95},
+	}
+	for _, tc := range testCases {
+		src := New(3, 3, color.NRGBA{90, 90, 90, tc.alpha})
+		src.SetNRGBA(1, 1, color.NRGBA{100, 100, 100, 0xff})
+		got := Convolve3x3(src, laplacian, &ConvolveOptions{PremultiplyAlpha: true}).NRGBAAt(1, 1)
+		if want := (color.NRGBA{tc.want, tc.want, tc.want, 0xff}); got != want {
+			t.Fatalf("alpha %#x: got %v want %v", tc.alpha, got, want)
+		}
+	}
+}
+
 func TestNormalizeKernel(t *testing.T) {
 	t.Parallel()
 
@@ -3,5 +3,9 @@ Package imaging provides basic image processing functions (resize, rotate, crop,
 
 All the image processing functions provided by the package accept any image type that implements image.Image interface
 as an input, and return a new image of *image.NRGBA type (32bit RGBA colors, non-premultiplied alpha).
+
+Functions with the 16 suffix, such as Resize16, Blur16 and AdjustGamma16, process the image with 16 bits
+per channel and return a new image of *image.NRGBA64 type (64bit RGBA colors, non-premultiplied alpha).
+They preserve the precision of 16-bit images, such as 16-bit PNG and TIFF images.
 */
 package imaging
@@ -21,6 +21,39 @@ func Blur(img image.Image, sigma float64) *image.NRGBA {
 		return Clone(img)
 	}
 
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	blurInto(dst, img, sigma)
+	return dst
+}
+
+// BlurInto is like Blur but stores the blurred image in the top-left corner of dst
+// instead of allocating a new image. The result is clipped to the size of dst.
+// The intermediate image is reused between calls. It returns the sub-image of dst
+// holding the result.
+//
+// Example:
+//
+//	buf := image.NewNRGBA(srcImage.Bounds().Sub(srcImage.Bounds().Min))
+//	dstImage := imaging.BlurInto(buf, srcImage, 3.5)
+func BlurInto(dst *image.NRGBA, img image.Image, sigma float64) *image.NRGBA {
+	b := img.Bounds()
+	r := image.Rect(0, 0, b.Dx(), b.Dy()).Intersect(dst.Rect.Sub(dst.Rect.Min))
+	if r.Empty() {
+		return &image.NRGBA{}
+	}
+
+	out := dst.SubImage(r.Add(dst.Rect.Min)).(*image.NRGBA)
+	if sigma <= 0 {
+		cropInto(out, img, r)
+		return out
+	}
+	blurInto(out, img, sigma)
+	return out
+}
+
+// blurInto blurs the image and stores the result in dst, which must not be larger than the image.
+func blurInto(dst *image.NRGBA, img image.Image, sigma float64) {
 	radius := int(math.Ceil(sigma * 3.0))
 	kernel := make([]float64, radius+1)
 
@@ -28,17 +61,24 @@ func Blur(img image.Image, sigma float64) *image.NRGBA {
 		kernel[i] = gaussianBlurKernel(float64(i), sigma)
 	}
 
-	return blurVertical(blurHorizontal(img, kernel), kernel)
+	b := img.Bounds()
+	tmp := getNRGBA(b.Dx(), b.Dy())
+	blurHorizontal(tmp, img, kernel)
+	blurVertical(dst, tmp, kernel)
+	putNRGBA(tmp)
 }
 
-func blurHorizontal(img image.Image, kernel []float64) *image.NRGBA {
+// blurHorizontal blurs the rows of the image and stores the result in dst,
+// which must have the size of the image.
+func blurHorizontal(dst *image.NRGBA, img image.Image, kernel []float64) {
 	src := newScanner(img)
-	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
 	radius := len(kernel) - 1
 
 	parallel(0, src.h, func(ys <-chan int) {
-		scanLine := make([]uint8, src.w*4)
-		scanLineF := make([]float64, len(scanLine))
+		scanLine := getPix(src.w * 4)
+		defer putPix(scanLine)
+		scanLineF := getFloats(len(scanLine))
+		defer putFloats(scanLineF)
 		for y := range ys {
 			src.scan(0, y, src.w, y+1, scanLine)
 			for i, v := range scanLine {
@@ -65,36 +105,44 @@ func blurHorizontal(img image.Image, kernel []float64) *image.NRGBA {
 					b += s[2] * wa
 					a += wa
 				}
+				j := y*dst.Stride + x*4
+				d := dst.Pix[j : j+4 : j+4]
 				if a != 0 {
 					aInv := 1 / a
-					j := y*dst.Stride + x*4
-					d := dst.Pix[j : j+4 : j+4]
 					d[0] = clamp(r * aInv)
 					d[1] = clamp(g * aInv)
 					d[2] = clamp(b * aInv)
 					d[3] = clamp(a / wsum)
+				} else {
+					d[0] = 0
+					d[1] = 0
+					d[2] = 0
+					d[3] = 0
 				}
 			}
 		}
 	})
-
-	return dst
 }
 
-func blurVertical(img image.Image, kernel []float64) *image.NRGBA {
+// blurVertical blurs the columns of the image and stores the result in dst,
+// which must not be larger than the image. The result is clipped to the size of dst.
+func blurVertical(dst *image.NRGBA, img image.Image, kernel []float64) {
 	src := newScanner(img)
-	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
 	radius := len(kernel) - 1
+	dstW := dst.Rect.Dx()
+	dstH := dst.Rect.Dy()
 
-	parallel(0, src.w, func(xs <-chan int) {
-		scanLine := make([]uint8, src.h*4)
-		scanLineF := make([]float64, len(scanLine))
+	parallel(0, dstW, func(xs <-chan int) {
+		scanLine := getPix(src.h * 4)
+		defer putPix(scanLine)
+		scanLineF := getFloats(len(scanLine))
+		defer putFloats(scanLineF)
 		for x := range xs {
 			src.scan(x, 0, x+1, src.h, scanLine)
 			for i, v := range scanLine {
 				scanLineF[i] = float64(v)
 			}
-			for y := 0; y < src.h; y++ {
+			for y := 0; y < dstH; y++ {
 				min := y - radius
 				if min < 0 {
 					min = 0
@@ -115,20 +163,23 @@ func blurVertical(img image.Image, kernel []float64) *image.NRGBA {
 					b += s[2] * wa
 					a += wa
 				}
+				j := y*dst.Stride + x*4
+				d := dst.Pix[j : j+4 : j+4]
 				if a != 0 {
 					aInv := 1 / a
-					j := y*dst.Stride + x*4
-					d := dst.Pix[j : j+4 : j+4]
 					d[0] = clamp(r * aInv)
 					d[1] = clamp(g * aInv)
 					d[2] = clamp(b * aInv)
 					d[3] = clamp(a / wsum)
+				} else {
+					d[0] = 0
+					d[1] = 0
+					d[2] = 0
+					d[3] = 0
 				}
 			}
 		}
 	})
-
-	return dst
 }
 
 // Sharpen produces a sharpened version of the image.
@@ -144,10 +195,13 @@ func Sharpen(img image.Image, sigma float64) *image.NRGBA {
 
 	src := newScanner(img)
 	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
-	blurred := Blur(img, sigma)
+	blurred := getNRGBA(src.w, src.h)
+	defer putNRGBA(blurred)
+	blurInto(blurred, img, sigma)
 
 	parallel(0, src.h, func(ys <-chan int) {
-		scanLine := make([]uint8, src.w*4)
+		scanLine := getPix(src.w * 4)
+		defer putPix(scanLine)
 		for y := range ys {
 			src.scan(0, y, src.w, y+1, scanLine)
 			j := y * dst.Stride
@@ -166,3 +220,155 @@ func Sharpen(img image.Image, sigma float64) *image.NRGBA {
 
 	return dst
 }
+
+// Blur16 is like Blur but processes the image with 16 bits per channel and returns
+// an *image.NRGBA64, preserving the precision of 16-bit images.
+//
+// Example:
+//
+//	dstImage := imaging.Blur16(srcImage, 3.5)
+func Blur16(img image.Image, sigma float64) *image.NRGBA64 {
+	if sigma <= 0 {
+		return Clone16(img)
+	}
+
+	radius := int(math.Ceil(sigma * 3.0))
+	kernel := make([]float64, radius+1)
+
+	for i := 0; i <= radius; i++ {
+		kernel[i] = gaussianBlurKernel(float64(i), sigma)
+	}
+
+	return blurVertical16(blurHorizontal16(img, kernel), kernel)
+}
+
+func blurHorizontal16(img image.Image, kernel []float64) *image.NRGBA64 {
+	src := newScanner(img)
+	dst := image.NewNRGBA64(image.Rect(0, 0, src.w, src.h))
+	radius := len(kernel) - 1
+
+	parallel(0, src.h, func(ys <-chan int) {
+		scanLine := make([]uint8, src.w*8)
+		scanLineF := make([]float64, src.w*4)
+		for y := range ys {
+			src.scan16(0, y, src.w, y+1, scanLine)
+			for i := range scanLineF {
+				scanLineF[i] = float64(uint16(scanLine[i*2])<<8 | uint16(scanLine[i*2+1]))
+			}
+			for x := 0; x < src.w; x++ {
+				min := x - radius
+				if min < 0 {
+					min = 0
+				}
+				max := x + radius
+				if max > src.w-1 {
+					max = src.w - 1
+				}
+				var r, g, b, a, wsum float64
+				for ix := min; ix <= max; ix++ {
+					i := ix * 4
+					weight := kernel[absInt(x-ix)]
+					wsum += weight
+					s := scanLineF[i : i+4 : i+4]
+					wa := s[3] * weight
+					r += s[0] * wa
+					g += s[1] * wa
+					b += s[2] * wa
+					a += wa
+				}
+				if a != 0 {
+					aInv := 1 / a
+					j := y*dst.Stride + x*8
+					setPixel16(dst.Pix[j:j+8:j+8], clamp16(r*aInv), clamp16(g*aInv), clamp16(b*aInv), clamp16(a/wsum))
+				}
+			}
+		}
+	})
+
+	return dst
+}
+
+func blurVertical16(img image.Image, kernel []float64) *image.NRGBA64 {
+	src := newScanner(img)
+	dst := image.NewNRGBA64(image.Rect(0, 0, src.w, src.h))
+	radius := len(kernel) - 1
+
+	parallel(0, src.w, func(xs <-chan int) {
+		scanLine := make([]uint8, src.h*8)
+		scanLineF := make([]float64, src.h*4)
+		for x := range xs {
+			src.scan16(x, 0, x+1, src.h, scanLine)
+			for i := range scanLineF {
+				scanLineF[i] = float64(uint16(scanLine[i*2])<<8 | uint16(scanLine[i*2+1]))
+			}
+			for y := 0; y < src.h; y++ {
+				min := y - radius
+				if min < 0 {
+					min = 0
+				}
+				max := y + radius
+				if max > src.h-1 {
+					max = src.h - 1
+				}
+				var r, g, b, a, wsum float64
+				for iy := min; iy <= max; iy++ {
+					i := iy * 4
+					weight := kernel[absInt(y-iy)]
+					wsum += weight
+					s := scanLineF[i : i+4 : i+4]
+					wa := s[3] * weight
+					r += s[0] * wa
+					g += s[1] * wa
+					b += s[2] * wa
+					a += wa
+				}
+				if a != 0 {
+					aInv := 1 / a
+					j := y*dst.Stride + x*8
+					setPixel16(dst.Pix[j:j+8:j+8], clamp16(r*aInv), clamp16(g*aInv), clamp16(b*aInv), clamp16(a/wsum))
+				}
+			}
+		}
+	})
+
+	return dst
+}
+
+// Sharpen16 is like Sharpen but processes the image with 16 bits per channel and returns
+// an *image.NRGBA64, preserving the precision of 16-bit images.
+//
+// Example:
+//
+//	dstImage := imaging.Sharpen16(srcImage, 3.5)
+func Sharpen16(img image.Image, sigma float64) *image.NRGBA64 {
+	if sigma <= 0 {
+		return Clone16(img)
+	}
+
+	src := newScanner(img)
+	dst := image.NewNRGBA64(image.Rect(0, 0, src.w, src.h))
+	blurred := Blur16(img, sigma)
+
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			j := y * dst.Stride
+			src.scan16(0, y, src.w, y+1, dst.Pix[j:j+src.w*8])
+			for i := 0; i < src.w*8; i += 2 {
+				d := dst.Pix[j+i : j+i+2 : j+i+2]
+				s := blurred.Pix[j+i : j+i+2 : j+i+2]
+				v := int(d[0])<<8 | int(d[1])
+				blur := int(s[0])<<8 | int(s[1])
+				val := v<<1 - blur
+				if val < 0 {
+					val = 0
+				} else if val > 0xffff {
+					val = 0xffff
+				}
+				d[0] = uint8(val >> 8)
+				d[1] = uint8(val)
+			}
+		}
+	})
+
+	return dst
+}
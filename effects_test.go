@@ -248,3 +248,66 @@ func BenchmarkSharpen(b *testing.B) {
 		Sharpen(testdataBranchesJPG, 3)
 	}
 }
+
+func TestBlur16(t *testing.T) {
+	t.Parallel()
+
+	for _, sigma := range []float64{0, 0.5, 3} {
+		got := Clone(Blur16(testdataFlowersSmallPNG, sigma))
+		want := Blur(testdataFlowersSmallPNG, sigma)
+		// Blur rounds to 8 bits between the horizontal and vertical passes.
+		if !compareNRGBA(got, want, 2) {
+			t.Fatalf("Blur16(%v) does not match Blur", sigma)
+		}
+	}
+
+	if levels := countLevels16(Blur16(makeGradient16(1024, 8), 2)); levels < 1000 {
+		t.Fatalf("got %d levels of the 16-bit gradient, want at least 1000", levels)
+	}
+}
+
+func TestSharpen16(t *testing.T) {
+	t.Parallel()
+
+	for _, sigma := range []float64{0, 0.5, 3} {
+		got := Clone(Sharpen16(testdataFlowersSmallPNG, sigma))
+		want := Sharpen(testdataFlowersSmallPNG, sigma)
+		// Sharpen uses the blurred image rounded to 8 bits.
+		if !compareNRGBA(got, want, 2) {
+			t.Fatalf("Sharpen16(%v) does not match Sharpen", sigma)
+		}
+	}
+
+	if levels := countLevels16(Sharpen16(makeGradient16(1024, 8), 2)); levels < 1000 {
+		t.Fatalf("got %d levels of the 16-bit gradient, want at least 1000", levels)
+	}
+}
+
+func TestBlurInto(t *testing.T) {
+	t.Parallel()
+
+	b := testdataFlowersSmallPNG.Bounds()
+	for _, sigma := range []float64{0, 0.5, 3} {
+		want := Blur(testdataFlowersSmallPNG, sigma)
+		buf := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		for i := range buf.Pix {
+			buf.Pix[i] = 0x5a
+		}
+		if got := BlurInto(buf, testdataFlowersSmallPNG, sigma); !compareNRGBA(got, want, 0) {
+			t.Fatalf("BlurInto(%v) does not match Blur", sigma)
+		}
+
+		// The result is clipped to the size of dst.
+		small := image.NewNRGBA(image.Rect(10, 10, 60, 40))
+		got := BlurInto(small, testdataFlowersSmallPNG, sigma)
+		if got.Rect != small.Rect {
+			t.Fatalf("got bounds %v want %v", got.Rect, small.Rect)
+		}
+		if !compareNRGBA(Clone(got), Crop(want, image.Rect(0, 0, 50, 30)), 0) {
+			t.Fatalf("clipped BlurInto(%v) does not match Blur", sigma)
+		}
+	}
+	if got := BlurInto(&image.NRGBA{}, testdataFlowersSmallPNG, 1); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
@@ -1,25 +1,27 @@
 package imaging
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // ReadOrientation tries to read the orientation EXIF flag from image data in r.
+// The EXIF data is looked for in JPEG APP1 segments, TIFF headers, PNG eXIf
+// chunks and WebP EXIF chunks.
 // If the EXIF data block is not found or the orientation flag is not found
 // or any other error occures while reading the data, it returns the
 // orientationUnspecified (0) value.
 func ReadOrientation(r io.Reader) Orientation {
-	if err := findJPEGSOIMarker(r); err != nil {
-		return OrientationUnspecified
-	}
-
-	if err := findJPEGAPP1Marker(r); err != nil {
-		return OrientationUnspecified
-	}
-
-	if err := findEXIFHeader(r); err != nil {
+	r, _, err := findEXIF(r)
+	if err != nil {
 		return OrientationUnspecified
 	}
 
@@ -44,6 +46,127 @@ func ReadOrientation(r io.Reader) Orientation {
 	return orientation
 }
 
+// findEXIF locates the EXIF data in the JPEG, TIFF, PNG or WebP image data in r.
+// It returns a reader positioned at the start of the EXIF data, i.e. at the
+// byte order of its TIFF structure, and the size of the EXIF data, or -1 for
+// TIFF images where the EXIF data is the whole file.
+func findEXIF(r io.Reader) (io.Reader, int64, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(formatHeaderLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, fmt.Errorf("%w: %v", ErrNoEXIF, err)
+	}
+
+	var size int64
+	switch f, _ := formatFromHeader(header); f {
+	case TIFF:
+		return br, -1, nil
+	case PNG:
+		size, err = findPNGEXIFChunk(br)
+	case WEBP:
+		size, err = findWebPEXIFChunk(br)
+	default:
+		size, err = findJPEGEXIF(br)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrNoEXIF, err)
+	}
+
+	// Some writers keep the JPEG EXIF header in PNG and WebP chunks.
+	if prefix, err := br.Peek(6); err == nil && string(prefix) == "Exif\x00\x00" && size >= 6 {
+		if _, err := br.Discard(6); err != nil {
+			return nil, 0, err
+		}
+		size -= 6
+	}
+	return br, size, nil
+}
+
+// findJPEGEXIF finds the EXIF data in the JPEG image and returns its size.
+// All the APP1 segments preceding the image data are examined, so that EXIF
+// data following other APP1 segments (e.g. XMP) is found.
+// This function assumes that the reader is positioned at the beginning of the file.
+func findJPEGEXIF(r io.Reader) (int64, error) {
+	if err := findJPEGSOIMarker(r); err != nil {
+		return 0, err
+	}
+	for {
+		size, err := findJPEGAPP1Marker(r)
+		if err != nil {
+			return 0, err
+		}
+		if size < 2 {
+			return 0, errors.New("invalid block size")
+		}
+		remaining := int64(size) - 2
+		if remaining >= int64(len(exifHeader)) {
+			header := make([]byte, len(exifHeader))
+			if _, err := io.ReadFull(r, header); err != nil {
+				return 0, err
+			}
+			remaining -= int64(len(exifHeader))
+			if string(header) == exifHeader {
+				return remaining, nil
+			}
+		}
+		if _, err := io.CopyN(io.Discard, r, remaining); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// findPNGEXIFChunk finds the PNG eXIf chunk and returns its size.
+// This function assumes that the reader is positioned at the beginning of the file.
+func findPNGEXIFChunk(r io.Reader) (int64, error) {
+	if _, err := io.CopyN(io.Discard, r, int64(len(pngSignature))); err != nil {
+		return 0, err
+	}
+	for {
+		var length uint32
+		var typ [4]byte
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return 0, err
+		}
+		if _, err := io.ReadFull(r, typ[:]); err != nil {
+			return 0, err
+		}
+		switch string(typ[:]) {
+		case "eXIf":
+			return int64(length), nil
+		case "IEND":
+			return 0, errors.New("missing PNG eXIf chunk")
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(length)+4); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// findWebPEXIFChunk finds the WebP EXIF chunk and returns its size.
+// This function assumes that the reader is positioned at the beginning of the file.
+func findWebPEXIFChunk(r io.Reader) (int64, error) {
+	if _, err := io.CopyN(io.Discard, r, 12); err != nil {
+		return 0, err
+	}
+	for {
+		var fourCC [4]byte
+		var size uint32
+		if _, err := io.ReadFull(r, fourCC[:]); err != nil {
+			return 0, err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return 0, err
+		}
+		if string(fourCC[:]) == "EXIF" {
+			return int64(size), nil
+		}
+		// Chunks are padded to an even size.
+		if _, err := io.CopyN(io.Discard, r, int64(size)+int64(size&1)); err != nil {
+			return 0, err
+		}
+	}
+}
+
 // findJPEGSOIMarker tries to find the JPEG SOI marker in r.
 // This function assumes that the reader is positioned at the beginning of the file.
 func findJPEGSOIMarker(r io.Reader) error {
@@ -61,55 +184,71 @@ func findJPEGSOIMarker(r io.Reader) error {
 	return nil
 }
 
-// findJPEGAPP1Marker tries to find the JPEG APP1 marker in r.
-// This function assumes that the reader is positioned after the JPEG SOI marker.
-func findJPEGAPP1Marker(r io.Reader) error {
+// exifHeader is the header of the JPEG APP1 segments holding EXIF data.
+const exifHeader = "Exif\x00\x00"
+
+// findJPEGAPP1Marker tries to find the next JPEG APP1 marker in r and returns
+// the size of the APP1 segment, including the two bytes of the size field.
+// The search stops at the start of the image data (SOS marker).
+// This function assumes that the reader is positioned at a JPEG marker.
+func findJPEGAPP1Marker(r io.Reader) (uint16, error) {
 	const (
-		markerAPP1 = 0xffe1
+		markerTEM  = 0x01
+		markerRST0 = 0xd0
+		markerRST7 = 0xd7
+		markerEOI  = 0xd9
+		markerSOS  = 0xda
+		markerAPP1 = 0xe1
 	)
 
 	for {
-		var marker, size uint16
-		if err := binary.Read(r, binary.BigEndian, &marker); err != nil {
-			return err
+		marker, err := readJPEGMarker(r)
+		if err != nil {
+			return 0, err
 		}
-		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
-			return err
+		switch {
+		case marker == markerSOS || marker == markerEOI:
+			return 0, errors.New("missing JPEG APP1 marker")
+		case marker == markerTEM || (marker >= markerRST0 && marker <= markerRST7):
+			// Standalone markers have no segment.
+			continue
 		}
-		if marker>>8 != 0xff {
-			return errors.New("invalid JPEG marker")
+
+		var size uint16
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return 0, err
 		}
 		if marker == markerAPP1 {
-			break
+			return size, nil
 		}
 		if size < 2 {
-			return errors.New("invalid block size")
+			return 0, errors.New("invalid block size")
 		}
 		if _, err := io.CopyN(io.Discard, r, int64(size-2)); err != nil {
-			return err
+			return 0, err
 		}
 	}
-	return nil
 }
 
-// findEXIFHeader tries to find the EXIF header in r.
-// This function assumes that the reader is positioned after the JPEG APP1 marker.
-func findEXIFHeader(r io.Reader) error {
-	const (
-		exifHeader = 0x45786966
-	)
-
-	var header uint32
-	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
-		return err
+// readJPEGMarker reads a JPEG marker from r and returns its code.
+// Any number of 0xff fill bytes may precede the marker.
+func readJPEGMarker(r io.Reader) (byte, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
 	}
-	if header != exifHeader {
-		return errors.New("exif header not found")
+	if b[0] != 0xff {
+		return 0, errors.New("invalid JPEG marker")
 	}
-	if _, err := io.CopyN(io.Discard, r, 2); err != nil {
-		return err
+	for b[0] == 0xff {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, err
+		}
 	}
-	return nil
+	if b[0] == 0 {
+		return 0, errors.New("invalid JPEG marker")
+	}
+	return b[0], nil
 }
 
 // readByteOrder reads the byte order from r.
@@ -202,3 +341,481 @@ func findOrientationTag(r io.Reader, byteOrder binary.ByteOrder, numTags uint16)
 	}
 	return OrientationUnspecified, nil // Missing orientation tag.
 }
+
+// ErrNoEXIF means the image does not contain EXIF data.
+var ErrNoEXIF = errors.New("imaging: EXIF data not found")
+
+// ErrEXIFTagNotFound means the requested EXIF tag is not present.
+var ErrEXIFTagNotFound = errors.New("imaging: EXIF tag not found")
+
+// errInvalidEXIF means the EXIF data is malformed.
+var errInvalidEXIF = errors.New("imaging: invalid EXIF data")
+
+// IFD identifies an image file directory (IFD) of the EXIF data.
+type IFD int
+
+// EXIF image file directories.
+const (
+	// IFD0 is the directory of the primary image (Make, Model, Orientation, ...).
+	IFD0 IFD = iota
+	// IFD1 is the directory of the thumbnail image.
+	IFD1
+	// ExifIFD is the Exif sub-directory (capture time, exposure settings, ...).
+	ExifIFD
+	// GPSIFD is the GPS sub-directory.
+	GPSIFD
+	// InteropIFD is the interoperability sub-directory.
+	InteropIFD
+)
+
+// EXIFType is the type of the value of an EXIF tag, as defined by the TIFF specification.
+type EXIFType uint16
+
+// EXIF tag value types.
+const (
+	// EXIFByte is an 8-bit unsigned integer.
+	EXIFByte EXIFType = 1
+	// EXIFASCII is a NUL-terminated 7-bit ASCII string.
+	EXIFASCII EXIFType = 2
+	// EXIFShort is a 16-bit unsigned integer.
+	EXIFShort EXIFType = 3
+	// EXIFLong is a 32-bit unsigned integer.
+	EXIFLong EXIFType = 4
+	// EXIFRational is a fraction of two 32-bit unsigned integers.
+	EXIFRational EXIFType = 5
+	// EXIFSByte is an 8-bit signed integer.
+	EXIFSByte EXIFType = 6
+	// EXIFUndefined is an 8-bit byte whose meaning depends on the tag.
+	EXIFUndefined EXIFType = 7
+	// EXIFSShort is a 16-bit signed integer.
+	EXIFSShort EXIFType = 8
+	// EXIFSLong is a 32-bit signed integer.
+	EXIFSLong EXIFType = 9
+	// EXIFSRational is a fraction of two 32-bit signed integers.
+	EXIFSRational EXIFType = 10
+	// EXIFFloat is a single precision IEEE floating point number.
+	EXIFFloat EXIFType = 11
+	// EXIFDouble is a double precision IEEE floating point number.
+	EXIFDouble EXIFType = 12
+	// EXIFIFDPointer is a 32-bit offset of an image file directory.
+	EXIFIFDPointer EXIFType = 13
+)
+
+// size returns the size in bytes of a single value of the type,
+// or 0 if the type is unknown.
+func (t EXIFType) size() uint32 {
+	switch t {
+	case EXIFByte, EXIFASCII, EXIFSByte, EXIFUndefined:
+		return 1
+	case EXIFShort, EXIFSShort:
+		return 2
+	case EXIFLong, EXIFSLong, EXIFFloat, EXIFIFDPointer:
+		return 4
+	case EXIFRational, EXIFSRational, EXIFDouble:
+		return 8
+	}
+	return 0
+}
+
+// Common EXIF tag IDs.
+const (
+	TagMake                        uint16 = 0x010f
+	TagModel                       uint16 = 0x0110
+	TagOrientation                 uint16 = 0x0112
+	TagSoftware                    uint16 = 0x0131
+	TagDateTime                    uint16 = 0x0132
+	TagArtist                      uint16 = 0x013b
+	TagJPEGInterchangeFormat       uint16 = 0x0201
+	TagJPEGInterchangeFormatLength uint16 = 0x0202
+	TagCopyright                   uint16 = 0x8298
+	TagExposureTime                uint16 = 0x829a
+	TagFNumber                     uint16 = 0x829d
+	TagExifIFDPointer              uint16 = 0x8769
+	TagGPSIFDPointer               uint16 = 0x8825
+	TagISOSpeedRatings             uint16 = 0x8827
+	TagDateTimeOriginal            uint16 = 0x9003
+	TagOffsetTimeOriginal          uint16 = 0x9011
+	TagFocalLength                 uint16 = 0x920a
+	TagMakerNote                   uint16 = 0x927c
+	TagSubSecTimeOriginal          uint16 = 0x9291
+	TagPixelXDimension             uint16 = 0xa002
+	TagPixelYDimension             uint16 = 0xa003
+	TagInteropIFDPointer           uint16 = 0xa005
+	TagBodySerialNumber            uint16 = 0xa431
+	TagGPSLatitudeRef              uint16 = 0x0001
+	TagGPSLatitude                 uint16 = 0x0002
+	TagGPSLongitudeRef             uint16 = 0x0003
+	TagGPSLongitude                uint16 = 0x0004
+)
+
+// EXIFTag is a tag of the EXIF data.
+type EXIFTag struct {
+	// IFD is the directory the tag belongs to.
+	IFD IFD
+	// ID is the tag identifier.
+	ID uint16
+	// Type is the type of the tag values.
+	Type EXIFType
+	// Count is the number of values.
+	Count uint32
+	// Value is the raw value data, in the byte order of the EXIF data.
+	Value []byte
+
+	order binary.ByteOrder
+}
+
+// errIndex returns an error if the i-th value doesn't exist.
+func (t *EXIFTag) errIndex(i int) error {
+	if i < 0 || int64(i) >= int64(t.Count) {
+		return fmt.Errorf("imaging: EXIF tag 0x%04x: index %d out of range [0:%d]", t.ID, i, t.Count)
+	}
+	return nil
+}
+
+// errType returns the error reported when the tag type doesn't match the accessor.
+func (t *EXIFTag) errType(want string) error {
+	return fmt.Errorf("imaging: EXIF tag 0x%04x of type %d is not %s", t.ID, t.Type, want)
+}
+
+// Int returns the i-th value of a tag of integer type (BYTE, SHORT, LONG,
+// their signed variants, UNDEFINED or IFD).
+func (t *EXIFTag) Int(i int) (int64, error) {
+	if err := t.errIndex(i); err != nil {
+		return 0, err
+	}
+	switch t.Type {
+	case EXIFByte, EXIFUndefined:
+		return int64(t.Value[i]), nil
+	case EXIFSByte:
+		return int64(int8(t.Value[i])), nil
+	case EXIFShort:
+		return int64(t.order.Uint16(t.Value[i*2:])), nil
+	case EXIFSShort:
+		return int64(int16(t.order.Uint16(t.Value[i*2:]))), nil
+	case EXIFLong, EXIFIFDPointer:
+		return int64(t.order.Uint32(t.Value[i*4:])), nil
+	case EXIFSLong:
+		return int64(int32(t.order.Uint32(t.Value[i*4:]))), nil
+	}
+	return 0, t.errType("an integer")
+}
+
+// Rat returns the numerator and the denominator of the i-th value
+// of a tag of type RATIONAL or SRATIONAL.
+func (t *EXIFTag) Rat(i int) (num, den int64, err error) {
+	if err := t.errIndex(i); err != nil {
+		return 0, 0, err
+	}
+	switch t.Type {
+	case EXIFRational:
+		return int64(t.order.Uint32(t.Value[i*8:])), int64(t.order.Uint32(t.Value[i*8+4:])), nil
+	case EXIFSRational:
+		return int64(int32(t.order.Uint32(t.Value[i*8:]))), int64(int32(t.order.Uint32(t.Value[i*8+4:]))), nil
+	}
+	return 0, 0, t.errType("a rational")
+}
+
+// Float returns the i-th value of a tag of any numeric type as a float64.
+// Rational values are divided.
+func (t *EXIFTag) Float(i int) (float64, error) {
+	if err := t.errIndex(i); err != nil {
+		return 0, err
+	}
+	switch t.Type {
+	case EXIFFloat:
+		return float64(math.Float32frombits(t.order.Uint32(t.Value[i*4:]))), nil
+	case EXIFDouble:
+		return math.Float64frombits(t.order.Uint64(t.Value[i*8:])), nil
+	case EXIFRational, EXIFSRational:
+		num, den, _ := t.Rat(i)
+		if den == 0 {
+			return 0, fmt.Errorf("imaging: EXIF tag 0x%04x: zero denominator", t.ID)
+		}
+		return float64(num) / float64(den), nil
+	case EXIFASCII:
+		return 0, t.errType("a number")
+	}
+	v, err := t.Int(i)
+	return float64(v), err
+}
+
+// StringVal returns the value of a tag of type ASCII, up to the first NUL character.
+func (t *EXIFTag) StringVal() (string, error) {
+	if t.Type != EXIFASCII {
+		return "", t.errType("an ASCII string")
+	}
+	s := t.Value
+	if i := bytes.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return string(s), nil
+}
+
+// EXIF holds the tags of the EXIF data of an image.
+type EXIF struct {
+	// ByteOrder is the byte order of the EXIF data.
+	ByteOrder binary.ByteOrder
+	// Tags are all the tags of all the directories, in the order they were read.
+	Tags []*EXIFTag
+
+	tiff []byte
+}
+
+// ReadEXIF reads the EXIF data from the JPEG, TIFF, PNG or WebP image data in r.
+// All the tags of IFD0, IFD1 and the Exif, GPS and interoperability
+// sub-directories are read.
+// ErrNoEXIF is returned if the image has no EXIF data.
+//
+// Example:
+//
+//	f, err := os.Open("photo.jpg")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	exif, err := imaging.ReadEXIF(f)
+//	if err != nil {
+//		return err
+//	}
+//	taken, err := exif.DateTimeOriginal()
+func ReadEXIF(r io.Reader) (*EXIF, error) {
+	r, size, err := findEXIF(r)
+	if err != nil {
+		return nil, err
+	}
+	var tiff []byte
+	if size < 0 {
+		tiff, err = io.ReadAll(r)
+	} else {
+		tiff = make([]byte, size)
+		_, err = io.ReadFull(r, tiff)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseEXIF(tiff)
+}
+
+// parseEXIF parses the EXIF data stored as a TIFF structure, starting with the byte order.
+func parseEXIF(tiff []byte) (*EXIF, error) {
+	order, err := readByteOrder(bytes.NewReader(tiff))
+	if err != nil || len(tiff) < 8 {
+		return nil, errInvalidEXIF
+	}
+	e := &EXIF{ByteOrder: order, tiff: tiff}
+	visited := map[uint32]bool{}
+
+	next, err := e.parseIFD(IFD0, order.Uint32(tiff[4:8]), visited)
+	if err != nil {
+		return nil, err
+	}
+	if next != 0 {
+		// The thumbnail is optional: ignore a broken IFD1.
+		_, _ = e.parseIFD(IFD1, next, visited)
+	}
+
+	subIFDs := []struct {
+		parent IFD
+		tag    uint16
+		ifd    IFD
+	}{
+		{IFD0, TagExifIFDPointer, ExifIFD},
+		{IFD0, TagGPSIFDPointer, GPSIFD},
+		{ExifIFD, TagInteropIFDPointer, InteropIFD},
+	}
+	for _, sub := range subIFDs {
+		t, ok := e.Tag(sub.parent, sub.tag)
+		if !ok {
+			continue
+		}
+		offset, err := t.Int(0)
+		if err != nil {
+			return nil, errInvalidEXIF
+		}
+		if _, err := e.parseIFD(sub.ifd, uint32(offset), visited); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// parseIFD reads the tags of the directory at the offset and returns the offset of the next directory.
+// Tags of unknown type or with values out of the EXIF data are skipped.
+func (e *EXIF) parseIFD(ifd IFD, offset uint32, visited map[uint32]bool) (uint32, error) {
+	if visited[offset] || uint64(offset)+2 > uint64(len(e.tiff)) {
+		return 0, errInvalidEXIF
+	}
+	visited[offset] = true
+
+	numTags := uint64(e.ByteOrder.Uint16(e.tiff[offset:]))
+	start := uint64(offset) + 2
+	end := start + numTags*12
+	if end > uint64(len(e.tiff)) {
+		return 0, errInvalidEXIF
+	}
+
+	for i := start; i < end; i += 12 {
+		entry := e.tiff[i : i+12]
+		typ := EXIFType(e.ByteOrder.Uint16(entry[2:4]))
+		count := e.ByteOrder.Uint32(entry[4:8])
+		size := uint64(typ.size()) * uint64(count)
+		if size == 0 {
+			continue
+		}
+
+		var value []byte
+		if size <= 4 {
+			value = entry[8 : 8+size]
+		} else {
+			valueOffset := uint64(e.ByteOrder.Uint32(entry[8:12]))
+			if valueOffset+size > uint64(len(e.tiff)) {
+				continue
+			}
+			value = e.tiff[valueOffset : valueOffset+size]
+		}
+		e.Tags = append(e.Tags, &EXIFTag{
+			IFD:   ifd,
+			ID:    e.ByteOrder.Uint16(entry[0:2]),
+			Type:  typ,
+			Count: count,
+			Value: value,
+			order: e.ByteOrder,
+		})
+	}
+
+	if end+4 > uint64(len(e.tiff)) {
+		return 0, nil
+	}
+	return e.ByteOrder.Uint32(e.tiff[end:]), nil
+}
+
+// Tag returns the tag with the given ID from the given directory.
+func (e *EXIF) Tag(ifd IFD, id uint16) (*EXIFTag, bool) {
+	for _, t := range e.Tags {
+		if t.IFD == ifd && t.ID == id {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// tag returns the tag with the given ID from the given directory or ErrEXIFTagNotFound.
+func (e *EXIF) tag(ifd IFD, id uint16) (*EXIFTag, error) {
+	if t, ok := e.Tag(ifd, id); ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: 0x%04x", ErrEXIFTagNotFound, id)
+}
+
+// Orientation returns the orientation tag value of the primary image, or
+// OrientationUnspecified if it is missing or invalid.
+func (e *EXIF) Orientation() Orientation {
+	t, ok := e.Tag(IFD0, TagOrientation)
+	if !ok {
+		return OrientationUnspecified
+	}
+	v, err := t.Int(0)
+	if err != nil || v < 1 || v > 8 {
+		return OrientationUnspecified
+	}
+	return Orientation(v)
+}
+
+// DateTimeOriginal returns the time the picture was taken. The sub-second
+// and time zone offset tags are used if present. Without time zone offset,
+// the time is assumed to be in the local time zone.
+func (e *EXIF) DateTimeOriginal() (time.Time, error) {
+	t, err := e.tag(ExifIFD, TagDateTimeOriginal)
+	if err != nil {
+		return time.Time{}, err
+	}
+	s, err := t.StringVal()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc := time.Local
+	if t, ok := e.Tag(ExifIFD, TagOffsetTimeOriginal); ok {
+		if offset, err := t.StringVal(); err == nil {
+			if zone, err := time.Parse("-07:00", strings.TrimSpace(offset)); err == nil {
+				loc = zone.Location()
+			}
+		}
+	}
+
+	tm, err := time.ParseInLocation("2006:01:02 15:04:05", strings.TrimSpace(s), loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("imaging: invalid EXIF date %q: %w", s, err)
+	}
+
+	if t, ok := e.Tag(ExifIFD, TagSubSecTimeOriginal); ok {
+		if subsec, err := t.StringVal(); err == nil {
+			subsec = strings.TrimSpace(subsec)
+			if frac, err := strconv.ParseFloat("0."+subsec, 64); err == nil && subsec != "" {
+				tm = tm.Add(time.Duration(math.Round(frac * float64(time.Second))))
+			}
+		}
+	}
+	return tm, nil
+}
+
+// GPS returns the latitude and the longitude of the picture in degrees.
+// Southern latitudes and western longitudes are negative.
+func (e *EXIF) GPS() (lat, lon float64, err error) {
+	lat, err = e.gpsCoordinate(TagGPSLatitude, TagGPSLatitudeRef, "S")
+	if err != nil {
+		return 0, 0, err
+	}
+	lon, err = e.gpsCoordinate(TagGPSLongitude, TagGPSLongitudeRef, "W")
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+// gpsCoordinate returns the coordinate stored as degrees, minutes and seconds.
+// The coordinate is negated if the reference tag is equal to negativeRef.
+func (e *EXIF) gpsCoordinate(valueTag, refTag uint16, negativeRef string) (float64, error) {
+	t, err := e.tag(GPSIFD, valueTag)
+	if err != nil {
+		return 0, err
+	}
+	var v float64
+	for i, div := range []float64{1, 60, 3600} {
+		f, err := t.Float(i)
+		if err != nil {
+			return 0, err
+		}
+		v += f / div
+	}
+	if ref, ok := e.Tag(GPSIFD, refTag); ok {
+		if s, err := ref.StringVal(); err == nil && strings.TrimSpace(s) == negativeRef {
+			v = -v
+		}
+	}
+	return v, nil
+}
+
+// Thumbnail returns the JPEG thumbnail image data embedded in the EXIF data.
+func (e *EXIF) Thumbnail() ([]byte, error) {
+	t, err := e.tag(IFD1, TagJPEGInterchangeFormat)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := t.Int(0)
+	if err != nil {
+		return nil, err
+	}
+	t, err = e.tag(IFD1, TagJPEGInterchangeFormatLength)
+	if err != nil {
+		return nil, err
+	}
+	length, err := t.Int(0)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || length < 0 || offset+length > int64(len(e.tiff)) {
+		return nil, errInvalidEXIF
+	}
+	return append([]byte(nil), e.tiff[offset:offset+length]...), nil
+}
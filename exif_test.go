@@ -1,9 +1,17 @@
 package imaging
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestReadOrientation(t *testing.T) {
@@ -143,6 +151,22 @@ func TestReadOrientationFails(t *testing.T) {
 			"invalid orientation value",
 			"\xff\xd8\xff\xe1\x00\xff\x45\x78\x69\x66\x00\x00\x4d\x4d\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x09",
 		},
+		{
+			"only XMP APP1 segment",
+			"\xff\xd8\xff\xe1\x00\x1ehttp://ns.adobe.com/xap/1.0/\x00\xff\xd9",
+		},
+		{
+			"APP1 segment after SOS marker",
+			"\xff\xd8\xff\xda\x00\x02\xff\xe1\x00\x16Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06",
+		},
+		{
+			"fill bytes only",
+			"\xff\xd8\xff\xff\xff",
+		},
+		{
+			"invalid marker after fill bytes",
+			"\xff\xd8\xff\xff\x00\xe1",
+		},
 	}
 	for _, tc := range testCases {
 		tc := tc
@@ -155,3 +179,669 @@ func TestReadOrientationFails(t *testing.T) {
 		})
 	}
 }
+
+func TestReadOrientationAPP1Segments(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		path string
+	}{
+		// XMP APP1 segment preceding the EXIF APP1 segment.
+		{"XMP first", "testdata/exif_xmp_first.jpg"},
+		// APP0, XMP, empty, truncated and unknown APP1 segments preceding the EXIF one.
+		{"multiple APP1", "testdata/exif_multiple_app1.jpg"},
+		// 0xff fill bytes preceding every marker.
+		{"fill bytes", "testdata/exif_fill_bytes.jpg"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(tc.path)
+			if err != nil {
+				t.Fatalf("failed to read %q: %v", tc.path, err)
+			}
+			if o := ReadOrientation(bytes.NewReader(data)); o != OrientationRotate270 {
+				t.Fatalf("got orientation %d want %d", o, OrientationRotate270)
+			}
+			x, err := ReadEXIF(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("ReadEXIF: %v", err)
+			}
+			if o := x.Orientation(); o != OrientationRotate270 {
+				t.Fatalf("got EXIF orientation %d want %d", o, OrientationRotate270)
+			}
+
+			img, err := Decode(bytes.NewReader(data), AutoOrientation(true))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			want, err := Open("testdata/orientation_6.jpg", AutoOrientation(true))
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if !compareNRGBA(Clone(img), Clone(want), 0) {
+				t.Fatal("decoded image does not match the reference image")
+			}
+		})
+	}
+}
+
+// testIFDEntry is a tag of a test EXIF directory. Data is the raw value.
+type testIFDEntry struct {
+	id    uint16
+	typ   EXIFType
+	count uint32
+	data  []byte
+}
+
+// testIFD is a test EXIF directory.
+type testIFD struct {
+	entries []testIFDEntry
+	// subs are the sub-directories referenced by pointer tags.
+	subs map[uint16]*testIFD
+	next *testIFD
+}
+
+// makeTestTIFF encodes the directories as EXIF data, starting with the byte order.
+func makeTestTIFF(order binary.ByteOrder, root *testIFD) []byte {
+	buf := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	if order == binary.LittleEndian {
+		buf = []byte("II\x2a\x00\x08\x00\x00\x00")
+	}
+
+	var write func(d *testIFD) uint32
+	write = func(d *testIFD) uint32 {
+		offset := uint32(len(buf))
+		n := len(d.entries) + len(d.subs)
+		buf = append(buf, make([]byte, 2+12*n+4)...)
+		order.PutUint16(buf[offset:], uint16(n))
+
+		entry := offset + 2
+		for _, e := range d.entries {
+			order.PutUint16(buf[entry:], e.id)
+			order.PutUint16(buf[entry+2:], uint16(e.typ))
+			order.PutUint32(buf[entry+4:], e.count)
+			if len(e.data) <= 4 {
+				copy(buf[entry+8:], e.data)
+			} else {
+				order.PutUint32(buf[entry+8:], uint32(len(buf)))
+				buf = append(buf, e.data...)
+			}
+			entry += 12
+		}
+		for _, id := range []uint16{TagExifIFDPointer, TagGPSIFDPointer, TagInteropIFDPointer} {
+			sub, ok := d.subs[id]
+			if !ok {
+				continue
+			}
+			subOffset := write(sub)
+			order.PutUint16(buf[entry:], id)
+			order.PutUint16(buf[entry+2:], uint16(EXIFLong))
+			order.PutUint32(buf[entry+4:], 1)
+			order.PutUint32(buf[entry+8:], subOffset)
+			entry += 12
+		}
+		if d.next != nil {
+			next := write(d.next)
+			order.PutUint32(buf[entry:], next)
+		}
+		return offset
+	}
+	write(root)
+	return buf
+}
+
+// makeEXIFJPEG returns a JPEG image with the EXIF data in an APP1 segment.
+func makeEXIFJPEG(t *testing.T, tiff []byte) []byte {
+	t.Helper()
+	img := &bytes.Buffer{}
+	if err := Encode(img, New(8, 4, color.White), JPEG); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+	data := []byte{0xff, 0xd8, 0xff, 0xe1, byte((len(app1) + 2) >> 8), byte(len(app1) + 2)}
+	data = append(data, app1...)
+	return append(data, img.Bytes()[2:]...)
+}
+
+func testASCII(s string) testIFDEntry {
+	return testIFDEntry{typ: EXIFASCII, count: uint32(len(s) + 1), data: append([]byte(s), 0)}
+}
+
+func testValues(order binary.ByteOrder, typ EXIFType, values ...uint64) testIFDEntry {
+	size := int(typ.size())
+	data := make([]byte, len(values)*size)
+	for i, v := range values {
+		switch size {
+		case 1:
+			data[i] = uint8(v)
+		case 2:
+			order.PutUint16(data[i*2:], uint16(v))
+		case 4:
+			order.PutUint32(data[i*4:], uint32(v))
+		case 8:
+			order.PutUint64(data[i*8:], v)
+		}
+	}
+	return testIFDEntry{typ: typ, count: uint32(len(values)), data: data}
+}
+
+func testRationals(order binary.ByteOrder, typ EXIFType, values ...[2]int32) testIFDEntry {
+	data := make([]byte, len(values)*8)
+	for i, v := range values {
+		order.PutUint32(data[i*8:], uint32(v[0]))
+		order.PutUint32(data[i*8+4:], uint32(v[1]))
+	}
+	return testIFDEntry{typ: typ, count: uint32(len(values)), data: data}
+}
+
+func withID(id uint16, e testIFDEntry) testIFDEntry {
+	e.id = id
+	return e
+}
+
+// makeTestEXIF returns the IFDs of a typical camera picture.
+func makeTestEXIF(order binary.ByteOrder) *testIFD {
+	thumbnail := []byte("\xff\xd8thumbnail\xff\xd9")
+	return &testIFD{
+		entries: []testIFDEntry{
+			withID(TagMake, testASCII("Canon")),
+			withID(TagModel, testASCII("Canon EOS 5D Mark IV")),
+			withID(TagOrientation, testValues(order, EXIFShort, 6)),
+			withID(0xff00, testValues(order, EXIFSByte, 0xfe, 3)),
+			withID(0xff01, testValues(order, EXIFSShort, 0xfffe, 3, 5)),
+			withID(0xff02, testValues(order, EXIFSLong, 0xfffffffe)),
+			withID(0xff03, testRationals(order, EXIFSRational, [2]int32{-3, 4})),
+			withID(0xff04, testValues(order, EXIFFloat, uint64(math.Float32bits(1.5)))),
+			withID(0xff05, testValues(order, EXIFDouble, math.Float64bits(-2.25), math.Float64bits(3))),
+			withID(0xff06, testValues(order, EXIFUndefined, 1, 2, 3, 4, 5)),
+			withID(0xff07, testIFDEntry{typ: 99, count: 1}),
+		},
+		subs: map[uint16]*testIFD{
+			TagExifIFDPointer: {
+				entries: []testIFDEntry{
+					withID(TagExposureTime, testRationals(order, EXIFRational, [2]int32{1, 250})),
+					withID(TagFNumber, testRationals(order, EXIFRational, [2]int32{28, 10})),
+					withID(TagISOSpeedRatings, testValues(order, EXIFShort, 400)),
+					withID(TagDateTimeOriginal, testASCII("2023:04:05 06:07:08")),
+					withID(TagOffsetTimeOriginal, testASCII("+09:00")),
+					withID(TagSubSecTimeOriginal, testASCII("25")),
+				},
+				subs: map[uint16]*testIFD{
+					TagInteropIFDPointer: {
+						entries: []testIFDEntry{
+							withID(0x0001, testASCII("R98")),
+						},
+					},
+				},
+			},
+			TagGPSIFDPointer: {
+				entries: []testIFDEntry{
+					withID(TagGPSLatitudeRef, testASCII("S")),
+					withID(TagGPSLatitude, testRationals(order, EXIFRational, [2]int32{33, 1}, [2]int32{51, 1}, [2]int32{3600, 100})),
+					withID(TagGPSLongitudeRef, testASCII("E")),
+					withID(TagGPSLongitude, testRationals(order, EXIFRational, [2]int32{151, 1}, [2]int32{12, 1}, [2]int32{36, 1})),
+				},
+			},
+		},
+		next: &testIFD{
+			entries: []testIFDEntry{
+				withID(TagJPEGInterchangeFormat, testValues(order, EXIFLong, 8)),
+				withID(TagJPEGInterchangeFormatLength, testValues(order, EXIFLong, uint64(len(thumbnail)))),
+				withID(0xff08, testIFDEntry{typ: EXIFUndefined, count: uint32(len(thumbnail)), data: thumbnail}),
+			},
+		},
+	}
+}
+
+func TestReadEXIF(t *testing.T) {
+	t.Parallel()
+
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		order := order
+
+		t.Run(order.String(), func(t *testing.T) {
+			t.Parallel()
+
+			root := makeTestEXIF(order)
+			tiff := makeTestTIFF(order, root)
+			// The thumbnail offset points to the value of the private tag 0xff08.
+			thumbnailOffset := bytes.Index(tiff, []byte("\xff\xd8thumbnail"))
+			root.next.entries[0] = withID(TagJPEGInterchangeFormat, testValues(order, EXIFLong, uint64(thumbnailOffset)))
+			tiff = makeTestTIFF(order, root)
+
+			e, err := ReadEXIF(bytes.NewReader(makeEXIFJPEG(t, tiff)))
+			if err != nil {
+				t.Fatalf("ReadEXIF: %v", err)
+			}
+			if e.ByteOrder != order {
+				t.Fatalf("got byte order %v want %v", e.ByteOrder, order)
+			}
+
+			stringTags := []struct {
+				ifd  IFD
+				id   uint16
+				want string
+			}{
+				{IFD0, TagMake, "Canon"},
+				{IFD0, TagModel, "Canon EOS 5D Mark IV"},
+				{ExifIFD, TagDateTimeOriginal, "2023:04:05 06:07:08"},
+				{InteropIFD, 0x0001, "R98"},
+				{GPSIFD, TagGPSLatitudeRef, "S"},
+			}
+			for _, tc := range stringTags {
+				tag, ok := e.Tag(tc.ifd, tc.id)
+				if !ok {
+					t.Fatalf("tag 0x%04x not found", tc.id)
+				}
+				if got, err := tag.StringVal(); err != nil || got != tc.want {
+					t.Fatalf("tag 0x%04x: got %q, %v want %q", tc.id, got, err, tc.want)
+				}
+			}
+
+			floatTags := []struct {
+				ifd  IFD
+				id   uint16
+				i    int
+				want float64
+			}{
+				{IFD0, TagOrientation, 0, 6},
+				{IFD0, 0xff00, 0, -2},
+				{IFD0, 0xff00, 1, 3},
+				{IFD0, 0xff01, 0, -2},
+				{IFD0, 0xff01, 2, 5},
+				{IFD0, 0xff02, 0, -2},
+				{IFD0, 0xff03, 0, -0.75},
+				{IFD0, 0xff04, 0, 1.5},
+				{IFD0, 0xff05, 0, -2.25},
+				{IFD0, 0xff05, 1, 3},
+				{IFD0, 0xff06, 4, 5},
+				{ExifIFD, TagExposureTime, 0, 0.004},
+				{ExifIFD, TagFNumber, 0, 2.8},
+				{ExifIFD, TagISOSpeedRatings, 0, 400},
+			}
+			for _, tc := range floatTags {
+				tag, ok := e.Tag(tc.ifd, tc.id)
+				if !ok {
+					t.Fatalf("tag 0x%04x not found", tc.id)
+				}
+				if got, err := tag.Float(tc.i); err != nil || got != tc.want {
+					t.Fatalf("tag 0x%04x[%d]: got %v, %v want %v", tc.id, tc.i, got, err, tc.want)
+				}
+			}
+
+			if _, ok := e.Tag(IFD0, 0xff07); ok {
+				t.Fatal("got tag of unknown type")
+			}
+			tag, _ := e.Tag(ExifIFD, TagExposureTime)
+			if num, den, err := tag.Rat(0); err != nil || num != 1 || den != 250 {
+				t.Fatalf("got rational %d/%d, %v want 1/250", num, den, err)
+			}
+			tag, _ = e.Tag(IFD0, 0xff02)
+			if v, err := tag.Int(0); err != nil || v != -2 {
+				t.Fatalf("got %d, %v want -2", v, err)
+			}
+
+			if got := e.Orientation(); got != OrientationRotate270 {
+				t.Fatalf("got orientation %d want %d", got, OrientationRotate270)
+			}
+
+			tm, err := e.DateTimeOriginal()
+			if err != nil {
+				t.Fatalf("DateTimeOriginal: %v", err)
+			}
+			if got, want := tm.Format(time.RFC3339Nano), "2023-04-05T06:07:08.25+09:00"; got != want {
+				t.Fatalf("got time %s want %s", got, want)
+			}
+
+			lat, lon, err := e.GPS()
+			if err != nil {
+				t.Fatalf("GPS: %v", err)
+			}
+			if math.Abs(lat+33.86) > 1e-9 || math.Abs(lon-151.21) > 1e-9 {
+				t.Fatalf("got GPS %v, %v want -33.86, 151.21", lat, lon)
+			}
+
+			thumbnail, err := e.Thumbnail()
+			if err != nil {
+				t.Fatalf("Thumbnail: %v", err)
+			}
+			if string(thumbnail) != "\xff\xd8thumbnail\xff\xd9" {
+				t.Fatalf("got thumbnail %q", thumbnail)
+			}
+		})
+	}
+}
+
+func TestReadEXIFOrientation(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i <= 8; i++ {
+		path := fmt.Sprintf("testdata/orientation_%d.jpg", i)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%q: failed to read: %v", path, err)
+		}
+		e, err := ReadEXIF(bytes.NewReader(data))
+		if i == 0 {
+			if !errors.Is(err, ErrNoEXIF) {
+				t.Fatalf("%q: got error %v want %v", path, err, ErrNoEXIF)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: ReadEXIF: %v", path, err)
+		}
+		if got := e.Orientation(); got != Orientation(i) {
+			t.Fatalf("%q: got orientation %d want %d", path, got, i)
+		}
+	}
+}
+
+func TestReadEXIFErrors(t *testing.T) {
+	t.Parallel()
+
+	order := binary.BigEndian
+	valid := &testIFD{entries: []testIFDEntry{withID(TagOrientation, testValues(order, EXIFShort, 1))}}
+	// Point the Exif sub-directory at IFD0.
+	loopTIFF := makeTestTIFF(order, &testIFD{entries: []testIFDEntry{withID(TagExifIFDPointer, testValues(order, EXIFLong, 8))}})
+
+	testCases := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{
+			name: "not a JPEG",
+			data: []byte("\x89PNG\r\n\x1a\n"),
+			err:  ErrNoEXIF,
+		},
+		{
+			name: "no APP1",
+			data: []byte("\xff\xd8\xff\xda\x00\x02"),
+			err:  ErrNoEXIF,
+		},
+		{
+			name: "invalid byte order",
+			data: makeEXIFJPEG(t, []byte("XX\x00\x2a\x00\x00\x00\x08\x00\x00")),
+			err:  errInvalidEXIF,
+		},
+		{
+			name: "IFD0 out of range",
+			data: makeEXIFJPEG(t, []byte("MM\x00\x2a\x00\x00\x01\x00")),
+			err:  errInvalidEXIF,
+		},
+		{
+			name: "truncated IFD0",
+			data: makeEXIFJPEG(t, []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x02")),
+			err:  errInvalidEXIF,
+		},
+		{
+			name: "IFD loop",
+			data: makeEXIFJPEG(t, loopTIFF),
+			err:  errInvalidEXIF,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := ReadEXIF(bytes.NewReader(tc.data)); !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v want %v", err, tc.err)
+			}
+		})
+	}
+
+	// Truncated APP1 segment.
+	data := makeEXIFJPEG(t, makeTestTIFF(order, valid))
+	if _, err := ReadEXIF(bytes.NewReader(data[:20])); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
+
+func TestEXIFAccessorErrors(t *testing.T) {
+	t.Parallel()
+
+	order := binary.LittleEndian
+	root := &testIFD{
+		entries: []testIFDEntry{
+			withID(TagMake, testASCII("Canon")),
+			withID(TagOrientation, testValues(order, EXIFShort, 9)),
+			withID(0xff00, testRationals(order, EXIFRational, [2]int32{1, 0})),
+		},
+		subs: map[uint16]*testIFD{
+			TagExifIFDPointer: {
+				entries: []testIFDEntry{
+					withID(TagDateTimeOriginal, testASCII("not a date")),
+				},
+			},
+			TagGPSIFDPointer: {
+				entries: []testIFDEntry{
+					withID(TagGPSLatitude, testRationals(order, EXIFRational, [2]int32{1, 1})),
+				},
+			},
+		},
+	}
+	e, err := ReadEXIF(bytes.NewReader(makeEXIFJPEG(t, makeTestTIFF(order, root))))
+	if err != nil {
+		t.Fatalf("ReadEXIF: %v", err)
+	}
+
+	ascii, _ := e.Tag(IFD0, TagMake)
+	if _, err := ascii.Int(0); err == nil {
+		t.Fatal("Int of ASCII tag: expected error got nil")
+	}
+	if _, err := ascii.Float(0); err == nil {
+		t.Fatal("Float of ASCII tag: expected error got nil")
+	}
+	if _, _, err := ascii.Rat(0); err == nil {
+		t.Fatal("Rat of ASCII tag: expected error got nil")
+	}
+	orientation, _ := e.Tag(IFD0, TagOrientation)
+	if _, err := orientation.StringVal(); err == nil {
+		t.Fatal("StringVal of SHORT tag: expected error got nil")
+	}
+	if _, err := orientation.Int(1); err == nil {
+		t.Fatal("index out of range: expected error got nil")
+	}
+	if _, err := orientation.Int(-1); err == nil {
+		t.Fatal("negative index: expected error got nil")
+	}
+	zero, _ := e.Tag(IFD0, 0xff00)
+	if _, err := zero.Float(0); err == nil {
+		t.Fatal("zero denominator: expected error got nil")
+	}
+
+	if got := e.Orientation(); got != OrientationUnspecified {
+		t.Fatalf("got orientation %d want %d", got, OrientationUnspecified)
+	}
+	if _, err := e.DateTimeOriginal(); err == nil {
+		t.Fatal("invalid date: expected error got nil")
+	}
+	if _, _, err := e.GPS(); err == nil {
+		t.Fatal("short latitude: expected error got nil")
+	}
+	if _, err := e.Thumbnail(); !errors.Is(err, ErrEXIFTagNotFound) {
+		t.Fatalf("got error %v want %v", err, ErrEXIFTagNotFound)
+	}
+
+	empty := &EXIF{}
+	if _, err := empty.DateTimeOriginal(); !errors.Is(err, ErrEXIFTagNotFound) {
+		t.Fatalf("got error %v want %v", err, ErrEXIFTagNotFound)
+	}
+	if _, _, err := empty.GPS(); !errors.Is(err, ErrEXIFTagNotFound) {
+		t.Fatalf("got error %v want %v", err, ErrEXIFTagNotFound)
+	}
+}
+
+// addTIFFOrientation adds the orientation tag to IFD0 of the little-endian
+// TIFF image by appending a copy of the directory with the new tag.
+func addTIFFOrientation(data []byte, o Orientation) []byte {
+	order := binary.LittleEndian
+	offset := order.Uint32(data[4:8])
+	n := int(order.Uint16(data[offset:]))
+	entries := data[offset+2 : offset+2+uint32(n)*12]
+
+	ifd := order.AppendUint16(nil, uint16(n+1))
+	inserted := false
+	for i := 0; i < n; i++ {
+		entry := entries[i*12 : i*12+12]
+		if !inserted && order.Uint16(entry) > TagOrientation {
+			ifd = append(ifd, makeOrientationEntry(order, o)...)
+			inserted = true
+		}
+		ifd = append(ifd, entry...)
+	}
+	if !inserted {
+		ifd = append(ifd, makeOrientationEntry(order, o)...)
+	}
+	ifd = append(ifd, 0, 0, 0, 0)
+
+	out := append([]byte(nil), data...)
+	order.PutUint32(out[4:8], uint32(len(out)))
+	return append(out, ifd...)
+}
+
+func makeOrientationEntry(order binary.ByteOrder, o Orientation) []byte {
+	entry := make([]byte, 12)
+	order.PutUint16(entry[0:], TagOrientation)
+	order.PutUint16(entry[2:], uint16(EXIFShort))
+	order.PutUint32(entry[4:], 1)
+	order.PutUint16(entry[8:], uint16(o))
+	return entry
+}
+
+// addPNGEXIF inserts an eXIf chunk with the EXIF data after the IHDR chunk.
+func addPNGEXIF(data, exif []byte) []byte {
+	const ihdrEnd = 8 + 25
+	out := append([]byte(nil), data[:ihdrEnd]...)
+	out = appendPNGChunk(out, "eXIf", exif)
+	return append(out, data[ihdrEnd:]...)
+}
+
+// addWebPEXIF converts the simple format lossless WebP image to the extended
+// format and adds an EXIF chunk with the EXIF data.
+func addWebPEXIF(data, exif []byte, width, height int) []byte {
+	le := binary.LittleEndian
+	vp8x := []byte("VP8X\x0a\x00\x00\x00\x08\x00\x00\x00")
+	vp8x = append(vp8x, byte(width-1), byte((width-1)>>8), byte((width-1)>>16))
+	vp8x = append(vp8x, byte(height-1), byte((height-1)>>8), byte((height-1)>>16))
+
+	chunk := le.AppendUint32([]byte("EXIF"), uint32(len(exif)))
+	chunk = append(chunk, exif...)
+	if len(exif)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+
+	body := append([]byte("WEBP"), vp8x...)
+	body = append(body, data[12:]...)
+	body = append(body, chunk...)
+	out := le.AppendUint32([]byte("RIFF"), uint32(len(body)))
+	return append(out, body...)
+}
+
+func TestReadOrientationContainers(t *testing.T) {
+	t.Parallel()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 10)
+	}
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+
+	encode := func(format Format) []byte {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, format); err != nil {
+			t.Fatalf("failed to encode image: %v", err)
+		}
+		return buf.Bytes()
+	}
+	exifData := func(order binary.ByteOrder, o Orientation) []byte {
+		return makeTestTIFF(order, &testIFD{
+			entries: []testIFDEntry{
+				withID(TagMake, testASCII("Scanner")),
+				withID(TagOrientation, testValues(order, EXIFShort, uint64(o))),
+			},
+		})
+	}
+	tiffData, pngData, webpData := encode(TIFF), encode(PNG), encode(WEBP)
+
+	testCases := []struct {
+		name string
+		data []byte
+		want Orientation
+	}{
+		{
+			name: "TIFF",
+			data: addTIFFOrientation(tiffData, OrientationRotate270),
+			want: OrientationRotate270,
+		},
+		{
+			name: "TIFF without orientation",
+			data: tiffData,
+			want: OrientationUnspecified,
+		},
+		{
+			name: "PNG eXIf",
+			data: addPNGEXIF(pngData, exifData(binary.BigEndian, OrientationTranspose)),
+			want: OrientationTranspose,
+		},
+		{
+			name: "PNG eXIf with EXIF header",
+			data: addPNGEXIF(pngData, append([]byte("Exif\x00\x00"), exifData(binary.LittleEndian, OrientationFlipV)...)),
+			want: OrientationFlipV,
+		},
+		{
+			name: "PNG without eXIf",
+			data: pngData,
+			want: OrientationUnspecified,
+		},
+		{
+			name: "WebP EXIF",
+			data: addWebPEXIF(webpData, exifData(binary.LittleEndian, OrientationRotate90), 3, 2),
+			want: OrientationRotate90,
+		},
+		{
+			name: "WebP without EXIF",
+			data: webpData,
+			want: OrientationUnspecified,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ReadOrientation(bytes.NewReader(tc.data)); got != tc.want {
+				t.Fatalf("got orientation %d want %d", got, tc.want)
+			}
+
+			e, err := ReadEXIF(bytes.NewReader(tc.data))
+			switch {
+			case err == nil:
+				if got := e.Orientation(); got != tc.want {
+					t.Fatalf("ReadEXIF: got orientation %d want %d", got, tc.want)
+				}
+			case tc.want != OrientationUnspecified || !errors.Is(err, ErrNoEXIF):
+				t.Fatalf("ReadEXIF: %v", err)
+			}
+
+			got, err := Decode(bytes.NewReader(tc.data), AutoOrientation(true))
+			if err != nil {
+				t.Fatalf("failed to decode image: %v", err)
+			}
+			if want := Clone(FixOrientation(img, tc.want)); !compareNRGBA(Clone(got), want, 0) {
+				t.Fatal("image is not correctly oriented")
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// errUnsupportedICC is returned for ICC profiles that are not RGB matrix/TRC profiles.
+var errUnsupportedICC = errors.New("imaging: unsupported ICC profile")
+
+// iccSRGBColorants are the colorants of the sRGB color space adapted to the
+// D50 illuminant of the ICC profile connection space (columns are red, green and blue).
+var iccSRGBColorants = [3][3]float64{ //nolint
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+// iccCurve is a tone reproduction curve mapping encoded values to linear values in [0, 1].
+type iccCurve struct {
+	// table holds the sampled curve, if not empty.
+	table []float64
+	// fn is the parametric function type (0 to 4) and params its parameters g, a, b, c, d, e, f.
+	fn     int
+	params [7]float64
+}
+
+// eval returns the linear value of the encoded value x in [0, 1].
+func (c *iccCurve) eval(x float64) float64 {
+	if len(c.table) > 0 {
+		pos := x * float64(len(c.table)-1)
+		i := int(pos)
+		if i >= len(c.table)-1 {
+			return c.table[len(c.table)-1]
+		}
+		frac := pos - float64(i)
+		return c.table[i]*(1-frac) + c.table[i+1]*frac
+	}
+
+	g, a, b, cc, d, e, f := c.params[0], c.params[1], c.params[2], c.params[3], c.params[4], c.params[5], c.params[6]
+	pow := func(v float64) float64 {
+		if v <= 0 {
+			return 0
+		}
+		return math.Pow(v, g)
+	}
+	var y float64
+	switch c.fn {
+	case 0:
+		y = pow(x)
+	case 1:
+		if a*x+b >= 0 {
+			y = pow(a*x + b)
+		}
+	case 2:
+		y = cc
+		if a*x+b >= 0 {
+			y += pow(a*x + b)
+		}
+	case 3:
+		if x >= d {
+			y = pow(a*x + b)
+		} else {
+			y = cc * x
+		}
+	case 4:
+		if x >= d {
+			y = pow(a*x+b) + e
+		} else {
+			y = cc*x + f
+		}
+	}
+	return clampUnit(y)
+}
+
+// iccProfile is an RGB matrix/TRC ICC profile.
+type iccProfile struct {
+	// colorants converts linear RGB values to the D50 XYZ profile connection space.
+	colorants [3][3]float64
+	curves    [3]iccCurve
+}
+
+// parseICC parses an ICC v2 or v4 RGB matrix/TRC profile.
+// errUnsupportedICC is returned for other profiles, e.g. CMYK or LUT-based profiles.
+func parseICC(data []byte) (*iccProfile, error) {
+	if len(data) < 132 {
+		return nil, errUnsupportedICC
+	}
+	if version := data[8]; version != 2 && version != 4 {
+		return nil, errUnsupportedICC
+	}
+	if string(data[16:20]) != "RGB " || string(data[20:24]) != "XYZ " {
+		return nil, errUnsupportedICC
+	}
+
+	tags := map[string][]byte{}
+	count := binary.BigEndian.Uint32(data[128:132])
+	if uint64(count)*12 > uint64(len(data)-132) {
+		return nil, errUnsupportedICC
+	}
+	for i := uint32(0); i < count; i++ {
+		entry := data[132+i*12:]
+		offset := uint64(binary.BigEndian.Uint32(entry[4:8]))
+		size := uint64(binary.BigEndian.Uint32(entry[8:12]))
+		if offset+size > uint64(len(data)) {
+			return nil, errUnsupportedICC
+		}
+		tags[string(entry[:4])] = data[offset : offset+size]
+	}
+
+	p := &iccProfile{}
+	for i, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		xyz, ok := tags[sig]
+		if !ok || len(xyz) < 20 || string(xyz[:4]) != "XYZ " {
+			return nil, errUnsupportedICC
+		}
+		for j := 0; j < 3; j++ {
+			p.colorants[j][i] = iccFixed(xyz[8+j*4:])
+		}
+	}
+	for i, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		trc, ok := tags[sig]
+		if !ok {
+			return nil, errUnsupportedICC
+		}
+		curve, err := parseICCCurve(trc)
+		if err != nil {
+			return nil, err
+		}
+		p.curves[i] = curve
+	}
+	return p, nil
+}
+
+// parseICCCurve parses a curv or para tag.
+func parseICCCurve(data []byte) (iccCurve, error) {
+	if len(data) < 12 {
+		return iccCurve{}, errUnsupportedICC
+	}
+	switch string(data[:4]) {
+	case "curv":
+		n := uint64(binary.BigEndian.Uint32(data[8:12]))
+		if n*2 > uint64(len(data)-12) {
+			return iccCurve{}, errUnsupportedICC
+		}
+		switch n {
+		case 0:
+			// Identity.
+			return iccCurve{params: [7]float64{1}}, nil
+		case 1:
+			// Gamma stored as a u8Fixed8Number.
+			return iccCurve{params: [7]float64{float64(binary.BigEndian.Uint16(data[12:])) / 256}}, nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(data[12+i*2:])) / 0xffff
+		}
+		return iccCurve{table: table}, nil
+
+	case "para":
+		fn := int(binary.BigEndian.Uint16(data[8:10]))
+		numParams := []int{1, 3, 4, 5, 7}
+		if fn >= len(numParams) || len(data) < 12+numParams[fn]*4 {
+			return iccCurve{}, errUnsupportedICC
+		}
+		c := iccCurve{fn: fn}
+		for i := 0; i < numParams[fn]; i++ {
+			c.params[i] = iccFixed(data[12+i*4:])
+		}
+		if fn != 0 && c.params[1] == 0 {
+			return iccCurve{}, errUnsupportedICC
+		}
+		return c, nil
+	}
+	return iccCurve{}, errUnsupportedICC
+}
+
+// iccFixed returns the value of an s15Fixed16Number.
+func iccFixed(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// isSRGB reports whether the profile is equivalent to sRGB, so that no conversion is needed.
+func (p *iccProfile) isSRGB() bool {
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if math.Abs(p.colorants[i][j]-iccSRGBColorants[i][j]) > 0.002 {
+				return false
+			}
+		}
+	}
+	for _, c := range p.curves {
+		for x := 0.0; x <= 1; x += 1.0 / 32 {
+			if math.Abs(c.eval(x)-srgbToLinear(x)) > 0.002 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matrix returns the matrix converting linear RGB values of the profile to linear sRGB values.
+func (p *iccProfile) matrix() [3][3]float64 {
+	inv := invert3x3(iccSRGBColorants)
+	var m [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += inv[i][k] * p.colorants[k][j]
+			}
+		}
+	}
+	return m
+}
+
+// invert3x3 returns the inverse of the 3x3 matrix.
+func invert3x3(m [3][3]float64) [3][3]float64 {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	return [3][3]float64{
+		{
+			(m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det,
+			(m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det,
+			(m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det,
+		},
+		{
+			(m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det,
+			(m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det,
+			(m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det,
+		},
+		{
+			(m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det,
+			(m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det,
+			(m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det,
+		},
+	}
+}
+
+// srgbToLinear converts an sRGB encoded value in [0, 1] to a linear value.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts a linear value in [0, 1] to an sRGB encoded value.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// clampUnit clamps v to the range [0, 1].
+func clampUnit(v float64) float64 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 1:
+		return 1
+	}
+	return v
+}
+
+// iccEncodeTableSize is the number of entries of the linear to sRGB lookup table.
+const iccEncodeTableSize = 1 << 14
+
+// iccTransform converts colors from the profile color space to sRGB.
+type iccTransform struct {
+	// decode maps the encoded values of each channel to linear values.
+	decode [3][]float64
+	m      [3][3]float64
+	// encode maps linear values to 16-bit sRGB encoded values.
+	encode []uint16
+}
+
+// newICCTransform returns the transform of the profile for input values of the given bit depth.
+func newICCTransform(p *iccProfile, depth int) *iccTransform {
+	t := &iccTransform{m: p.matrix()}
+	n := 1 << depth
+	for c := range t.decode {
+		t.decode[c] = make([]float64, n)
+		for i := range t.decode[c] {
+			t.decode[c][i] = p.curves[c].eval(float64(i) / float64(n-1))
+		}
+	}
+	t.encode = make([]uint16, iccEncodeTableSize)
+	for i := range t.encode {
+		t.encode[i] = uint16(linearToSRGB(float64(i)/(iccEncodeTableSize-1))*0xffff + 0.5)
+	}
+	return t
+}
+
+// convert returns the 16-bit sRGB values of the color with the encoded channel values.
+func (t *iccTransform) convert(r, g, b int) (uint16, uint16, uint16) {
+	lr, lg, lb := t.decode[0][r], t.decode[1][g], t.decode[2][b]
+	var out [3]uint16
+	for i := range out {
+		v := t.m[i][0]*lr + t.m[i][1]*lg + t.m[i][2]*lb
+		out[i] = t.encode[int(clampUnit(v)*(iccEncodeTableSize-1)+0.5)]
+	}
+	return out[0], out[1], out[2]
+}
+
+// convertToSRGB returns the image converted from the color space of the profile to sRGB.
+// 16-bit images are converted to *image.NRGBA64, other images to *image.NRGBA.
+// An *image.NRGBA image is converted in place.
+func (p *iccProfile) convertToSRGB(img image.Image) image.Image {
+	if bitDepth(img.ColorModel()) == 16 {
+		return p.convertToSRGB16(img)
+	}
+
+	t := newICCTransform(p, 8)
+	dst := toNRGBA(img)
+	parallel(0, dst.Bounds().Dy(), func(ys <-chan int) {
+		for y := range ys {
+			row := dst.Pix[y*dst.Stride : y*dst.Stride+dst.Rect.Dx()*4]
+			for i := 0; i < len(row); i += 4 {
+				r, g, b := t.convert(int(row[i]), int(row[i+1]), int(row[i+2]))
+				row[i], row[i+1], row[i+2] = to8Bit(r), to8Bit(g), to8Bit(b)
+			}
+		}
+	})
+	return dst
+}
+
+// to8Bit rounds the 16-bit value to 8 bits.
+func to8Bit(v uint16) uint8 {
+	return uint8((uint32(v) + 128) / 257)
+}
+
+// convertToSRGB16 returns the 16-bit image converted to sRGB as an *image.NRGBA64.
+func (p *iccProfile) convertToSRGB16(img image.Image) *image.NRGBA64 {
+	t := newICCTransform(p, 16)
+	bounds := img.Bounds()
+	dst := image.NewNRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	parallel(0, bounds.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < bounds.Dx(); x++ {
+				c := color.NRGBA64Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA64)
+				c.R, c.G, c.B = t.convert(int(c.R), int(c.G), int(c.B))
+				dst.SetNRGBA64(x, y, c)
+			}
+		}
+	})
+	return dst
+}
+
+// ConvertToSRGB returns a DecodeOption that converts the decoded image to the sRGB
+// color space using the ICC profile embedded in JPEG (APP2 segments) and PNG (iCCP chunk)
+// images. ICC v2 and v4 RGB matrix/TRC profiles, such as Adobe RGB and Display P3, are
+// supported. Images without a profile, with an sRGB profile or with an unsupported
+// profile are returned unchanged. Converted images are returned as *image.NRGBA, or as
+// *image.NRGBA64 for 16-bit images. By default it's disabled.
+//
+// Example:
+//
+//	// Display P3 photos from phones are converted to sRGB before resizing.
+//	img, err := imaging.Open("photo.jpg", imaging.ConvertToSRGB(true))
+func ConvertToSRGB(enabled bool) DecodeOption {
+	return func(c *decodeConfig) {
+		c.convertToSRGB = enabled
+	}
+}
+
+// decodeToSRGB reads an image from io.Reader using the decode config and
+// converts it to sRGB using its ICC profile.
+func decodeToSRGB(r io.Reader, cfg *decodeConfig) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	plain := *cfg
+	plain.convertToSRGB = false
+	// The number of pixels has already been checked.
+	plain.maxPixels = 0
+	img, err := decode(bytes.NewReader(data), &plain)
+	if err != nil {
+		return nil, err
+	}
+
+	p := embeddedICCProfile(data)
+	if p == nil || p.isSRGB() {
+		return img, nil
+	}
+	return p.convertToSRGB(img), nil
+}
+
+// embeddedICCProfile returns the supported ICC profile embedded in the image data, or nil.
+func embeddedICCProfile(data []byte) *iccProfile {
+	m, err := ReadMetadata(bytes.NewReader(data))
+	if err != nil || len(m.ICC) == 0 {
+		return nil
+	}
+	p, err := parseICC(m.ICC)
+	if err != nil {
+		return nil
+	}
+	return p
+}
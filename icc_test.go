@@ -0,0 +1,362 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// testAdobeRGBColorants are the D50 colorants of the Adobe RGB (1998) profile.
+var testAdobeRGBColorants = [3][3]float64{ //nolint
+	{0.60974, 0.20528, 0.14919},
+	{0.31111, 0.62567, 0.06322},
+	{0.01947, 0.06087, 0.74457},
+}
+
+// testICCGamma returns a curv tag with a single gamma value.
+func testICCGamma(gamma float64) []byte {
+	data := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01")
+	return binary.BigEndian.AppendUint16(data, uint16(gamma*256+0.5))
+}
+
+// testICCPara returns a para tag of the function type with the parameters.
+func testICCPara(fn uint16, params ...float64) []byte {
+	data := binary.BigEndian.AppendUint16([]byte("para\x00\x00\x00\x00"), fn)
+	data = append(data, 0, 0)
+	for _, p := range params {
+		data = binary.BigEndian.AppendUint32(data, uint32(int32(math.Round(p*65536))))
+	}
+	return data
+}
+
+// testICCSRGBCurve returns the sRGB tone reproduction curve as a para tag.
+func testICCSRGBCurve() []byte {
+	return testICCPara(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045)
+}
+
+// makeTestICC returns an RGB matrix/TRC ICC profile. The same curve is used for all channels.
+func makeTestICC(version byte, colorants [3][3]float64, curve []byte) []byte {
+	type tag struct {
+		sig  string
+		data []byte
+	}
+	var tags []tag
+	for i, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		data := []byte("XYZ \x00\x00\x00\x00")
+		for j := 0; j < 3; j++ {
+			data = binary.BigEndian.AppendUint32(data, uint32(int32(math.Round(colorants[j][i]*65536))))
+		}
+		tags = append(tags, tag{sig, data})
+	}
+	for _, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		tags = append(tags, tag{sig, curve})
+	}
+
+	header := make([]byte, 128)
+	header[8] = version
+	copy(header[12:], "mntrRGB XYZ ")
+	copy(header[36:], "acsp")
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	var data []byte
+	offset := len(header) + 4 + len(tags)*12
+	for _, t := range tags {
+		table = append(table, t.sig...)
+		table = binary.BigEndian.AppendUint32(table, uint32(offset+len(data)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(t.data)))
+		data = append(data, t.data...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+	profile := append(append(header, table...), data...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+// testAdobeRGBToSRGB converts the Adobe RGB color to sRGB using the D65 matrices
+// of the color spaces rather than the D50 colorants of the profiles.
+func testAdobeRGBToSRGB(c color.NRGBA) color.NRGBA {
+	adobe := [3][3]float64{
+		{0.5767309, 0.1855540, 0.1881852},
+		{0.2973769, 0.6273491, 0.0752741},
+		{0.0270343, 0.0706872, 0.9911085},
+	}
+	srgb := [3][3]float64{
+		{3.2404542, -1.5371385, -0.4985314},
+		{-0.9692660, 1.8760108, 0.0415560},
+		{0.0556434, -0.2040259, 1.0572252},
+	}
+	in := [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+	var xyz, out [3]float64
+	for i := range xyz {
+		for j := range in {
+			xyz[i] += adobe[i][j] * math.Pow(in[j]/255, 563.0/256)
+		}
+	}
+	for i := range out {
+		for j := range xyz {
+			out[i] += srgb[i][j] * xyz[j]
+		}
+		out[i] = math.Round(linearToSRGB(clampUnit(out[i])) * 255)
+	}
+	return color.NRGBA{uint8(out[0]), uint8(out[1]), uint8(out[2]), c.A}
+}
+
+func TestICCCurves(t *testing.T) {
+	t.Parallel()
+
+	table := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x40\x00\xff\xff")
+	testCases := []struct {
+		name string
+		data []byte
+		want [5]float64 // values at 0, 0.25, 0.5, 0.75 and 1
+	}{
+		{"identity", []byte("curv\x00\x00\x00\x00\x00\x00\x00\x00"), [5]float64{0, 0.25, 0.5, 0.75, 1}},
+		{"gamma", testICCGamma(2), [5]float64{0, 0.0625, 0.25, 0.5625, 1}},
+		{"table", table, [5]float64{0, 0.125, 0.25, 0.625, 1}},
+		{"para 0", testICCPara(0, 2), [5]float64{0, 0.0625, 0.25, 0.5625, 1}},
+		{"para 1", testICCPara(1, 1, 2, -0.5), [5]float64{0, 0, 0.5, 1, 1}},
+		{"para 2", testICCPara(2, 1, 2, -1, 0.25), [5]float64{0.25, 0.25, 0.25, 0.75, 1}},
+		{"para 3", testICCPara(3, 1, 1, 0, 2, 0.5), [5]float64{0, 0.5, 0.5, 0.75, 1}},
+		{"para 4", testICCPara(4, 1, 1, -0.25, 1, 0.5, 0.25, 0.125), [5]float64{0.125, 0.375, 0.5, 0.75, 1}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := parseICCCurve(tc.data)
+			if err != nil {
+				t.Fatalf("parseICCCurve: %v", err)
+			}
+			for i, want := range tc.want {
+				if got := c.eval(float64(i) / 4); math.Abs(got-want) > 1e-4 {
+					t.Fatalf("got value %f at %f want %f", got, float64(i)/4, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseICCErrors(t *testing.T) {
+	t.Parallel()
+
+	valid := makeTestICC(4, testAdobeRGBColorants, testICCGamma(2.2))
+	modify := func(fn func(p []byte) []byte) []byte {
+		return fn(append([]byte(nil), valid...))
+	}
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"short", valid[:100]},
+		{"version", modify(func(p []byte) []byte { p[8] = 5; return p })},
+		{"CMYK", modify(func(p []byte) []byte { copy(p[16:], "CMYK"); return p })},
+		{"Lab PCS", modify(func(p []byte) []byte { copy(p[20:], "Lab "); return p })},
+		{"tag count", modify(func(p []byte) []byte { binary.BigEndian.PutUint32(p[128:], 1000); return p })},
+		{"tag offset", modify(func(p []byte) []byte { binary.BigEndian.PutUint32(p[136:], 1<<20); return p })},
+		{"missing XYZ tag", modify(func(p []byte) []byte { copy(p[132:], "xXYZ"); return p })},
+		{"invalid XYZ tag", modify(func(p []byte) []byte { copy(p[len(p)-108:], "abcd"); return p })},
+		{"missing TRC tag", modify(func(p []byte) []byte { copy(p[132+5*12:], "xTRC"); return p })},
+		{"invalid TRC tag", modify(func(p []byte) []byte { copy(p[len(p)-16:], "abcd"); return p })},
+		{"truncated TRC tag", modify(func(p []byte) []byte { binary.BigEndian.PutUint32(p[132+5*12+8:], 8); return p })},
+		{"truncated curv table", makeTestICC(2, testAdobeRGBColorants, []byte("curv\x00\x00\x00\x00\x00\x00\x00\x05\x00"))},
+		{"para function", makeTestICC(4, testAdobeRGBColorants, testICCPara(5, 1))},
+		{"para parameters", makeTestICC(4, testAdobeRGBColorants, testICCPara(4, 1, 1))},
+		{"para zero a", makeTestICC(4, testAdobeRGBColorants, testICCPara(1, 1, 0, 0))},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := parseICC(tc.data); err == nil {
+				t.Fatal("expected error got nil")
+			}
+		})
+	}
+}
+
+func TestICCConvertAdobeRGB(t *testing.T) {
+	t.Parallel()
+
+	p, err := parseICC(makeTestICC(2, testAdobeRGBColorants, testICCGamma(563.0/256)))
+	if err != nil {
+		t.Fatalf("parseICC: %v", err)
+	}
+	if p.isSRGB() {
+		t.Fatal("Adobe RGB profile detected as sRGB")
+	}
+
+	colors := []color.NRGBA{
+		{0, 0, 0, 255},
+		{255, 255, 255, 255},
+		{128, 128, 128, 128},
+		{200, 100, 50, 255},
+		{30, 160, 90, 0},
+		{90, 120, 240, 255},
+	}
+	src := image.NewNRGBA(image.Rect(0, 0, len(colors), 1))
+	for i, c := range colors {
+		src.SetNRGBA(i, 0, c)
+	}
+	dst := p.convertToSRGB(src).(*image.NRGBA)
+	for i, c := range colors {
+		want := testAdobeRGBToSRGB(c)
+		got := dst.NRGBAAt(i, 0)
+		if absInt(int(got.R)-int(want.R)) > 1 || absInt(int(got.G)-int(want.G)) > 1 ||
+			absInt(int(got.B)-int(want.B)) > 1 || got.A != want.A {
+			t.Fatalf("color %v: got %v want %v", c, got, want)
+		}
+		// Gray colors stay gray.
+		if c.R == c.G && c.G == c.B && (got.R != got.G || got.G != got.B) {
+			t.Fatalf("color %v: got %v", c, got)
+		}
+	}
+}
+
+func TestICCSRGBProfile(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []byte{2, 4} {
+		p, err := parseICC(makeTestICC(version, iccSRGBColorants, testICCSRGBCurve()))
+		if err != nil {
+			t.Fatalf("parseICC: %v", err)
+		}
+		if !p.isSRGB() {
+			t.Fatal("sRGB profile not detected")
+		}
+		// The conversion is close to the identity anyway.
+		dst := p.convertToSRGB(Clone(testdataBranchesPNG)).(*image.NRGBA)
+		if !compareNRGBA(dst, Clone(testdataBranchesPNG), 1) {
+			t.Fatal("sRGB conversion modified the image")
+		}
+	}
+}
+
+func TestDecodeConvertToSRGB(t *testing.T) {
+	t.Parallel()
+
+	adobe := makeTestICC(2, testAdobeRGBColorants, testICCGamma(563.0/256))
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3] = uint8(i*8), uint8(255-i*4), 100, uint8(128+i)
+	}
+
+	encode := func(format Format, img image.Image, icc []byte) []byte {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, format, WithMetadata(&Metadata{ICC: icc})); err != nil {
+			t.Fatalf("failed to encode %v: %v", format, err)
+		}
+		return buf.Bytes()
+	}
+
+	testCases := []struct {
+		name      string
+		data      []byte
+		converted bool
+	}{
+		{"JPEG Adobe RGB", encode(JPEG, src, adobe), true},
+		{"PNG Adobe RGB", encode(PNG, src, adobe), true},
+		{"PNG no profile", encode(PNG, src, nil), false},
+		{"PNG sRGB profile", encode(PNG, src, makeTestICC(4, iccSRGBColorants, testICCSRGBCurve())), false},
+		{"PNG unsupported profile", encode(PNG, src, adobe[:200]), false},
+		{"GIF", encode(GIF, src, adobe), false},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			plain, err := Decode(bytes.NewReader(tc.data))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			img, err := Decode(bytes.NewReader(tc.data), ConvertToSRGB(true))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			want := Clone(plain)
+			if tc.converted {
+				for i := 0; i < len(want.Pix); i += 4 {
+					c := testAdobeRGBToSRGB(color.NRGBA{want.Pix[i], want.Pix[i+1], want.Pix[i+2], want.Pix[i+3]})
+					want.Pix[i], want.Pix[i+1], want.Pix[i+2] = c.R, c.G, c.B
+				}
+			}
+			if !compareNRGBA(Clone(img), want, 1) {
+				t.Fatal("unexpected colors")
+			}
+		})
+	}
+}
+
+func TestDecodeConvertToSRGB16(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA64(image.Rect(0, 0, 3, 1))
+	src.SetNRGBA64(0, 0, color.NRGBA64{0x8080, 0x8080, 0x8080, 0xffff})
+	src.SetNRGBA64(1, 0, color.NRGBA64{0xc8c8, 0x6464, 0x3232, 0x8000})
+	src.SetNRGBA64(2, 0, color.NRGBA64{0xffff, 0xffff, 0xffff, 0xffff})
+	buf := &bytes.Buffer{}
+	m := &Metadata{ICC: makeTestICC(4, testAdobeRGBColorants, testICCGamma(563.0/256))}
+	if err := Encode(buf, src, PNG, WithMetadata(m)); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+
+	img, err := Decode(buf, ConvertToSRGB(true))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	dst, ok := img.(*image.NRGBA64)
+	if !ok {
+		t.Fatalf("got image type %T want *image.NRGBA64", img)
+	}
+	for x := 0; x < 3; x++ {
+		c := src.NRGBA64At(x, 0)
+		want := testAdobeRGBToSRGB(color.NRGBA{uint8(c.R >> 8), uint8(c.G >> 8), uint8(c.B >> 8), uint8(c.A >> 8)})
+		got := dst.NRGBA64At(x, 0)
+		if absInt(int(got.R>>8)-int(want.R)) > 1 || absInt(int(got.G>>8)-int(want.G)) > 1 ||
+			absInt(int(got.B>>8)-int(want.B)) > 1 || got.A != c.A {
+			t.Fatalf("pixel %d: got %v want %v", x, got, want)
+		}
+	}
+}
+
+func TestDecodeConvertToSRGBOptions(t *testing.T) {
+	t.Parallel()
+
+	icc := makeTestICC(2, testAdobeRGBColorants, testICCGamma(563.0/256))
+	exif := makeTestTIFF(binary.BigEndian, &testIFD{
+		entries: []testIFDEntry{withID(TagOrientation, testValues(binary.BigEndian, EXIFShort, 6))},
+	})
+	data := makeMetadataJPEG(t, &Metadata{EXIF: exif, ICC: icc})
+
+	img, m, err := DecodeWithMetadata(bytes.NewReader(data), ConvertToSRGB(true), AutoOrientation(true))
+	if err != nil {
+		t.Fatalf("DecodeWithMetadata: %v", err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(4, 8) {
+		t.Fatalf("got size %v want (4,8)", size)
+	}
+	if m.ICC != nil || m.EXIF == nil {
+		t.Fatal("ICC profile of the converted image returned")
+	}
+
+	if _, m, err = DecodeWithMetadata(bytes.NewReader(data)); err != nil || m.ICC == nil {
+		t.Fatalf("ICC profile not returned: %v", err)
+	}
+	if _, err := Decode(bytes.NewReader(data), ConvertToSRGB(true), MaxPixels(10)); err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if _, err := Decode(bytes.NewReader(data[:100]), ConvertToSRGB(true)); err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if _, err := Decode(bytes.NewReader(data), ConvertToSRGB(true), MaxInputBytes(100)); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
@@ -0,0 +1,99 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// ImageInfo holds the information about an image that is available
+// without decoding the whole image data.
+type ImageInfo struct {
+	// Width and Height are the dimensions of the image as stored in the file.
+	Width, Height int
+	// OrientedWidth and OrientedHeight are the dimensions of the image after
+	// the transformation specified by the EXIF orientation tag is applied,
+	// i.e. the dimensions of the image decoded with AutoOrientation(true).
+	OrientedWidth, OrientedHeight int
+	// Format is the format detected from the image data.
+	Format Format
+	// ColorModel is the color model of the decoded image.
+	ColorModel color.Model
+	// BitDepth is the number of bits per color channel of the color model (8 or 16).
+	BitDepth int
+	// Orientation is the EXIF orientation tag value, OrientationUnspecified if not present.
+	Orientation Orientation
+}
+
+// Info reads the image information from file without decoding the pixel data.
+//
+// Example:
+//
+//	// Reject images larger than 10 megapixels before decoding them.
+//	info, err := imaging.Info("test.jpg")
+//	if err != nil {
+//		return err
+//	}
+//	if info.Width*info.Height > 10_000_000 {
+//		return errors.New("image is too large")
+//	}
+func Info(filename string) (info ImageInfo, err error) {
+	file, err := fs.Open(filename)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	return DecodeInfo(file)
+}
+
+// DecodeInfo reads the image information from io.Reader. Only the image
+// header and the EXIF data are read, the pixel data is not decoded.
+func DecodeInfo(r io.Reader) (ImageInfo, error) {
+	format, r, err := DetectFormat(r)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+
+	// Keep the header bytes read by DecodeConfig to look for the orientation tag.
+	header := &bytes.Buffer{}
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, header))
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	orientation := ReadOrientation(io.MultiReader(header, r))
+
+	info := ImageInfo{
+		Width:          cfg.Width,
+		Height:         cfg.Height,
+		OrientedWidth:  cfg.Width,
+		OrientedHeight: cfg.Height,
+		Format:         format,
+		ColorModel:     cfg.ColorModel,
+		BitDepth:       bitDepth(cfg.ColorModel),
+		Orientation:    orientation,
+	}
+	switch orientation {
+	case OrientationTranspose, OrientationRotate270, OrientationTransverse, OrientationRotate90:
+		info.OrientedWidth, info.OrientedHeight = cfg.Height, cfg.Width
+	}
+	return info, nil
+}
+
+// bitDepth returns the number of bits per color channel of the color model.
+func bitDepth(m color.Model) int {
+	switch m {
+	case color.RGBA64Model, color.NRGBA64Model, color.Alpha16Model, color.Gray16Model:
+		return 16
+	}
+	return 8
+}
@@ -0,0 +1,154 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestInfoOrientation(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i <= 8; i++ {
+		i := i
+		path := fmt.Sprintf("testdata/orientation_%d.jpg", i)
+
+		t.Run(path, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := Info(path)
+			if err != nil {
+				t.Fatalf("Info(%q): %v", path, err)
+			}
+			if info.Orientation != Orientation(i) {
+				t.Fatalf("got orientation %d want %d", info.Orientation, i)
+			}
+			if info.Format != JPEG {
+				t.Fatalf("got format %v want %v", info.Format, JPEG)
+			}
+
+			img, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open(%q): %v", path, err)
+			}
+			if info.Width != img.Bounds().Dx() || info.Height != img.Bounds().Dy() {
+				t.Fatalf("got size %dx%d want %v", info.Width, info.Height, img.Bounds().Size())
+			}
+
+			oriented, err := Open(path, AutoOrientation(true))
+			if err != nil {
+				t.Fatalf("Open(%q): %v", path, err)
+			}
+			if info.OrientedWidth != oriented.Bounds().Dx() || info.OrientedHeight != oriented.Bounds().Dy() {
+				t.Fatalf("got oriented size %dx%d want %v", info.OrientedWidth, info.OrientedHeight, oriented.Bounds().Size())
+			}
+		})
+	}
+}
+
+func TestDecodeInfo(t *testing.T) {
+	t.Parallel()
+
+	gray16 := image.NewGray16(image.Rect(0, 0, 7, 3))
+	nrgba64 := image.NewNRGBA64(image.Rect(0, 0, 5, 9))
+	nrgba64.SetNRGBA64(1, 1, color.NRGBA64{1, 2, 3, 4})
+
+	testCases := []struct {
+		name       string
+		img        image.Image
+		format     Format
+		colorModel color.Model
+		bitDepth   int
+	}{
+		{
+			name:       "png gray",
+			img:        image.NewGray(image.Rect(0, 0, 3, 2)),
+			format:     PNG,
+			colorModel: color.GrayModel,
+			bitDepth:   8,
+		},
+		{
+			name:       "png gray16",
+			img:        gray16,
+			format:     PNG,
+			colorModel: color.Gray16Model,
+			bitDepth:   16,
+		},
+		{
+			name:       "png nrgba64",
+			img:        nrgba64,
+			format:     PNG,
+			colorModel: color.NRGBA64Model,
+			bitDepth:   16,
+		},
+		{
+			name:       "jpeg",
+			img:        testdataBranchesPNG,
+			format:     JPEG,
+			colorModel: color.YCbCrModel,
+			bitDepth:   8,
+		},
+		{
+			name:       "bmp",
+			img:        testdataBranchesPNG,
+			format:     BMP,
+			colorModel: color.RGBAModel,
+			bitDepth:   8,
+		},
+		{
+			name:       "webp",
+			img:        testdataFlowersSmallPNG,
+			format:     WEBP,
+			colorModel: color.NRGBAModel,
+			bitDepth:   8,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, tc.img, tc.format); err != nil {
+				t.Fatalf("failed to encode image: %v", err)
+			}
+			info, err := DecodeInfo(buf)
+			if err != nil {
+				t.Fatalf("DecodeInfo: %v", err)
+			}
+			size := tc.img.Bounds().Size()
+			want := ImageInfo{
+				Width:          size.X,
+				Height:         size.Y,
+				OrientedWidth:  size.X,
+				OrientedHeight: size.Y,
+				Format:         tc.format,
+				ColorModel:     tc.colorModel,
+				BitDepth:       tc.bitDepth,
+				Orientation:    OrientationUnspecified,
+			}
+			if info != want {
+				t.Fatalf("got info %+v want %+v", info, want)
+			}
+		})
+	}
+}
+
+func TestDecodeInfoErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := DecodeInfo(strings.NewReader("invalid data")); err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if _, err := DecodeInfo(strings.NewReader("\x89PNG\r\n\x1a\ntruncated")); err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if _, err := Info("testdata/missing.jpg"); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
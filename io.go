@@ -1,6 +1,8 @@
 package imaging
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"image"
@@ -14,6 +16,7 @@ import (
 
 	"golang.org/x/image/bmp"
 	"golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp" // register the WebP decoder for image.Decode
 	"golang.org/x/sync/errgroup"
 )
 
@@ -48,11 +51,20 @@ func Open(filename string, opts ...DecodeOption) (img image.Image, err error) {
 type decodeConfig struct {
 	// autoOrientation enables or disables the auto-orientation mode.
 	autoOrientation bool
+	// maxPixels is the maximum number of pixels of the image. Default is 0 (no limit).
+	maxPixels int64
+	// maxInputBytes is the maximum number of bytes read from the input. Default is 0 (no limit).
+	maxInputBytes int64
+	// convertToSRGB enables or disables the conversion to sRGB using the embedded ICC profile.
+	convertToSRGB bool
 }
 
 // defaultDecodeConfig is the default decode config.
 var defaultDecodeConfig = decodeConfig{
 	autoOrientation: false,
+	maxPixels:       0,
+	maxInputBytes:   0,
+	convertToSRGB:   false,
 }
 
 // DecodeOption sets an optional parameter for the Decode and Open functions.
@@ -60,27 +72,143 @@ type DecodeOption func(*decodeConfig)
 
 // AutoOrientation returns a DecodeOption that sets the auto-orientation mode.
 // If auto-orientation is enabled, the image will be transformed after decoding
-// according to the EXIF orientation tag (if present). By default it's disabled.
+// according to the EXIF orientation tag (if present). The tag is read from JPEG,
+// TIFF, PNG and WebP images. By default it's disabled.
 func AutoOrientation(enabled bool) DecodeOption {
 	return func(c *decodeConfig) {
 		c.autoOrientation = enabled
 	}
 }
 
+// MaxPixels returns a DecodeOption that limits the number of pixels (width * height)
+// of the decoded image. The dimensions are read from the image header before any
+// pixel data is allocated and ErrImageTooLarge is returned if the image has more
+// than n pixels. A value of 0 or less means no limit, which is the default.
+func MaxPixels(n int64) DecodeOption {
+	return func(c *decodeConfig) {
+		c.maxPixels = n
+	}
+}
+
+// MaxInputBytes returns a DecodeOption that limits the number of bytes read from
+// the input. ErrImageTooLarge is returned if the image data is larger than n bytes.
+// A value of 0 or less means no limit, which is the default.
+func MaxInputBytes(n int64) DecodeOption {
+	return func(c *decodeConfig) {
+		c.maxInputBytes = n
+	}
+}
+
+// ErrImageTooLarge means the image exceeds the limits set by the MaxPixels
+// or MaxInputBytes options.
+var ErrImageTooLarge = errors.New("imaging: image is too large")
+
 // Decode reads an image from io.Reader.
+// JPEG, PNG, GIF, TIFF, BMP and WebP (lossy, lossless and with alpha) images are supported.
+//
+// When decoding untrusted images, use the MaxPixels and MaxInputBytes options
+// to avoid allocating huge amounts of memory.
+//
+// Example:
+//
+//	// Decode an uploaded image of at most 25 megapixels and 10 MiB.
+//	img, err := imaging.Decode(r, imaging.MaxPixels(25_000_000), imaging.MaxInputBytes(10<<20))
+//	if errors.Is(err, imaging.ErrImageTooLarge) {
+//		// Reject the image.
+//	}
 func Decode(r io.Reader, opts ...DecodeOption) (image.Image, error) {
 	cfg := defaultDecodeConfig
 	for _, option := range opts {
 		option(&cfg)
 	}
 
+	r, lr := cfg.limitInput(r)
+	img, err := decode(r, &cfg)
+	if err != nil && lr != nil && lr.exceeded {
+		return nil, lr.err()
+	}
+	return img, err
+}
+
+// decode reads an image from io.Reader using the decode config.
+func decode(r io.Reader, cfg *decodeConfig) (image.Image, error) {
+	if cfg.maxPixels > 0 {
+		var err error
+		if r, err = limitPixels(r, cfg.maxPixels); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.convertToSRGB {
+		return decodeToSRGB(r, cfg)
+	}
+
 	if !cfg.autoOrientation {
-		img, _, err := image.Decode(r)
-		return img, err
+		return decodeImage(r)
 	}
 	return decodeWithAutoOrientation(r)
 }
 
+// limitInput wraps r in a limitReader if the number of input bytes is limited.
+func (c *decodeConfig) limitInput(r io.Reader) (io.Reader, *limitReader) {
+	if c.maxInputBytes <= 0 {
+		return r, nil
+	}
+	lr := &limitReader{r: r, limit: c.maxInputBytes}
+	return lr, lr
+}
+
+// limitPixels reads the image header from r and returns ErrImageTooLarge if the
+// image has more than maxPixels pixels. Otherwise it returns a reader yielding
+// all the image data, including the header.
+func limitPixels(r io.Reader, maxPixels int64) (io.Reader, error) {
+	header := &bytes.Buffer{}
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, header))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDimensions(cfg.Width, cfg.Height, maxPixels); err != nil {
+		return nil, err
+	}
+	return io.MultiReader(header, r), nil
+}
+
+// checkDimensions returns ErrImageTooLarge if the image with the given
+// dimensions has more than maxPixels pixels.
+func checkDimensions(width, height int, maxPixels int64) error {
+	if maxPixels > 0 && int64(width)*int64(height) > maxPixels {
+		return fmt.Errorf("%w: %dx%d exceeds the limit of %d pixels", ErrImageTooLarge, width, height, maxPixels)
+	}
+	return nil
+}
+
+// limitReader reads from r and fails with ErrImageTooLarge when more than
+// limit bytes are read.
+type limitReader struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, l.err()
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		l.exceeded = true
+		return n - int(l.read-l.limit), l.err()
+	}
+	return n, err
+}
+
+// err returns the error reported when the limit is exceeded.
+func (l *limitReader) err() error {
+	return fmt.Errorf("%w: input exceeds the limit of %d bytes", ErrImageTooLarge, l.limit)
+}
+
 // decodeWithAutoOrientation reads an image from io.Reader and automatically orientates it.
 func decodeWithAutoOrientation(r io.Reader) (image.Image, error) {
 	var orient Orientation
@@ -97,7 +225,7 @@ func decodeWithAutoOrientation(r io.Reader) (image.Image, error) {
 		return nil
 	})
 
-	img, _, err := image.Decode(r)
+	img, err := decodeImage(r)
 	if err != nil {
 		return nil, err
 	}
@@ -136,6 +264,10 @@ const (
 	// BMP (Bitmap): A basic image format that stores pixel data without compression.
 	// It is widely supported but results in larger file sizes compared to compressed formats.
 	BMP
+	// WEBP (WebP): An image format developed by Google that supports both lossy and lossless
+	// compression as well as transparency. It is commonly used for web graphics because it
+	// produces smaller files than JPEG and PNG at comparable quality.
+	WEBP
 )
 
 // formatExts maps image format extensions to Format.
@@ -147,6 +279,7 @@ var formatExts = map[string]Format{
 	"tif":  TIFF,
 	"tiff": TIFF,
 	"bmp":  BMP,
+	"webp": WEBP,
 }
 
 // formatNames maps image formats to their names.
@@ -156,6 +289,7 @@ var formatNames = map[Format]string{
 	GIF:  "GIF",
 	TIFF: "TIFF",
 	BMP:  "BMP",
+	WEBP: "WEBP",
 }
 
 // String returns the name of the image format.
@@ -167,7 +301,7 @@ func (f Format) String() string {
 var ErrUnsupportedFormat = errors.New("imaging: unsupported image format")
 
 // FormatFromExtension parses image format from filename extension:
-// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff") and "bmp" are supported.
+// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff"), "bmp" and "webp" are supported.
 func FormatFromExtension(ext string) (Format, error) {
 	if f, ok := formatExts[strings.ToLower(strings.TrimPrefix(ext, "."))]; ok {
 		return f, nil
@@ -176,7 +310,9 @@ func FormatFromExtension(ext string) (Format, error) {
 }
 
 // FormatFromFilename parses image format from filename:
-// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff") and "bmp" are supported.
+// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff"), "bmp" and "webp" are supported.
+// The extension may not match the actual content of the file; use DetectFormat
+// to detect the format from the image data.
 func FormatFromFilename(filename string) (Format, error) {
 	ext := filepath.Ext(filename)
 	return FormatFromExtension(ext)
@@ -194,6 +330,12 @@ type encodeConfig struct {
 	gifDrawer draw.Drawer
 	// pngCompressionLevel PNG compression level (1-9). Default is DefaultCompression.
 	pngCompressionLevel png.CompressionLevel
+	// webpEffort WebP encoder effort (0-9). Default is 5.
+	webpEffort int
+	// webpExact WebP encoder preserves the color of fully transparent pixels. Default is false.
+	webpExact bool
+	// metadata is written to JPEG and PNG images. Default is nil (no metadata).
+	metadata *Metadata
 }
 
 // defaultEncodeConfig is the default encoding configuration.
@@ -203,6 +345,9 @@ var defaultEncodeConfig = encodeConfig{
 	gifQuantizer:        nil,
 	gifDrawer:           nil,
 	pngCompressionLevel: png.DefaultCompression,
+	webpEffort:          5,
+	webpExact:           false,
+	metadata:            nil,
 }
 
 // EncodeOption sets an optional parameter for the Encode and Save functions.
@@ -248,12 +393,38 @@ func PNGCompressionLevel(level png.CompressionLevel) EncodeOption {
 	}
 }
 
-// Encode writes the image img to w in the specified format (JPEG, PNG, GIF, TIFF or BMP).
+// WebPEffort returns an EncodeOption that sets the effort of the lossless WebP encoder.
+// Effort ranges from 0 to 9 inclusive, higher is slower but produces smaller files. Default is 5.
+func WebPEffort(effort int) EncodeOption {
+	return func(c *encodeConfig) {
+		c.webpEffort = effort
+	}
+}
+
+// WebPExact returns an EncodeOption that sets whether the lossless WebP encoder preserves
+// the color values of fully transparent pixels. By default they are discarded, which
+// produces smaller files without changing the visible image.
+func WebPExact(exact bool) EncodeOption {
+	return func(c *encodeConfig) {
+		c.webpExact = exact
+	}
+}
+
+// Encode writes the image img to w in the specified format (JPEG, PNG, GIF, TIFF, BMP or WEBP).
+// WebP images are encoded losslessly.
 func Encode(w io.Writer, img image.Image, format Format, opts ...EncodeOption) error {
 	cfg := defaultEncodeConfig
 	for _, option := range opts {
 		option(&cfg)
 	}
+	return encode(w, img, format, &cfg)
+}
+
+// encode writes the image img to w in the specified format using the encode config.
+func encode(w io.Writer, img image.Image, format Format, cfg *encodeConfig) error {
+	if cfg.metadata != nil && (format == JPEG || format == PNG) {
+		return encodeWithMetadata(w, img, format, cfg)
+	}
 
 	switch format {
 	case JPEG:
@@ -283,6 +454,9 @@ func Encode(w io.Writer, img image.Image, format Format, opts ...EncodeOption) e
 
 	case BMP:
 		return bmp.Encode(w, img)
+
+	case WEBP:
+		return encodeWebP(w, img, cfg.webpEffort, cfg.webpExact)
 	}
 
 	return ErrUnsupportedFormat
@@ -290,7 +464,7 @@ func Encode(w io.Writer, img image.Image, format Format, opts ...EncodeOption) e
 
 // Save saves the image to file with the specified filename.
 // The format is determined from the filename extension:
-// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff") and "bmp" are supported.
+// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff"), "bmp" and "webp" are supported.
 //
 // Examples:
 //
@@ -317,6 +491,127 @@ func Save(img image.Image, filename string, opts ...EncodeOption) (err error) {
 	return err
 }
 
+// formatHeaderLen is the number of bytes needed to detect all the formats.
+const formatHeaderLen = 12
+
+// DetectFormat detects the image format from the magic bytes at the start of the
+// image data: JPEG, PNG, GIF, TIFF, BMP and WEBP are supported.
+//
+// The returned reader yields all the data of r, including the header bytes
+// read by DetectFormat, and should be used instead of r afterwards.
+// If r is a *bufio.Reader or an io.Seeker, r itself is returned.
+//
+// Example:
+//
+//	// Detect the format of an uploaded image and decode it.
+//	format, r, err := imaging.DetectFormat(upload)
+//	if err != nil {
+//		return err
+//	}
+//	img, err := imaging.Decode(r)
+func DetectFormat(r io.Reader) (Format, io.Reader, error) {
+	var header []byte
+	switch rr := r.(type) {
+	case *bufio.Reader:
+		h, err := rr.Peek(formatHeaderLen)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return -1, r, err
+		}
+		header = h
+	case io.ReadSeeker:
+		h := make([]byte, formatHeaderLen)
+		n, err := io.ReadFull(rr, h)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return -1, r, err
+		}
+		if _, err := rr.Seek(int64(-n), io.SeekCurrent); err != nil {
+			return -1, r, err
+		}
+		header = h[:n]
+	default:
+		br := bufio.NewReader(r)
+		h, err := br.Peek(formatHeaderLen)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return -1, br, err
+		}
+		r = br
+		header = h
+	}
+
+	f, err := formatFromHeader(header)
+	return f, r, err
+}
+
+// formatFromHeader returns the image format matching the magic bytes.
+func formatFromHeader(header []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte("\xff\xd8\xff")):
+		return JPEG, nil
+	case bytes.HasPrefix(header, []byte(pngSignature)):
+		return PNG, nil
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return GIF, nil
+	case bytes.HasPrefix(header, []byte("II*\x00")), bytes.HasPrefix(header, []byte("MM\x00*")):
+		return TIFF, nil
+	case bytes.HasPrefix(header, []byte("BM")):
+		return BMP, nil
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")):
+		return WEBP, nil
+	}
+	return -1, ErrUnsupportedFormat
+}
+
+// SaveAsDetected saves the image to file with the specified filename using
+// the format detected from the content of the source file src, instead of the
+// format given by the filename extension. This is useful when the extension of
+// the source file cannot be trusted, e.g. for uploaded files.
+//
+// Example:
+//
+//	// Resize an uploaded image named "upload.jpg" that is actually a PNG
+//	// and save the result as PNG.
+//	img, err := imaging.Open("upload.jpg")
+//	if err != nil {
+//		return err
+//	}
+//	err = imaging.SaveAsDetected(imaging.Resize(img, 100, 0, imaging.Lanczos), "upload.jpg", "thumbnail.jpg")
+func SaveAsDetected(img image.Image, src, filename string, opts ...EncodeOption) (err error) {
+	f, err := detectFileFormat(src)
+	if err != nil {
+		return err
+	}
+	file, err := fs.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = Encode(file, img, f, opts...)
+	errClose := file.Close()
+	if err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// detectFileFormat detects the image format from the content of the file.
+func detectFileFormat(filename string) (f Format, err error) {
+	file, err := fs.Open(filename)
+	if err != nil {
+		return -1, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	f, _, err = DetectFormat(file)
+	return f, err
+}
+
 // Orientation is an EXIF flag that specifies the transformation
 // that should be applied to image to display it correctly.
 type Orientation int
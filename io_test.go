@@ -1,6 +1,7 @@
 package imaging
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"image"
@@ -123,7 +124,7 @@ func TestOpenSave(t *testing.T) {
 		}
 		defer os.RemoveAll(dir) //nolint
 
-		for _, ext := range []string{"jpg", "jpeg", "png", "gif", "bmp", "tif", "tiff"} {
+		for _, ext := range []string{"jpg", "jpeg", "png", "gif", "bmp", "tif", "tiff", "webp"} {
 			filename := filepath.Join(dir, "test."+ext)
 
 			img := imgWithoutAlpha
@@ -218,6 +219,7 @@ func TestFormats(t *testing.T) {
 		GIF:        "GIF",
 		BMP:        "BMP",
 		TIFF:       "TIFF",
+		WEBP:       "WEBP",
 		Format(-1): "",
 	}
 	for format, name := range formatNames {
@@ -252,6 +254,11 @@ func TestFormatFromExtension(t *testing.T) {
 			ext:  ".JPG",
 			want: JPEG,
 		},
+		{
+			name: "webp",
+			ext:  ".webp",
+			want: WEBP,
+		},
 		{
 			name: "unsupported",
 			ext:  ".unsupportedextension",
@@ -275,6 +282,327 @@ func TestFormatFromExtension(t *testing.T) {
 	}
 }
 
+func TestOpenWebP(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		path string
+		want image.Rectangle
+	}{
+		{
+			name: "lossy",
+			path: "testdata/webp_lossy.webp",
+			want: image.Rect(0, 0, 150, 100),
+		},
+		{
+			name: "lossless",
+			path: "testdata/webp_lossless.webp",
+			want: image.Rect(0, 0, 75, 100),
+		},
+		{
+			name: "lossy with alpha",
+			path: "testdata/webp_alpha.webp",
+			want: image.Rect(0, 0, 400, 301),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			img, err := Open(tc.path)
+			if err != nil {
+				t.Fatalf("failed to open image (%q): %v", tc.path, err)
+			}
+			if img.Bounds() != tc.want {
+				t.Fatalf("got bounds %v want %v", img.Bounds(), tc.want)
+			}
+		})
+	}
+}
+
+// onlyReader hides all the methods of the wrapped reader but Read.
+type onlyReader struct {
+	io.Reader
+}
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+
+	img := testdataFlowersSmallPNG
+	for _, format := range []Format{JPEG, PNG, GIF, TIFF, BMP, WEBP} {
+		format := format
+
+		t.Run(format.String(), func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, img, format); err != nil {
+				t.Fatalf("failed to encode image: %v", err)
+			}
+			data := buf.Bytes()
+
+			readers := map[string]io.Reader{
+				"seeker": bytes.NewReader(data),
+				"bufio":  bufio.NewReader(bytes.NewReader(data)),
+				"reader": onlyReader{bytes.NewReader(data)},
+			}
+			for name, r := range readers {
+				got, rest, err := DetectFormat(r)
+				if err != nil {
+					t.Fatalf("%s: DetectFormat: %v", name, err)
+				}
+				if got != format {
+					t.Fatalf("%s: got format %v want %v", name, got, format)
+				}
+				all, err := io.ReadAll(rest)
+				if err != nil {
+					t.Fatalf("%s: failed to read: %v", name, err)
+				}
+				if !bytes.Equal(all, data) {
+					t.Fatalf("%s: DetectFormat consumed the reader", name)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectFormatHeaders(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		header string
+		want   Format
+		err    error
+	}{
+		{
+			name:   "big-endian TIFF",
+			header: "MM\x00*\x00\x00\x00\x08",
+			want:   TIFF,
+		},
+		{
+			name:   "GIF87a",
+			header: "GIF87a",
+			want:   GIF,
+		},
+		{
+			name:   "RIFF but not WebP",
+			header: "RIFF\x00\x00\x00\x00WAVE",
+			want:   -1,
+			err:    ErrUnsupportedFormat,
+		},
+		{
+			name:   "truncated WebP",
+			header: "RIFF\x00\x00",
+			want:   -1,
+			err:    ErrUnsupportedFormat,
+		},
+		{
+			name:   "text",
+			header: "hello, world",
+			want:   -1,
+			err:    ErrUnsupportedFormat,
+		},
+		{
+			name:   "empty",
+			header: "",
+			want:   -1,
+			err:    ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, _, err := DetectFormat(onlyReader{strings.NewReader(tc.header)})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v want %v", err, tc.err)
+			}
+			if got != tc.want {
+				t.Fatalf("got format %v want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSaveAsDetected(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "upload.jpg")
+	if err := Save(testdataFlowersSmallPNG, filepath.Join(dir, "upload.png")); err != nil {
+		t.Fatalf("failed to save image: %v", err)
+	}
+	if err := os.Rename(filepath.Join(dir, "upload.png"), src); err != nil {
+		t.Fatalf("failed to rename image: %v", err)
+	}
+
+	dst := filepath.Join(dir, "thumbnail.jpg")
+	thumb := Thumbnail(testdataFlowersSmallPNG, 10, 10, Box)
+	if err := SaveAsDetected(thumb, src, dst); err != nil {
+		t.Fatalf("SaveAsDetected: %v", err)
+	}
+
+	file, err := os.Open(dst)
+	if err != nil {
+		t.Fatalf("failed to open image: %v", err)
+	}
+	defer file.Close() //nolint
+	format, r, err := DetectFormat(file)
+	if err != nil {
+		t.Fatalf("DetectFormat: %v", err)
+	}
+	if format != PNG {
+		t.Fatalf("got format %v want %v", format, PNG)
+	}
+	got, err := Decode(r)
+	if err != nil {
+		t.Fatalf("failed to decode image: %v", err)
+	}
+	if !compareNRGBA(Clone(got), thumb, 0) {
+		t.Fatal("saved image differs from the original")
+	}
+
+	if err := SaveAsDetected(thumb, filepath.Join(dir, "missing.jpg"), dst); err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if err := SaveAsDetected(thumb, dst, filepath.Join(dir, "missing", "out.jpg")); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	t.Parallel()
+
+	// A PNG header declaring a 50000x50000 image followed by garbage.
+	bomb := []byte(pngSignature)
+	bomb = appendPNGChunk(bomb, "IHDR", []byte{0, 0, 0xc3, 0x50, 0, 0, 0xc3, 0x50, 8, 0, 0, 0, 0})
+	bomb = appendPNGChunk(bomb, "IDAT", []byte{1, 2, 3})
+	bomb = appendPNGChunk(bomb, "IEND", nil)
+
+	img := testdataFlowersSmallPNG
+	pixels := int64(img.Bounds().Dx() * img.Bounds().Dy())
+	encoded := map[Format][]byte{}
+	for _, format := range []Format{JPEG, PNG, GIF, TIFF, BMP, WEBP} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, format); err != nil {
+			t.Fatalf("failed to encode image: %v", err)
+		}
+		encoded[format] = buf.Bytes()
+	}
+
+	testCases := []struct {
+		name string
+		data []byte
+		opts []DecodeOption
+		err  error
+	}{
+		{
+			name: "bomb",
+			data: bomb,
+			opts: []DecodeOption{MaxPixels(100_000_000)},
+			err:  ErrImageTooLarge,
+		},
+		{
+			name: "bomb with auto orientation",
+			data: bomb,
+			opts: []DecodeOption{MaxPixels(100_000_000), AutoOrientation(true)},
+			err:  ErrImageTooLarge,
+		},
+		{
+			name: "pixels at limit",
+			data: encoded[PNG],
+			opts: []DecodeOption{MaxPixels(pixels)},
+		},
+		{
+			name: "bytes at limit",
+			data: encoded[PNG],
+			opts: []DecodeOption{MaxInputBytes(int64(len(encoded[PNG])))},
+		},
+		{
+			name: "bytes over limit",
+			data: encoded[PNG],
+			opts: []DecodeOption{MaxInputBytes(int64(len(encoded[PNG]) - 1))},
+			err:  ErrImageTooLarge,
+		},
+		{
+			name: "bytes over limit with auto orientation",
+			data: encoded[JPEG],
+			opts: []DecodeOption{MaxInputBytes(100), AutoOrientation(true)},
+			err:  ErrImageTooLarge,
+		},
+		{
+			name: "no limit",
+			data: encoded[PNG],
+			opts: []DecodeOption{MaxPixels(0), MaxInputBytes(-1)},
+		},
+	}
+	for format, data := range encoded {
+		testCases = append(testCases, struct {
+			name string
+			data []byte
+			opts []DecodeOption
+			err  error
+		}{
+			name: format.String() + " pixels over limit",
+			data: data,
+			opts: []DecodeOption{MaxPixels(pixels - 1)},
+			err:  ErrImageTooLarge,
+		})
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Decode(bytes.NewReader(tc.data), tc.opts...)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v want %v", err, tc.err)
+			}
+			if tc.err == nil && got.Bounds() != img.Bounds() {
+				t.Fatalf("got bounds %v want %v", got.Bounds(), img.Bounds())
+			}
+
+			_, err = DecodeAnimation(bytes.NewReader(tc.data), tc.opts...)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("DecodeAnimation: got error %v want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestDecodeAnimationLimits(t *testing.T) {
+	t.Parallel()
+
+	anim := &Animation{Frames: []*image.NRGBA{New(40, 30, color.White), New(40, 30, color.Black)}}
+	for _, format := range []Format{GIF, PNG} {
+		buf := &bytes.Buffer{}
+		if err := EncodeAnimation(buf, anim, format); err != nil {
+			t.Fatalf("EncodeAnimation: %v", err)
+		}
+		data := buf.Bytes()
+
+		if _, err := DecodeAnimation(bytes.NewReader(data), MaxPixels(40*30)); err != nil {
+			t.Fatalf("%v: DecodeAnimation: %v", format, err)
+		}
+		if _, err := DecodeAnimation(bytes.NewReader(data), MaxPixels(40*30-1)); !errors.Is(err, ErrImageTooLarge) {
+			t.Fatalf("%v: got error %v want %v", format, err, ErrImageTooLarge)
+		}
+		if _, err := DecodeAnimation(bytes.NewReader(data), MaxInputBytes(int64(len(data)-1))); !errors.Is(err, ErrImageTooLarge) {
+			t.Fatalf("%v: got error %v want %v", format, err, ErrImageTooLarge)
+		}
+	}
+}
+
 func TestAutoOrientation(t *testing.T) {
 	t.Parallel()
 
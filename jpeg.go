@@ -0,0 +1,135 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+)
+
+// adobeCMYKSegment is an Adobe APP14 segment with the unknown (CMYK) color transform.
+const adobeCMYKSegment = "\xff\xee\x00\x0eAdobe\x00\x64\x00\x00\x00\x00\x00"
+
+// jpegColorInfo describes the color encoding of a JPEG image.
+type jpegColorInfo struct {
+	// components is the number of color components of the frame.
+	components int
+	// adobe reports whether the image has an Adobe APP14 segment.
+	adobe bool
+	// transform is the color transform of the Adobe APP14 segment:
+	// 0 for RGB or CMYK, 1 for YCbCr and 2 for YCCK.
+	transform byte
+}
+
+// readJPEGColorInfo reads the JPEG segments up to the start of the frame (SOF marker)
+// and returns the color encoding of the image.
+func readJPEGColorInfo(r io.Reader) (jpegColorInfo, error) {
+	const (
+		markerSOF0  = 0xc0
+		markerSOF15 = 0xcf
+		markerDHT   = 0xc4
+		markerJPG   = 0xc8
+		markerDAC   = 0xcc
+		markerEOI   = 0xd9
+		markerSOS   = 0xda
+		markerAPP14 = 0xee
+	)
+
+	var info jpegColorInfo
+	if err := findJPEGSOIMarker(r); err != nil {
+		return info, err
+	}
+	for {
+		marker, err := readJPEGMarker(r)
+		if err != nil {
+			return info, err
+		}
+		if marker == markerSOS || marker == markerEOI {
+			return info, errors.New("missing JPEG SOF marker")
+		}
+		// Standalone markers (TEM and RSTn) have no segment.
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			continue
+		}
+
+		var size uint16
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return info, err
+		}
+		if size < 2 {
+			return info, errors.New("invalid block size")
+		}
+		isSOF := marker >= markerSOF0 && marker <= markerSOF15 &&
+			marker != markerDHT && marker != markerJPG && marker != markerDAC
+		if !isSOF && marker != markerAPP14 {
+			if _, err := io.CopyN(io.Discard, r, int64(size-2)); err != nil {
+				return info, err
+			}
+			continue
+		}
+
+		data := make([]byte, size-2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return info, err
+		}
+		if isSOF {
+			// Precision, height, width and number of components.
+			if len(data) < 6 {
+				return info, errors.New("invalid JPEG SOF segment")
+			}
+			info.components = int(data[5])
+			return info, nil
+		}
+		if len(data) >= 12 && string(data[:5]) == "Adobe" {
+			info.adobe = true
+			info.transform = data[11]
+		}
+	}
+}
+
+// decodeImage reads an image from io.Reader like image.Decode.
+// See decodeJPEG for the handling of CMYK JPEG images.
+func decodeImage(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	if header, _ := br.Peek(3); string(header) == "\xff\xd8\xff" {
+		return decodeJPEG(br)
+	}
+	img, _, err := image.Decode(br)
+	return img, err
+}
+
+// decodeJPEG reads a JPEG image from io.Reader.
+//
+// 4-component JPEG images are decoded as *image.CMYK. With an Adobe APP14 segment,
+// the image is either CMYK or YCCK (YCbCr and black) depending on the transform
+// flag of the segment, and the channels are inverted as written by Adobe software
+// (255 means no ink). This is handled by the image/jpeg package. Images without the
+// segment, which image/jpeg rejects, are decoded as regular, non-inverted, CMYK images.
+func decodeJPEG(r io.Reader) (image.Image, error) {
+	header := &bytes.Buffer{}
+	info, err := readJPEGColorInfo(io.TeeReader(r, header))
+	if err != nil || info.components != 4 || info.adobe {
+		return jpeg.Decode(io.MultiReader(header, r))
+	}
+
+	// Let image/jpeg decode the image as an Adobe CMYK image and undo the inversion.
+	data := io.MultiReader(
+		bytes.NewReader(header.Bytes()[:2]),
+		bytes.NewReader([]byte(adobeCMYKSegment)),
+		bytes.NewReader(header.Bytes()[2:]),
+		r,
+	)
+	img, err := jpeg.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if cmyk, ok := img.(*image.CMYK); ok {
+		for i := range cmyk.Pix {
+			cmyk.Pix[i] = 255 - cmyk.Pix[i]
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,139 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadJPEGColorInfo(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		path string
+		want jpegColorInfo
+	}{
+		{"testdata/cmyk_adobe.jpg", jpegColorInfo{components: 4, adobe: true, transform: 0}},
+		{"testdata/cmyk_no_adobe.jpg", jpegColorInfo{components: 4}},
+		{"testdata/ycck_adobe.jpg", jpegColorInfo{components: 4, adobe: true, transform: 2}},
+		{"testdata/orientation_6.jpg", jpegColorInfo{components: 3}},
+		{"testdata/exif_fill_bytes.jpg", jpegColorInfo{components: 3}},
+	}
+	for _, tc := range testCases {
+		data, err := os.ReadFile(tc.path)
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", tc.path, err)
+		}
+		info, err := readJPEGColorInfo(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%q: readJPEGColorInfo: %v", tc.path, err)
+		}
+		if info != tc.want {
+			t.Fatalf("%q: got %+v want %+v", tc.path, info, tc.want)
+		}
+	}
+
+	for _, data := range []string{
+		"",
+		"\x89PNG",
+		"\xff\xd8\xff",
+		"\xff\xd8\xff\xe0\x00",
+		"\xff\xd8\xff\xe0\x00\x01",
+		"\xff\xd8\xff\xe0\x00\x04\x00",
+		"\xff\xd8\xff\xee\x00\x10Adobe",
+		"\xff\xd8\xff\xc0\x00\x05\x08\x00\x01",
+		"\xff\xd8\xff\xd0\xff\xda\x00\x02",
+	} {
+		if _, err := readJPEGColorInfo(strings.NewReader(data)); err == nil {
+			t.Fatalf("%q: expected error got nil", data)
+		}
+	}
+}
+
+func TestDecodeCMYKJPEG(t *testing.T) {
+	t.Parallel()
+
+	adobe, err := Open("testdata/cmyk_adobe.jpg")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	adobeCMYK, ok := adobe.(*image.CMYK)
+	if !ok {
+		t.Fatalf("got image type %T want *image.CMYK", adobe)
+	}
+	want, err := Open("testdata/cmyk_adobe.png")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	// The reference image was converted by another decoder.
+	if !compareNRGBA(Clone(adobe), Clone(want), 8) {
+		t.Fatal("Adobe CMYK image does not match the reference image")
+	}
+
+	// Without the Adobe segment, the channels are not inverted.
+	f, err := os.Open("testdata/cmyk_no_adobe.jpg")
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	defer f.Close()
+	if _, _, err := image.Decode(f); err == nil {
+		t.Fatal("image.Decode unexpectedly succeeded")
+	}
+	for _, opts := range [][]DecodeOption{nil, {AutoOrientation(true)}} {
+		plain, err := Open("testdata/cmyk_no_adobe.jpg", opts...)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		plainCMYK, ok := plain.(*image.CMYK)
+		if !ok {
+			t.Fatalf("got image type %T want *image.CMYK", plain)
+		}
+		for i := range plainCMYK.Pix {
+			if plainCMYK.Pix[i] != 255-adobeCMYK.Pix[i] {
+				t.Fatalf("got value %d at %d want %d", plainCMYK.Pix[i], i, 255-adobeCMYK.Pix[i])
+			}
+		}
+	}
+
+	ycck, err := Open("testdata/ycck_adobe.jpg")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, ok := ycck.(*image.CMYK); !ok {
+		t.Fatalf("got image type %T want *image.CMYK", ycck)
+	}
+	// The YCCK image was encoded with libjpeg without chroma subsampling and the reference
+	// image was rendered from the CMYK output of the libjpeg decoder.
+	want, err = Open("testdata/ycck_adobe.png")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !compareNRGBA(Clone(ycck), Clone(want), 4) {
+		t.Fatal("YCCK image does not match the reference image")
+	}
+
+	// Truncated images are still reported.
+	data, err := os.ReadFile("testdata/cmyk_no_adobe.jpg")
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if _, err := Decode(bytes.NewReader(data[:300])); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
+
+func TestResizeCMYK(t *testing.T) {
+	t.Parallel()
+
+	img, err := Open("testdata/cmyk_adobe.jpg")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got := Resize(img, 30, 0, Lanczos)
+	want := Resize(Clone(img), 30, 0, Lanczos)
+	if !compareNRGBA(got, want, 0) {
+		t.Fatal("resized CMYK image does not match the resized NRGBA image")
+	}
+}
@@ -0,0 +1,85 @@
+package imaging
+
+import (
+	"image"
+	"sync"
+)
+
+var (
+	// linearTablesOnce guards the lazy initialization of the conversion tables.
+	linearTablesOnce sync.Once //nolint
+	// toLinearTable maps 16-bit sRGB encoded values to 16-bit linear values.
+	toLinearTable []uint16 //nolint
+	// fromLinearTable maps 16-bit linear values to 16-bit sRGB encoded values.
+	fromLinearTable []uint16 //nolint
+)
+
+// linearTables returns the tables converting 16-bit values between sRGB and linear light.
+func linearTables() ([]uint16, []uint16) {
+	linearTablesOnce.Do(func() {
+		toLinearTable = make([]uint16, 0x10000)
+		fromLinearTable = make([]uint16, 0x10000)
+		for i := range toLinearTable {
+			v := float64(i) / 0xffff
+			toLinearTable[i] = clamp16(srgbToLinear(v) * 0xffff)
+			fromLinearTable[i] = clamp16(linearToSRGB(v) * 0xffff)
+		}
+	})
+	return toLinearTable, fromLinearTable
+}
+
+// toLinear16 returns a copy of the image with 16 bits per channel with the sRGB encoded
+// color channels converted to linear light. The alpha channel is left unchanged.
+func toLinear16(img image.Image) *image.NRGBA64 {
+	lut, _ := linearTables()
+	return adjustLUT16(img, lut)
+}
+
+// fromLinear16 converts the color channels of the image from linear light to sRGB in place.
+func fromLinear16(img *image.NRGBA64) *image.NRGBA64 {
+	_, lut := linearTables()
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * img.Stride
+			for x := 0; x < w; x++ {
+				d := img.Pix[i : i+8 : i+8]
+				r, g, b, a := pixel16(d)
+				setPixel16(d, lut[r], lut[g], lut[b], a)
+				i += 8
+			}
+		}
+	})
+	return img
+}
+
+// fromLinear returns the image with linear light color channels converted to an
+// sRGB encoded *image.NRGBA.
+func fromLinear(img *image.NRGBA64) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, img.Rect.Dx(), img.Rect.Dy()))
+	fromLinearInto(dst, img)
+	return dst
+}
+
+// fromLinearInto converts the image with linear light color channels to sRGB and stores
+// the result in dst, which must have the size of the image.
+func fromLinearInto(dst *image.NRGBA, img *image.NRGBA64) {
+	_, lut := linearTables()
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * img.Stride
+			j := y * dst.Stride
+			for x := 0; x < w; x++ {
+				r, g, b, a := pixel16(img.Pix[i : i+8 : i+8])
+				d := dst.Pix[j : j+4 : j+4]
+				d[0] = to8Bit(lut[r])
+				d[1] = to8Bit(lut[g])
+				d[2] = to8Bit(lut[b])
+				d[3] = to8Bit(a)
+				i += 8
+				j += 4
+			}
+		}
+	})
+}
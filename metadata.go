@@ -0,0 +1,458 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sort"
+)
+
+// Metadata holds the metadata of an image that is not part of the pixel data.
+// Empty fields mean the metadata is not present.
+type Metadata struct {
+	// EXIF is the EXIF data stored as a TIFF structure, starting with the byte order.
+	EXIF []byte
+	// ICC is the ICC color profile.
+	ICC []byte
+	// XMP is the XMP packet.
+	XMP []byte
+}
+
+const (
+	// xmpHeader is the header of the JPEG APP1 segments holding XMP data.
+	xmpHeader = "http://ns.adobe.com/xap/1.0/\x00"
+	// iccHeader is the header of the JPEG APP2 segments holding ICC profile chunks.
+	iccHeader = "ICC_PROFILE\x00"
+	// xmpKeyword is the keyword of the PNG iTXt chunk holding XMP data.
+	xmpKeyword = "XML:com.adobe.xmp"
+
+	// jpegMaxSegmentData is the maximum size of the data of a JPEG segment.
+	jpegMaxSegmentData = 0xffff - 2
+)
+
+// ReadMetadata reads the EXIF data, the ICC profile and the XMP packet from
+// the JPEG, PNG or WebP image data in r. The pixel data is not decoded.
+// Images of other formats have no metadata.
+func ReadMetadata(r io.Reader) (*Metadata, error) {
+	format, r, err := DetectFormat(r)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case JPEG:
+		return readJPEGMetadata(r)
+	case PNG:
+		return readPNGMetadata(r)
+	case WEBP:
+		return readWebPMetadata(r)
+	}
+	return &Metadata{}, nil
+}
+
+// OpenWithMetadata loads an image from file and returns it with its metadata.
+// See DecodeWithMetadata.
+func OpenWithMetadata(filename string, opts ...DecodeOption) (img image.Image, m *Metadata, err error) {
+	file, err := fs.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	return DecodeWithMetadata(file, opts...)
+}
+
+// DecodeWithMetadata reads an image from io.Reader and returns it with its metadata,
+// so that the metadata can be written to the processed image using the WithMetadata option.
+// If the image is converted to sRGB with the ConvertToSRGB option, the ICC profile is
+// not returned.
+//
+// Example:
+//
+//	img, meta, err := imaging.OpenWithMetadata("photo.jpg", imaging.AutoOrientation(true))
+//	if err != nil {
+//		return err
+//	}
+//	img = imaging.Resize(img, 800, 0, imaging.Lanczos)
+//	err = imaging.Save(img, "photo_small.jpg", imaging.WithMetadata(meta))
+func DecodeWithMetadata(r io.Reader, opts ...DecodeOption) (image.Image, *Metadata, error) {
+	cfg := defaultDecodeConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+
+	r, lr := cfg.limitInput(r)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		if lr != nil && lr.exceeded {
+			return nil, nil, lr.err()
+		}
+		return nil, nil, err
+	}
+	img, err := decode(bytes.NewReader(data), &cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := ReadMetadata(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	// The profile no longer describes the colors of the converted image.
+	if cfg.convertToSRGB {
+		if p := embeddedICCProfile(data); p != nil && !p.isSRGB() {
+			m.ICC = nil
+		}
+	}
+	return img, m, nil
+}
+
+// WithMetadata returns an EncodeOption that writes the metadata to JPEG and PNG images.
+// The orientation tag of the EXIF data is reset to 1 (normal), as the image is
+// expected to be decoded with AutoOrientation, and the pixel dimension tags are
+// set to the size of the encoded image. EXIF data that can't be parsed is not written.
+// The metadata is ignored for other formats.
+func WithMetadata(m *Metadata) EncodeOption {
+	return func(c *encodeConfig) {
+		c.metadata = m
+	}
+}
+
+// readJPEGMetadata reads the metadata from the JPEG segments preceding the image data.
+func readJPEGMetadata(r io.Reader) (*Metadata, error) {
+	const (
+		markerEOI  = 0xd9
+		markerSOS  = 0xda
+		markerAPP1 = 0xe1
+		markerAPP2 = 0xe2
+	)
+
+	if err := findJPEGSOIMarker(r); err != nil {
+		return nil, err
+	}
+
+	m := &Metadata{}
+	iccChunks := map[byte][]byte{}
+	for {
+		marker, err := readJPEGMarker(r)
+		if err != nil {
+			return nil, err
+		}
+		if marker == markerSOS || marker == markerEOI {
+			break
+		}
+		// Standalone markers (TEM and RSTn) have no segment.
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			continue
+		}
+
+		var size uint16
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size < 2 {
+			return nil, errors.New("invalid block size")
+		}
+		if marker != markerAPP1 && marker != markerAPP2 {
+			if _, err := io.CopyN(io.Discard, r, int64(size-2)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		data := make([]byte, size-2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case marker == markerAPP1 && bytes.HasPrefix(data, []byte(exifHeader)):
+			if m.EXIF == nil {
+				m.EXIF = data[len(exifHeader):]
+			}
+		case marker == markerAPP1 && bytes.HasPrefix(data, []byte(xmpHeader)):
+			if m.XMP == nil {
+				m.XMP = data[len(xmpHeader):]
+			}
+		case marker == markerAPP2 && bytes.HasPrefix(data, []byte(iccHeader)) && len(data) >= len(iccHeader)+2:
+			// The profile is split in chunks numbered from 1.
+			iccChunks[data[len(iccHeader)]] = data[len(iccHeader)+2:]
+		}
+	}
+
+	if len(iccChunks) > 0 {
+		seqs := make([]int, 0, len(iccChunks))
+		for seq := range iccChunks {
+			seqs = append(seqs, int(seq))
+		}
+		sort.Ints(seqs)
+		for _, seq := range seqs {
+			m.ICC = append(m.ICC, iccChunks[byte(seq)]...)
+		}
+	}
+	return m, nil
+}
+
+// readPNGMetadata reads the metadata from the iCCP, eXIf and iTXt chunks of the PNG image.
+func readPNGMetadata(r io.Reader) (*Metadata, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Metadata{}
+	for _, c := range chunks {
+		switch c.typ {
+		case "eXIf":
+			m.EXIF = bytes.TrimPrefix(c.data, []byte(exifHeader))
+		case "iCCP":
+			// Profile name, compression method and zlib-compressed profile.
+			i := bytes.IndexByte(c.data, 0)
+			if i < 0 || i+2 > len(c.data) {
+				return nil, errors.New("imaging: invalid PNG iCCP chunk")
+			}
+			if m.ICC, err = zlibDecompress(c.data[i+2:]); err != nil {
+				return nil, err
+			}
+		case "iTXt":
+			if m.XMP == nil {
+				if m.XMP, err = pngXMP(c.data); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// pngXMP returns the XMP packet of the PNG iTXt chunk data, or nil if the
+// chunk holds another text.
+func pngXMP(data []byte) ([]byte, error) {
+	// Keyword, compression flag and method, language tag, translated keyword and text.
+	fields := bytes.SplitN(data, []byte{0}, 2)
+	if len(fields) != 2 || string(fields[0]) != xmpKeyword {
+		return nil, nil
+	}
+	rest := fields[1]
+	if len(rest) < 2 {
+		return nil, errors.New("imaging: invalid PNG iTXt chunk")
+	}
+	compressed := rest[0] == 1
+	fields = bytes.SplitN(rest[2:], []byte{0}, 3)
+	if len(fields) != 3 {
+		return nil, errors.New("imaging: invalid PNG iTXt chunk")
+	}
+	if compressed {
+		return zlibDecompress(fields[2])
+	}
+	return fields[2], nil
+}
+
+// zlibDecompress returns the decompressed zlib data.
+func zlibDecompress(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// readWebPMetadata reads the metadata from the ICCP, EXIF and XMP chunks of the WebP image.
+func readWebPMetadata(r io.Reader) (*Metadata, error) {
+	if _, err := io.CopyN(io.Discard, r, 12); err != nil {
+		return nil, err
+	}
+
+	m := &Metadata{}
+	for {
+		var fourCC [4]byte
+		var size uint32
+		if _, err := io.ReadFull(r, fourCC[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return m, nil
+			}
+			return nil, err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, err
+		}
+
+		var dst *[]byte
+		switch string(fourCC[:]) {
+		case "EXIF":
+			dst = &m.EXIF
+		case "ICCP":
+			dst = &m.ICC
+		case "XMP ":
+			dst = &m.XMP
+		}
+		// Chunks are padded to an even size.
+		padded := int64(size) + int64(size&1)
+		if dst == nil {
+			if _, err := io.CopyN(io.Discard, r, padded); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		data := make([]byte, padded)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		*dst = data[:size]
+	}
+}
+
+// encodeWithMetadata writes the image in the JPEG or PNG format with the metadata of the config.
+func encodeWithMetadata(w io.Writer, img image.Image, format Format, cfg *encodeConfig) error {
+	m := cfg.metadata
+	plain := *cfg
+	plain.metadata = nil
+
+	buf := &bytes.Buffer{}
+	if err := encode(buf, img, format, &plain); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+
+	size := img.Bounds().Size()
+	exif := updateEXIF(m.EXIF, size.X, size.Y)
+
+	var out []byte
+	var err error
+	switch format {
+	case JPEG:
+		out, err = appendJPEGMetadata(data, exif, m.ICC, m.XMP)
+	case PNG:
+		out, err = appendPNGMetadata(data, exif, m.ICC, m.XMP)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// updateEXIF returns a copy of the EXIF data with the orientation tag set to 1
+// and the pixel dimension tags set to the given size. It returns nil if the EXIF
+// data can't be parsed: writing it unchanged could keep an orientation that no
+// longer matches the pixels.
+func updateEXIF(tiff []byte, width, height int) []byte {
+	if len(tiff) == 0 {
+		return nil
+	}
+	tiff = append([]byte(nil), tiff...)
+	e, err := parseEXIF(tiff)
+	if err != nil {
+		return nil
+	}
+
+	set := func(ifd IFD, id uint16, v int) {
+		t, ok := e.Tag(ifd, id)
+		if !ok || t.Count != 1 {
+			return
+		}
+		switch {
+		case t.Type == EXIFShort && v <= 0xffff:
+			t.order.PutUint16(t.Value, uint16(v))
+		case t.Type == EXIFLong:
+			t.order.PutUint32(t.Value, uint32(v))
+		}
+	}
+	set(IFD0, TagOrientation, int(OrientationNormal))
+	set(ExifIFD, TagPixelXDimension, width)
+	set(ExifIFD, TagPixelYDimension, height)
+	return tiff
+}
+
+// appendJPEGMetadata returns the JPEG image data with the metadata segments
+// inserted after the SOI marker.
+func appendJPEGMetadata(data, exif, icc, xmp []byte) ([]byte, error) {
+	const (
+		markerAPP1 = 0xe1
+		markerAPP2 = 0xe2
+		// iccChunkSize is the maximum size of an ICC profile chunk in an APP2 segment.
+		iccChunkSize = jpegMaxSegmentData - len(iccHeader) - 2
+	)
+
+	segment := func(buf []byte, marker byte, parts ...[]byte) []byte {
+		n := 2
+		for _, p := range parts {
+			n += len(p)
+		}
+		buf = append(buf, 0xff, marker, byte(n>>8), byte(n))
+		for _, p := range parts {
+			buf = append(buf, p...)
+		}
+		return buf
+	}
+
+	out := append([]byte(nil), data[:2]...)
+	if len(exif) > 0 {
+		if len(exifHeader)+len(exif) > jpegMaxSegmentData {
+			return nil, errors.New("imaging: EXIF data is too large for a JPEG image")
+		}
+		out = segment(out, markerAPP1, []byte(exifHeader), exif)
+	}
+	if len(icc) > 0 {
+		count := (len(icc) + iccChunkSize - 1) / iccChunkSize
+		if count > 255 {
+			return nil, errors.New("imaging: ICC profile is too large for a JPEG image")
+		}
+		for i := 0; i < count; i++ {
+			end := (i + 1) * iccChunkSize
+			if end > len(icc) {
+				end = len(icc)
+			}
+			out = segment(out, markerAPP2, []byte(iccHeader), []byte{byte(i + 1), byte(count)}, icc[i*iccChunkSize:end])
+		}
+	}
+	if len(xmp) > 0 {
+		if len(xmpHeader)+len(xmp) > jpegMaxSegmentData {
+			return nil, errors.New("imaging: XMP data is too large for a JPEG image")
+		}
+		out = segment(out, markerAPP1, []byte(xmpHeader), xmp)
+	}
+	return append(out, data[2:]...), nil
+}
+
+// appendPNGMetadata returns the PNG image data with the metadata chunks
+// inserted after the IHDR chunk.
+func appendPNGMetadata(data, exif, icc, xmp []byte) ([]byte, error) {
+	// The IHDR chunk always has 13 bytes of data.
+	const ihdrEnd = len(pngSignature) + 12 + 13
+
+	out := append([]byte(nil), data[:ihdrEnd]...)
+	if len(icc) > 0 {
+		compressed := &bytes.Buffer{}
+		zw := zlib.NewWriter(compressed)
+		if _, err := zw.Write(icc); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		out = appendPNGChunk(out, "iCCP", append([]byte("ICC Profile\x00\x00"), compressed.Bytes()...))
+	}
+	if len(exif) > 0 {
+		out = appendPNGChunk(out, "eXIf", exif)
+	}
+	if len(xmp) > 0 {
+		// Uncompressed text with empty language tag and translated keyword.
+		text := append([]byte(xmpKeyword+"\x00\x00\x00\x00\x00"), xmp...)
+		out = appendPNGChunk(out, "iTXt", text)
+	}
+	return append(out, data[ihdrEnd:]...), nil
+}
@@ -0,0 +1,367 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// makeMetadataJPEG returns a JPEG image with EXIF, ICC profile and XMP segments.
+// The ICC profile is split in as many APP2 segments as needed.
+func makeMetadataJPEG(t *testing.T, m *Metadata) []byte {
+	t.Helper()
+	img := &bytes.Buffer{}
+	if err := Encode(img, New(8, 4, color.White), JPEG); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	data, err := appendJPEGMetadata(img.Bytes(), m.EXIF, m.ICC, m.XMP)
+	if err != nil {
+		t.Fatalf("failed to add metadata: %v", err)
+	}
+	return data
+}
+
+// makeMetadataEXIF returns EXIF data with the orientation and pixel dimension tags.
+func makeMetadataEXIF(order binary.ByteOrder) []byte {
+	return makeTestTIFF(order, &testIFD{
+		entries: []testIFDEntry{
+			withID(TagOrientation, testValues(order, EXIFShort, 6)),
+			withID(TagSoftware, testASCII("imaging")),
+		},
+		subs: map[uint16]*testIFD{
+			TagExifIFDPointer: {
+				entries: []testIFDEntry{
+					withID(TagPixelXDimension, testValues(order, EXIFShort, 8)),
+					withID(TagPixelYDimension, testValues(order, EXIFLong, 4)),
+				},
+			},
+		},
+	})
+}
+
+func TestReadMetadataJPEG(t *testing.T) {
+	t.Parallel()
+
+	icc := bytes.Repeat([]byte("0123456789"), 15000)
+	want := &Metadata{
+		EXIF: makeMetadataEXIF(binary.BigEndian),
+		ICC:  icc,
+		XMP:  []byte("<x:xmpmeta xmlns:x='adobe:ns:meta/'></x:xmpmeta>"),
+	}
+	data := makeMetadataJPEG(t, want)
+	if n := bytes.Count(data, []byte(iccHeader)); n != 3 {
+		t.Fatalf("got %d ICC segments want 3", n)
+	}
+
+	got, err := ReadMetadata(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMetadata: %v", err)
+	}
+	compareMetadata(t, got, want)
+}
+
+func TestReadMetadataEmpty(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []Format{JPEG, PNG, GIF, BMP, WEBP} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, New(2, 2, color.Black), format); err != nil {
+			t.Fatalf("failed to encode %v: %v", format, err)
+		}
+		m, err := ReadMetadata(buf)
+		if err != nil {
+			t.Fatalf("ReadMetadata(%v): %v", format, err)
+		}
+		compareMetadata(t, m, &Metadata{})
+	}
+
+	if _, err := ReadMetadata(strings.NewReader("invalid data")); err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if _, err := ReadMetadata(strings.NewReader("\xff\xd8\xff\xe1\x00\x01")); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
+
+func TestReadMetadataWebP(t *testing.T) {
+	t.Parallel()
+
+	want := &Metadata{
+		EXIF: makeMetadataEXIF(binary.LittleEndian),
+		ICC:  []byte("icc profile"),
+		XMP:  []byte("<x:xmpmeta/>"),
+	}
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, New(2, 2, color.Black), WEBP); err != nil {
+		t.Fatalf("failed to encode WebP: %v", err)
+	}
+	data := buf.Bytes()
+	for _, c := range []struct {
+		fourCC string
+		data   []byte
+	}{{"ICCP", want.ICC}, {"EXIF", want.EXIF}, {"XMP ", want.XMP}} {
+		data = append(data, c.fourCC...)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(c.data)))
+		data = append(data, c.data...)
+		if len(c.data)%2 == 1 {
+			data = append(data, 0)
+		}
+	}
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	got, err := ReadMetadata(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMetadata: %v", err)
+	}
+	compareMetadata(t, got, want)
+}
+
+func TestWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		src := &Metadata{
+			EXIF: makeMetadataEXIF(order),
+			ICC:  bytes.Repeat([]byte("profile"), 10000),
+			XMP:  []byte("<x:xmpmeta xmlns:x='adobe:ns:meta/'></x:xmpmeta>"),
+		}
+		data := makeMetadataJPEG(t, src)
+
+		img, m, err := DecodeWithMetadata(bytes.NewReader(data), AutoOrientation(true))
+		if err != nil {
+			t.Fatalf("DecodeWithMetadata: %v", err)
+		}
+		if size := img.Bounds().Size(); size != image.Pt(4, 8) {
+			t.Fatalf("got size %v want (4,8)", size)
+		}
+		compareMetadata(t, m, src)
+		resized := Resize(img, 2, 0, Box)
+
+		for _, format := range []Format{JPEG, PNG} {
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, resized, format, WithMetadata(m)); err != nil {
+				t.Fatalf("failed to encode %v: %v", format, err)
+			}
+			out := buf.Bytes()
+
+			got, err := ReadMetadata(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("ReadMetadata(%v): %v", format, err)
+			}
+			if !bytes.Equal(got.ICC, src.ICC) || !bytes.Equal(got.XMP, src.XMP) {
+				t.Fatalf("%v: ICC profile or XMP data not preserved", format)
+			}
+
+			x, err := ReadEXIF(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("ReadEXIF(%v): %v", format, err)
+			}
+			if o := x.Orientation(); o != OrientationNormal {
+				t.Fatalf("%v: got orientation %d want %d", format, o, OrientationNormal)
+			}
+			for _, dim := range []struct {
+				id   uint16
+				want int64
+			}{{TagPixelXDimension, 2}, {TagPixelYDimension, 4}} {
+				tag, ok := x.Tag(ExifIFD, dim.id)
+				if !ok {
+					t.Fatalf("%v: missing tag 0x%04x", format, dim.id)
+				}
+				if v, err := tag.Int(0); err != nil || v != dim.want {
+					t.Fatalf("%v: got tag 0x%04x value %d (%v) want %d", format, dim.id, v, err, dim.want)
+				}
+			}
+			if tag, ok := x.Tag(IFD0, TagSoftware); !ok {
+				t.Fatalf("%v: missing software tag", format)
+			} else if s, _ := tag.StringVal(); s != "imaging" {
+				t.Fatalf("%v: got software %q want %q", format, s, "imaging")
+			}
+
+			decoded, err := Decode(bytes.NewReader(out), AutoOrientation(true))
+			if err != nil {
+				t.Fatalf("Decode(%v): %v", format, err)
+			}
+			if size := decoded.Bounds().Size(); size != image.Pt(2, 4) {
+				t.Fatalf("%v: got size %v want (2,4)", format, size)
+			}
+		}
+
+		// The source metadata is not modified.
+		compareMetadata(t, m, src)
+	}
+}
+
+func TestReadMetadataPNGCompressedXMP(t *testing.T) {
+	t.Parallel()
+
+	xmp := []byte("<x:xmpmeta/>")
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, New(2, 2, color.Black), PNG); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	text := append([]byte(xmpKeyword+"\x00\x01\x00en\x00\x00"), zlibCompress(t, xmp)...)
+	data := buf.Bytes()
+	const ihdrEnd = len(pngSignature) + 12 + 13
+	out := appendPNGChunk(append([]byte(nil), data[:ihdrEnd]...), "tEXt", []byte("Comment\x00hello"))
+	out = appendPNGChunk(out, "iTXt", []byte("Comment\x00\x00\x00\x00\x00text"))
+	out = appendPNGChunk(out, "iTXt", text)
+	out = append(out, data[ihdrEnd:]...)
+
+	m, err := ReadMetadata(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("ReadMetadata: %v", err)
+	}
+	compareMetadata(t, m, &Metadata{XMP: xmp})
+}
+
+func TestWithMetadataOtherFormats(t *testing.T) {
+	t.Parallel()
+
+	m := &Metadata{EXIF: makeMetadataEXIF(binary.BigEndian), XMP: []byte("<x:xmpmeta/>")}
+	img := New(2, 2, color.Black)
+	for _, format := range []Format{GIF, TIFF, BMP, WEBP} {
+		want := &bytes.Buffer{}
+		if err := Encode(want, img, format); err != nil {
+			t.Fatalf("failed to encode %v: %v", format, err)
+		}
+		got := &bytes.Buffer{}
+		if err := Encode(got, img, format, WithMetadata(m)); err != nil {
+			t.Fatalf("failed to encode %v: %v", format, err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Fatalf("%v: metadata unexpectedly written", format)
+		}
+	}
+}
+
+func TestWithMetadataErrors(t *testing.T) {
+	t.Parallel()
+
+	img := New(2, 2, color.Black)
+	testCases := []struct {
+		name   string
+		format Format
+		m      *Metadata
+	}{
+		{"EXIF too large", JPEG, &Metadata{EXIF: append(makeMetadataEXIF(binary.BigEndian), make([]byte, 1<<16)...)}},
+		{"XMP too large", JPEG, &Metadata{XMP: make([]byte, 1<<16)}},
+		{"ICC too large", JPEG, &Metadata{ICC: make([]byte, 256<<16)}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := Encode(&bytes.Buffer{}, img, tc.format, WithMetadata(tc.m)); err == nil {
+				t.Fatal("expected error got nil")
+			}
+		})
+	}
+}
+
+func TestWithMetadataInvalidEXIF(t *testing.T) {
+	t.Parallel()
+
+	// EXIF data with the Exif directory pointer out of range.
+	corrupted := makeMetadataEXIF(binary.BigEndian)
+	e, err := parseEXIF(corrupted)
+	if err != nil {
+		t.Fatalf("parseEXIF: %v", err)
+	}
+	tag, ok := e.Tag(IFD0, TagExifIFDPointer)
+	if !ok {
+		t.Fatal("missing Exif directory pointer")
+	}
+	tag.order.PutUint32(tag.Value, 0xfffffff0)
+	if _, err := parseEXIF(corrupted); err == nil {
+		t.Fatal("expected corrupted EXIF data")
+	}
+
+	img := New(2, 2, color.Black)
+	for _, format := range []Format{JPEG, PNG} {
+		for _, exif := range [][]byte{corrupted, []byte("invalid")} {
+			m := &Metadata{EXIF: exif, ICC: []byte("icc profile"), XMP: []byte("<x:xmpmeta/>")}
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, img, format, WithMetadata(m)); err != nil {
+				t.Fatalf("%v: Encode: %v", format, err)
+			}
+			if _, err := Decode(bytes.NewReader(buf.Bytes())); err != nil {
+				t.Fatalf("%v: Decode: %v", format, err)
+			}
+			got, err := ReadMetadata(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("%v: ReadMetadata: %v", format, err)
+			}
+			if got.EXIF != nil {
+				t.Fatalf("%v: invalid EXIF data written", format)
+			}
+			if string(got.ICC) != "icc profile" || string(got.XMP) != "<x:xmpmeta/>" {
+				t.Fatalf("%v: got ICC %q and XMP %q", format, got.ICC, got.XMP)
+			}
+		}
+	}
+}
+
+func TestOpenWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	img, m, err := OpenWithMetadata("testdata/orientation_6.jpg", AutoOrientation(true))
+	if err != nil {
+		t.Fatalf("OpenWithMetadata: %v", err)
+	}
+	want, err := Open("testdata/orientation_6.jpg", AutoOrientation(true))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !compareNRGBA(Clone(img), Clone(want), 0) {
+		t.Fatal("decoded image does not match the reference image")
+	}
+	x, err := ReadEXIF(bytes.NewReader(m.EXIF))
+	if err != nil {
+		t.Fatalf("ReadEXIF: %v", err)
+	}
+	if o := x.Orientation(); o != OrientationRotate270 {
+		t.Fatalf("got orientation %d want %d", o, OrientationRotate270)
+	}
+
+	if _, _, err := OpenWithMetadata("testdata/missing.jpg"); err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if _, _, err := DecodeWithMetadata(strings.NewReader("invalid data")); err == nil {
+		t.Fatal("expected error got nil")
+	}
+	data := makeMetadataJPEG(t, &Metadata{XMP: []byte("<x:xmpmeta/>")})
+	if _, _, err := DecodeWithMetadata(bytes.NewReader(data), MaxInputBytes(10)); err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
+
+func compareMetadata(t *testing.T, got, want *Metadata) {
+	t.Helper()
+	if !bytes.Equal(got.EXIF, want.EXIF) {
+		t.Fatalf("got EXIF data %q want %q", got.EXIF, want.EXIF)
+	}
+	if !bytes.Equal(got.ICC, want.ICC) {
+		t.Fatalf("got ICC profile of %d bytes want %d bytes", len(got.ICC), len(want.ICC))
+	}
+	if !bytes.Equal(got.XMP, want.XMP) {
+		t.Fatalf("got XMP data %q want %q", got.XMP, want.XMP)
+	}
+}
+
+func zlibCompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zlib.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
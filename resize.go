@@ -2,6 +2,7 @@ package imaging
 
 import (
 	"image"
+	"image/color"
 	"math"
 )
 
@@ -54,6 +55,90 @@ func precomputeWeights(dstSize, srcSize int, filter ResampleFilter) [][]indexWei
 	return out
 }
 
+// resizeConfig holds the optional parameters of the resizing functions.
+type resizeConfig struct {
+	// linearLight enables resampling in linear light.
+	linearLight bool
+	// weights caches the resampling weights between calls. It's set by Resizer.
+	weights *weightCache
+	// focal is the fractional focal point Thumbnail crops around, nil for the center.
+	focal *[2]float64
+	// padBlur is the blur sigma of the background of Pad. Zero means a flat background.
+	padBlur float64
+}
+
+// defaultResizeConfig is the default resize config.
+var defaultResizeConfig = resizeConfig{
+	linearLight: false,
+	weights:     nil,
+	focal:       nil,
+	padBlur:     0,
+}
+
+// ResizeOption sets an optional parameter for the ResizeWithOptions, FitWithOptions,
+// FillWithOptions and ThumbnailWithOptions functions, their 16-bit variants and the other
+// resizing functions taking options, such as ResizeInto and Pad.
+type ResizeOption func(*resizeConfig)
+
+// newResizeConfig returns the resize config with the options applied.
+func newResizeConfig(opts []ResizeOption) resizeConfig {
+	cfg := defaultResizeConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// ThumbnailFocalPoint returns a ResizeOption that makes ThumbnailWithOptions crop the image
+// around the fractional focal point (x, y) as FillFocal does. Other functions ignore it.
+// By default the image is cropped around the center.
+//
+// Example:
+//
+//	dstImage := imaging.ThumbnailWithOptions(srcImage, 100, 100, imaging.Lanczos, imaging.ThumbnailFocalPoint(0.3, 0.2))
+func ThumbnailFocalPoint(x, y float64) ResizeOption {
+	return func(c *resizeConfig) {
+		c.focal = &[2]float64{x, y}
+	}
+}
+
+// PadBlur returns a ResizeOption that makes Pad fill the background with a blurred copy of
+// the image, enlarged to cover the whole canvas, instead of a flat color. The sigma parameter
+// is the blur strength as in Blur. The background color remains visible through transparent
+// pixels. Other functions ignore it. By default the background is flat.
+//
+// Example:
+//
+//	dstImage := imaging.Pad(srcImage, 1200, 630, imaging.Center, color.Black, imaging.Lanczos, imaging.PadBlur(20))
+func PadBlur(sigma float64) ResizeOption {
+	return func(c *resizeConfig) {
+		c.padBlur = sigma
+	}
+}
+
+// precomputeWeights returns the resampling weights, using the weight cache if any.
+func (c resizeConfig) precomputeWeights(dstSize, srcSize int, filter ResampleFilter) [][]indexWeight {
+	if c.weights != nil {
+		return c.weights.get(dstSize, srcSize, filter)
+	}
+	return precomputeWeights(dstSize, srcSize, filter)
+}
+
+// LinearLight returns a ResizeOption that enables gamma-correct resampling. If enabled,
+// the sRGB encoded colors are converted to linear light before resampling and back
+// afterwards, with 16 bits per channel in between. Averaging sRGB encoded values
+// darkens high-contrast details, such as thin bright lines or text, when downscaling.
+// It has no effect with the NearestNeighbor filter. By default it's disabled.
+//
+// Example:
+//
+//	dstImage := imaging.ResizeWithOptions(srcImage, 800, 0, imaging.Lanczos, imaging.LinearLight(true))
+func LinearLight(enabled bool) ResizeOption {
+	return func(c *resizeConfig) {
+		c.linearLight = enabled
+	}
+}
+
 // Resize resizes the image to the specified width and height using the specified resampling
 // filter and returns the transformed image. If one of width or height is 0, the image aspect
 // ratio is preserved.
@@ -62,18 +147,91 @@ func precomputeWeights(dstSize, srcSize int, filter ResampleFilter) [][]indexWei
 //
 //	dstImage := imaging.Resize(srcImage, 800, 600, imaging.Lanczos)
 func Resize(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA {
-	dstW, dstH := width, height
-	if dstW < 0 || dstH < 0 {
+	return ResizeWithOptions(img, width, height, filter)
+}
+
+// ResizeWithOptions is like Resize but takes optional parameters such as LinearLight.
+//
+// Example:
+//
+//	dstImage := imaging.ResizeWithOptions(srcImage, 800, 0, imaging.Lanczos, imaging.LinearLight(true))
+func ResizeWithOptions(img image.Image, width, height int, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
+	dstW, dstH, ok := resizeSize(img.Bounds(), width, height)
+	if !ok {
 		return &image.NRGBA{}
 	}
-	if dstW == 0 && dstH == 0 {
-		return &image.NRGBA{}
+
+	srcW := img.Bounds().Dx()
+	srcH := img.Bounds().Dy()
+	if srcW == dstW && srcH == dstH {
+		return Clone(img)
 	}
 
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	resizeInto(dst, img, filter, newResizeConfig(opts))
+	return dst
+}
+
+// ResizeInto is like Resize but resizes the image to the size of dst and stores the result
+// in dst instead of allocating a new image. The intermediate image is reused between calls.
+// It returns dst.
+//
+// Example:
+//
+//	// Reuse the thumbnail buffer for every uploaded image.
+//	thumb := image.NewNRGBA(image.Rect(0, 0, 200, 200))
+//	imaging.ResizeInto(thumb, srcImage, imaging.Lanczos)
+func ResizeInto(dst *image.NRGBA, img image.Image, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
+	if dst.Rect.Empty() || img.Bounds().Empty() {
+		return dst
+	}
+	resizeInto(dst, img, filter, newResizeConfig(opts))
+	return dst
+}
+
+// resizeInto resizes the non-empty image to the size of dst and stores the result in dst.
+func resizeInto(dst *image.NRGBA, img image.Image, filter ResampleFilter, cfg resizeConfig) {
 	srcW := img.Bounds().Dx()
 	srcH := img.Bounds().Dy()
+	dstW := dst.Rect.Dx()
+	dstH := dst.Rect.Dy()
+
+	switch {
+	case srcW == dstW && srcH == dstH:
+		cropInto(dst, img, image.Rect(0, 0, srcW, srcH))
+	case filter.Support <= 0:
+		// Nearest-neighbor special case.
+		resizeNearest(dst, img)
+	case cfg.linearLight:
+		fromLinearInto(dst, resample16(toLinear16(img), dstW, dstH, filter, cfg))
+	case srcW != dstW && srcH != dstH:
+		tmp := getNRGBA(dstW, srcH)
+		resizeHorizontal(tmp, img, cfg.precomputeWeights(dstW, srcW, filter))
+		resizeVertical(dst, tmp, cfg.precomputeWeights(dstH, srcH, filter))
+		putNRGBA(tmp)
+	case srcW != dstW:
+		resizeHorizontal(dst, img, cfg.precomputeWeights(dstW, srcW, filter))
+	default:
+		resizeVertical(dst, img, cfg.precomputeWeights(dstH, srcH, filter))
+	}
+}
+
+// resizeSize returns the size of the image with the bounds b resized to the specified width and height.
+// If one of width or height is 0, the image aspect ratio is preserved. It returns false if the image
+// can't be resized.
+func resizeSize(b image.Rectangle, width, height int) (int, int, bool) {
+	dstW, dstH := width, height
+	if dstW < 0 || dstH < 0 {
+		return 0, 0, false
+	}
+	if dstW == 0 && dstH == 0 {
+		return 0, 0, false
+	}
+
+	srcW := b.Dx()
+	srcH := b.Dy()
 	if srcW <= 0 || srcH <= 0 {
-		return &image.NRGBA{}
+		return 0, 0, false
 	}
 
 	// If new width or height is 0 then preserve aspect ratio, minimum 1px.
@@ -85,32 +243,16 @@ func Resize(img image.Image, width, height int, filter ResampleFilter) *image.NR
 		tmpH := float64(dstW) * float64(srcH) / float64(srcW)
 		dstH = int(math.Max(1.0, math.Floor(tmpH+0.5)))
 	}
-
-	if srcW == dstW && srcH == dstH {
-		return Clone(img)
-	}
-
-	if filter.Support <= 0 {
-		// Nearest-neighbor special case.
-		return resizeNearest(img, dstW, dstH)
-	}
-
-	if srcW != dstW && srcH != dstH {
-		return resizeVertical(resizeHorizontal(img, dstW, filter), dstH, filter)
-	}
-	if srcW != dstW {
-		return resizeHorizontal(img, dstW, filter)
-	}
-	return resizeVertical(img, dstH, filter)
-
+	return dstW, dstH, true
 }
 
-func resizeHorizontal(img image.Image, width int, filter ResampleFilter) *image.NRGBA {
+// resizeHorizontal resizes the image to the width of dst using the resampling weights
+// and stores the result in dst, which must have the height of the image.
+func resizeHorizontal(dst *image.NRGBA, img image.Image, weights [][]indexWeight) {
 	src := newScanner(img)
-	dst := image.NewNRGBA(image.Rect(0, 0, width, src.h))
-	weights := precomputeWeights(width, src.w, filter)
 	parallel(0, src.h, func(ys <-chan int) {
-		scanLine := make([]uint8, src.w*4)
+		scanLine := getPix(src.w * 4)
+		defer putPix(scanLine)
 		for y := range ys {
 			src.scan(0, y, src.w, y+1, scanLine)
 			j0 := y * dst.Stride
@@ -125,27 +267,32 @@ func resizeHorizontal(img image.Image, width int, filter ResampleFilter) *image.
 					b += float64(s[2]) * aw
 					a += aw
 				}
+				j := j0 + x*4
+				d := dst.Pix[j : j+4 : j+4]
 				if a != 0 {
 					aInv := 1 / a
-					j := j0 + x*4
-					d := dst.Pix[j : j+4 : j+4]
 					d[0] = clamp(r * aInv)
 					d[1] = clamp(g * aInv)
 					d[2] = clamp(b * aInv)
 					d[3] = clamp(a)
+				} else {
+					d[0] = 0
+					d[1] = 0
+					d[2] = 0
+					d[3] = 0
 				}
 			}
 		}
 	})
-	return dst
 }
 
-func resizeVertical(img image.Image, height int, filter ResampleFilter) *image.NRGBA {
+// resizeVertical resizes the image to the height of dst using the resampling weights
+// and stores the result in dst, which must have the width of the image.
+func resizeVertical(dst *image.NRGBA, img image.Image, weights [][]indexWeight) {
 	src := newScanner(img)
-	dst := image.NewNRGBA(image.Rect(0, 0, src.w, height))
-	weights := precomputeWeights(height, src.h, filter)
 	parallel(0, src.w, func(xs <-chan int) {
-		scanLine := make([]uint8, src.h*4)
+		scanLine := getPix(src.h * 4)
+		defer putPix(scanLine)
 		for x := range xs {
 			src.scan(x, 0, x+1, src.h, scanLine)
 			for y := range weights {
@@ -159,24 +306,29 @@ func resizeVertical(img image.Image, height int, filter ResampleFilter) *image.N
 					b += float64(s[2]) * aw
 					a += aw
 				}
+				j := y*dst.Stride + x*4
+				d := dst.Pix[j : j+4 : j+4]
 				if a != 0 {
 					aInv := 1 / a
-					j := y*dst.Stride + x*4
-					d := dst.Pix[j : j+4 : j+4]
 					d[0] = clamp(r * aInv)
 					d[1] = clamp(g * aInv)
 					d[2] = clamp(b * aInv)
 					d[3] = clamp(a)
+				} else {
+					d[0] = 0
+					d[1] = 0
+					d[2] = 0
+					d[3] = 0
 				}
 			}
 		}
 	})
-	return dst
 }
 
-// resizeNearest is a fast nearest-neighbor resize, no filtering.
-func resizeNearest(img image.Image, width, height int) *image.NRGBA {
-	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+// resizeNearest is a fast nearest-neighbor resize to the size of dst, no filtering.
+func resizeNearest(dst *image.NRGBA, img image.Image) {
+	width := dst.Rect.Dx()
+	height := dst.Rect.Dy()
 	dx := float64(img.Bounds().Dx()) / float64(width)
 	dy := float64(img.Bounds().Dy()) / float64(height)
 
@@ -209,8 +361,6 @@ func resizeNearest(img image.Image, width, height int) *image.NRGBA {
 			}
 		})
 	}
-
-	return dst
 }
 
 // Fit scales down the image using the specified resample filter to fit the specified
@@ -220,6 +370,15 @@ func resizeNearest(img image.Image, width, height int) *image.NRGBA {
 //
 //	dstImage := imaging.Fit(srcImage, 800, 600, imaging.Lanczos)
 func Fit(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA {
+	return FitWithOptions(img, width, height, filter)
+}
+
+// FitWithOptions is like Fit but takes optional parameters such as LinearLight.
+//
+// Example:
+//
+//	dstImage := imaging.FitWithOptions(srcImage, 800, 600, imaging.Lanczos, imaging.LinearLight(true))
+func FitWithOptions(img image.Image, width, height int, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
 	maxW, maxH := width, height
 
 	if maxW <= 0 || maxH <= 0 {
@@ -238,6 +397,38 @@ func Fit(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA
 		return Clone(img)
 	}
 
+	newW, newH := fitSize(srcW, srcH, maxW, maxH)
+	return ResizeWithOptions(img, newW, newH, filter, opts...)
+}
+
+// FitInto is like Fit but scales down the image to fit the size of dst and stores the result
+// in the top-left corner of dst instead of allocating a new image. The intermediate image
+// is reused between calls. It returns the sub-image of dst holding the result.
+//
+// Example:
+//
+//	buf := image.NewNRGBA(image.Rect(0, 0, 800, 600))
+//	dstImage := imaging.FitInto(buf, srcImage, imaging.Lanczos)
+func FitInto(dst *image.NRGBA, img image.Image, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
+	maxW, maxH := dst.Rect.Dx(), dst.Rect.Dy()
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	if maxW <= 0 || maxH <= 0 || srcW <= 0 || srcH <= 0 {
+		return &image.NRGBA{}
+	}
+
+	newW, newH := srcW, srcH
+	if srcW > maxW || srcH > maxH {
+		// As in Fit, a zero dimension is computed by preserving the aspect ratio.
+		newW, newH = fitSize(srcW, srcH, maxW, maxH)
+		newW, newH, _ = resizeSize(img.Bounds(), newW, newH)
+	}
+	out := dst.SubImage(image.Rect(0, 0, newW, newH).Add(dst.Rect.Min)).(*image.NRGBA)
+	resizeInto(out, img, filter, newResizeConfig(opts))
+	return out
+}
+
+// fitSize returns the size of the image scaled down to fit the specified maximum width and height.
+func fitSize(srcW, srcH, maxW, maxH int) (int, int) {
 	srcAspectRatio := float64(srcW) / float64(srcH)
 	maxAspectRatio := float64(maxW) / float64(maxH)
 
@@ -249,8 +440,7 @@ func Fit(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA
 		newH = maxH
 		newW = int(float64(newH) * srcAspectRatio)
 	}
-
-	return Resize(img, newW, newH, filter)
+	return newW, newH
 }
 
 // Fill creates an image with the specified dimensions and fills it with the scaled source image.
@@ -260,6 +450,30 @@ func Fit(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA
 //
 //	dstImage := imaging.Fill(srcImage, 800, 600, imaging.Center, imaging.Lanczos)
 func Fill(img image.Image, width, height int, anchor Anchor, filter ResampleFilter) *image.NRGBA {
+	return FillWithOptions(img, width, height, anchor, filter)
+}
+
+// FillWithOptions is like Fill but takes optional parameters such as LinearLight.
+//
+// Example:
+//
+//	dstImage := imaging.FillWithOptions(srcImage, 800, 600, imaging.Center, imaging.Lanczos, imaging.LinearLight(true))
+func FillWithOptions(img image.Image, width, height int, anchor Anchor, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
+	return fill(img, width, height, anchorPos(anchor), filter, opts...)
+}
+
+// FillFocal is like Fill but crops the source image around the fractional focal point (x, y)
+// as CropFocal does, keeping the focal point as close to the center of the result as possible.
+//
+// Example:
+//
+//	dstImage := imaging.FillFocal(srcImage, 200, 200, 0.3, 0.2, imaging.Lanczos)
+func FillFocal(img image.Image, width, height int, x, y float64, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
+	return fill(img, width, height, focalPos(x, y), filter, opts...)
+}
+
+// fill implements Fill and FillFocal, placing the cropped region with pos.
+func fill(img image.Image, width, height int, pos regionPos, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
 	dstW, dstH := width, height
 
 	if dstW <= 0 || dstH <= 0 {
@@ -279,66 +493,343 @@ func Fill(img image.Image, width, height int, anchor Anchor, filter ResampleFilt
 	}
 
 	if srcW >= 100 && srcH >= 100 {
-		return cropAndResize(img, dstW, dstH, anchor, filter)
+		return cropAndResize(img, dstW, dstH, pos, filter, opts...)
 	}
-	return resizeAndCrop(img, dstW, dstH, anchor, filter)
+	return resizeAndCrop(img, dstW, dstH, pos, filter, opts...)
 }
 
 // cropAndResize crops the image to the smallest possible size that has the required aspect ratio using
-// the given anchor point, then scales it to the specified dimensions and returns the transformed image.
+// the given position, then scales it to the specified dimensions and returns the transformed image.
 //
 // This is generally faster than resizing first, but may result in inaccuracies when used on small source images.
-func cropAndResize(img image.Image, width, height int, anchor Anchor, filter ResampleFilter) *image.NRGBA {
+func cropAndResize(img image.Image, width, height int, pos regionPos, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
+	cropW, cropH := fillCropSize(img.Bounds(), width, height)
+	tmp := Crop(img, cropRegion(img.Bounds(), cropW, cropH, pos))
+	return ResizeWithOptions(tmp, width, height, filter, opts...)
+}
+
+// fillCropSize returns the size of the largest region of the image with the bounds b
+// that has the aspect ratio of the specified dimensions.
+func fillCropSize(b image.Rectangle, width, height int) (int, int) {
 	dstW, dstH := width, height
 
-	srcBounds := img.Bounds()
-	srcW := srcBounds.Dx()
-	srcH := srcBounds.Dy()
+	srcW := b.Dx()
+	srcH := b.Dy()
 	srcAspectRatio := float64(srcW) / float64(srcH)
 	dstAspectRatio := float64(dstW) / float64(dstH)
 
-	var tmp *image.NRGBA
 	if srcAspectRatio < dstAspectRatio {
 		cropH := float64(srcW) * float64(dstH) / float64(dstW)
-		tmp = CropAnchor(img, srcW, int(math.Max(1, cropH)+0.5), anchor)
-	} else {
-		cropW := float64(srcH) * float64(dstW) / float64(dstH)
-		tmp = CropAnchor(img, int(math.Max(1, cropW)+0.5), srcH, anchor)
+		return srcW, int(math.Max(1, cropH) + 0.5)
 	}
-
-	return Resize(tmp, dstW, dstH, filter)
+	cropW := float64(srcH) * float64(dstW) / float64(dstH)
+	return int(math.Max(1, cropW) + 0.5), srcH
 }
 
 // resizeAndCrop resizes the image to the smallest possible size that will cover the specified dimensions,
-// crops the resized image to the specified dimensions using the given anchor point and returns
+// crops the resized image to the specified dimensions using the given position and returns
 // the transformed image.
-func resizeAndCrop(img image.Image, width, height int, anchor Anchor, filter ResampleFilter) *image.NRGBA {
+func resizeAndCrop(img image.Image, width, height int, pos regionPos, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
+	resizeW, resizeH := fillResizeSize(img.Bounds(), width, height)
+	tmp := ResizeWithOptions(img, resizeW, resizeH, filter, opts...)
+	return Crop(tmp, cropRegion(tmp.Bounds(), width, height, pos))
+}
+
+// fillResizeSize returns the arguments of Resize that scale the image with the bounds b
+// to the smallest possible size that covers the specified dimensions.
+func fillResizeSize(b image.Rectangle, width, height int) (int, int) {
+	srcAspectRatio := float64(b.Dx()) / float64(b.Dy())
+	dstAspectRatio := float64(width) / float64(height)
+
+	if srcAspectRatio < dstAspectRatio {
+		return width, 0
+	}
+	return 0, height
+}
+
+// Thumbnail scales the image up or down using the specified resample filter, crops it
+// to the specified width and hight and returns the transformed image. The image is cropped
+// around the center.
+//
+// Example:
+//
+//	dstImage := imaging.Thumbnail(srcImage, 100, 100, imaging.Lanczos)
+func Thumbnail(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA {
+	return ThumbnailWithOptions(img, width, height, filter)
+}
+
+// ThumbnailWithOptions is like Thumbnail but takes optional parameters such as LinearLight.
+// With the ThumbnailFocalPoint option, the image is cropped around the focal point.
+//
+// Example:
+//
+//	dstImage := imaging.ThumbnailWithOptions(srcImage, 100, 100, imaging.Lanczos, imaging.ThumbnailFocalPoint(0.3, 0.2))
+func ThumbnailWithOptions(img image.Image, width, height int, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
+	if focal := newResizeConfig(opts).focal; focal != nil {
+		return FillFocal(img, width, height, focal[0], focal[1], filter, opts...)
+	}
+	return FillWithOptions(img, width, height, Center, filter, opts...)
+}
+
+// Pad scales the image up or down using the specified resample filter to the largest size
+// that fits the specified width and height, places it on a canvas of that size filled with
+// the background color at the given anchor point and returns the combined image. Unlike Fill,
+// no part of the image is cropped. With the PadBlur option, the canvas is filled with
+// a blurred copy of the image instead.
+//
+// Example:
+//
+//	// Letterbox the image on a black canvas for a 1200x630 social card.
+//	dstImage := imaging.Pad(srcImage, 1200, 630, imaging.Center, color.Black, imaging.Lanczos)
+func Pad(img image.Image, width, height int, anchor Anchor, bg color.Color, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA {
 	dstW, dstH := width, height
+	if dstW <= 0 || dstH <= 0 {
+		return &image.NRGBA{}
+	}
+
+	srcW := img.Bounds().Dx()
+	srcH := img.Bounds().Dy()
+	if srcW <= 0 || srcH <= 0 {
+		return &image.NRGBA{}
+	}
+
+	dst := New(dstW, dstH, bg)
+	if sigma := newResizeConfig(opts).padBlur; sigma > 0 {
+		background := Blur(FillWithOptions(img, dstW, dstH, Center, filter, opts...), sigma)
+		dst = Overlay(dst, background, image.Pt(0, 0), 1.0)
+	}
+
+	newW, newH := fitSize(srcW, srcH, dstW, dstH)
+	if newW < 1 {
+		newW = 1
+	}
+	if newH < 1 {
+		newH = 1
+	}
+	fitted := img
+	if newW != srcW || newH != srcH {
+		fitted = ResizeWithOptions(img, newW, newH, filter, opts...)
+	}
+	pt := anchorPt(dst.Rect, newW, newH, anchor)
+	return Overlay(dst, fitted, pt, 1.0)
+}
+
+// Resize16 is like Resize but processes the image with 16 bits per channel and returns
+// an *image.NRGBA64, preserving the precision of 16-bit images.
+//
+// Example:
+//
+//	dstImage := imaging.Resize16(srcImage, 800, 600, imaging.Lanczos)
+func Resize16(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA64 {
+	return Resize16WithOptions(img, width, height, filter)
+}
+
+// Resize16WithOptions is like Resize16 but takes optional parameters such as LinearLight.
+func Resize16WithOptions(img image.Image, width, height int, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA64 {
+	dstW, dstH, ok := resizeSize(img.Bounds(), width, height)
+	if !ok {
+		return &image.NRGBA64{}
+	}
+
+	srcW := img.Bounds().Dx()
+	srcH := img.Bounds().Dy()
+	if srcW == dstW && srcH == dstH {
+		return Clone16(img)
+	}
+
+	if filter.Support <= 0 {
+		// Nearest-neighbor special case.
+		return resizeNearest16(img, dstW, dstH)
+	}
+
+	cfg := newResizeConfig(opts)
+	if cfg.linearLight {
+		return fromLinear16(resample16(toLinear16(img), dstW, dstH, filter, cfg))
+	}
+	return resample16(img, dstW, dstH, filter, cfg)
+}
+
+// resample16 resizes the image to the specified width and height using the resampling filter
+// with 16 bits per channel.
+func resample16(img image.Image, width, height int, filter ResampleFilter, cfg resizeConfig) *image.NRGBA64 {
+	srcW := img.Bounds().Dx()
+	srcH := img.Bounds().Dy()
+	if srcW != width && srcH != height {
+		tmp := resizeHorizontal16(img, cfg.precomputeWeights(width, srcW, filter))
+		return resizeVertical16(tmp, cfg.precomputeWeights(height, srcH, filter))
+	}
+	if srcW != width {
+		return resizeHorizontal16(img, cfg.precomputeWeights(width, srcW, filter))
+	}
+	return resizeVertical16(img, cfg.precomputeWeights(height, srcH, filter))
+}
+
+func resizeHorizontal16(img image.Image, weights [][]indexWeight) *image.NRGBA64 {
+	src := newScanner(img)
+	dst := image.NewNRGBA64(image.Rect(0, 0, len(weights), src.h))
+	parallel(0, src.h, func(ys <-chan int) {
+		scanLine := make([]uint8, src.w*8)
+		for y := range ys {
+			src.scan16(0, y, src.w, y+1, scanLine)
+			j0 := y * dst.Stride
+			for x := range weights {
+				var r, g, b, a float64
+				for _, w := range weights[x] {
+					i := w.index * 8
+					sr, sg, sb, sa := pixel16(scanLine[i : i+8 : i+8])
+					aw := float64(sa) * w.weight
+					r += float64(sr) * aw
+					g += float64(sg) * aw
+					b += float64(sb) * aw
+					a += aw
+				}
+				if a != 0 {
+					aInv := 1 / a
+					j := j0 + x*8
+					setPixel16(dst.Pix[j:j+8:j+8], clamp16(r*aInv), clamp16(g*aInv), clamp16(b*aInv), clamp16(a))
+				}
+			}
+		}
+	})
+	return dst
+}
+
+func resizeVertical16(img image.Image, weights [][]indexWeight) *image.NRGBA64 {
+	src := newScanner(img)
+	dst := image.NewNRGBA64(image.Rect(0, 0, src.w, len(weights)))
+	parallel(0, src.w, func(xs <-chan int) {
+		scanLine := make([]uint8, src.h*8)
+		for x := range xs {
+			src.scan16(x, 0, x+1, src.h, scanLine)
+			for y := range weights {
+				var r, g, b, a float64
+				for _, w := range weights[y] {
+					i := w.index * 8
+					sr, sg, sb, sa := pixel16(scanLine[i : i+8 : i+8])
+					aw := float64(sa) * w.weight
+					r += float64(sr) * aw
+					g += float64(sg) * aw
+					b += float64(sb) * aw
+					a += aw
+				}
+				if a != 0 {
+					aInv := 1 / a
+					j := y*dst.Stride + x*8
+					setPixel16(dst.Pix[j:j+8:j+8], clamp16(r*aInv), clamp16(g*aInv), clamp16(b*aInv), clamp16(a))
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// resizeNearest16 is a fast nearest-neighbor resize with 16 bits per channel, no filtering.
+func resizeNearest16(img image.Image, width, height int) *image.NRGBA64 {
+	dst := image.NewNRGBA64(image.Rect(0, 0, width, height))
+	dx := float64(img.Bounds().Dx()) / float64(width)
+	dy := float64(img.Bounds().Dy()) / float64(height)
+
+	src := toNRGBA64(img)
+	parallel(0, height, func(ys <-chan int) {
+		for y := range ys {
+			srcY := int((float64(y) + 0.5) * dy)
+			srcOff0 := srcY * src.Stride
+			dstOff := y * dst.Stride
+			for x := 0; x < width; x++ {
+				srcX := int((float64(x) + 0.5) * dx)
+				srcOff := srcOff0 + srcX*8
+				copy(dst.Pix[dstOff:dstOff+8], src.Pix[srcOff:srcOff+8])
+				dstOff += 8
+			}
+		}
+	})
+
+	return dst
+}
+
+// Fit16 is like Fit but processes the image with 16 bits per channel and returns
+// an *image.NRGBA64, preserving the precision of 16-bit images.
+//
+// Example:
+//
+//	dstImage := imaging.Fit16(srcImage, 800, 600, imaging.Lanczos)
+func Fit16(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA64 {
+	return Fit16WithOptions(img, width, height, filter)
+}
+
+// Fit16WithOptions is like Fit16 but takes optional parameters such as LinearLight.
+func Fit16WithOptions(img image.Image, width, height int, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA64 {
+	maxW, maxH := width, height
+
+	if maxW <= 0 || maxH <= 0 {
+		return &image.NRGBA64{}
+	}
 
 	srcBounds := img.Bounds()
 	srcW := srcBounds.Dx()
 	srcH := srcBounds.Dy()
-	srcAspectRatio := float64(srcW) / float64(srcH)
-	dstAspectRatio := float64(dstW) / float64(dstH)
 
-	var tmp *image.NRGBA
-	if srcAspectRatio < dstAspectRatio {
-		tmp = Resize(img, dstW, 0, filter)
-	} else {
-		tmp = Resize(img, 0, dstH, filter)
+	if srcW <= 0 || srcH <= 0 {
+		return &image.NRGBA64{}
 	}
 
-	return CropAnchor(tmp, dstW, dstH, anchor)
+	if srcW <= maxW && srcH <= maxH {
+		return Clone16(img)
+	}
+
+	newW, newH := fitSize(srcW, srcH, maxW, maxH)
+	return Resize16WithOptions(img, newW, newH, filter, opts...)
 }
 
-// Thumbnail scales the image up or down using the specified resample filter, crops it
-// to the specified width and hight and returns the transformed image.
+// Fill16 is like Fill but processes the image with 16 bits per channel and returns
+// an *image.NRGBA64, preserving the precision of 16-bit images.
 //
 // Example:
 //
-//	dstImage := imaging.Thumbnail(srcImage, 100, 100, imaging.Lanczos)
-func Thumbnail(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA {
-	return Fill(img, width, height, Center, filter)
+//	dstImage := imaging.Fill16(srcImage, 800, 600, imaging.Center, imaging.Lanczos)
+func Fill16(img image.Image, width, height int, anchor Anchor, filter ResampleFilter) *image.NRGBA64 {
+	return Fill16WithOptions(img, width, height, anchor, filter)
+}
+
+// Fill16WithOptions is like Fill16 but takes optional parameters such as LinearLight.
+func Fill16WithOptions(img image.Image, width, height int, anchor Anchor, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA64 {
+	return fill16(img, width, height, anchorPos(anchor), filter, opts...)
+}
+
+// FillFocal16 is like FillFocal but processes the image with 16 bits per channel and returns
+// an *image.NRGBA64, preserving the precision of 16-bit images.
+func FillFocal16(img image.Image, width, height int, x, y float64, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA64 {
+	return fill16(img, width, height, focalPos(x, y), filter, opts...)
+}
+
+// fill16 implements Fill16 and FillFocal16, placing the cropped region with pos.
+func fill16(img image.Image, width, height int, pos regionPos, filter ResampleFilter, opts ...ResizeOption) *image.NRGBA64 {
+	dstW, dstH := width, height
+
+	if dstW <= 0 || dstH <= 0 {
+		return &image.NRGBA64{}
+	}
+
+	srcBounds := img.Bounds()
+	srcW := srcBounds.Dx()
+	srcH := srcBounds.Dy()
+
+	if srcW <= 0 || srcH <= 0 {
+		return &image.NRGBA64{}
+	}
+
+	if srcW == dstW && srcH == dstH {
+		return Clone16(img)
+	}
+
+	// See cropAndResize and resizeAndCrop for the choice between cropping and resizing first.
+	if srcW >= 100 && srcH >= 100 {
+		cropW, cropH := fillCropSize(srcBounds, dstW, dstH)
+		return Resize16WithOptions(Crop16(img, cropRegion(srcBounds, cropW, cropH, pos)), dstW, dstH, filter, opts...)
+	}
+	resizeW, resizeH := fillResizeSize(srcBounds, dstW, dstH)
+	tmp := Resize16WithOptions(img, resizeW, resizeH, filter, opts...)
+	return Crop16(tmp, cropRegion(tmp.Bounds(), dstW, dstH, pos))
 }
 
 // ResampleFilter specifies a resampling filter to be used for image resizing.
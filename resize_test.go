@@ -3,6 +3,8 @@ package imaging
 import (
 	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 	"path/filepath"
 	"testing"
 )
@@ -636,7 +638,7 @@ func TestResizeAndCrop(t *testing.T) {
 		tc := tc
 
 		t.Run(tc.name, func(t *testing.T) {
-			got := resizeAndCrop(tc.src, tc.w, tc.h, tc.a, tc.f)
+			got := resizeAndCrop(tc.src, tc.w, tc.h, anchorPos(tc.a), tc.f)
 			if !compareNRGBA(got, tc.want, 0) {
 				t.Fatalf("got result %#v want %#v", got, tc.want)
 			}
@@ -767,7 +769,7 @@ func TestCropAndResize(t *testing.T) {
 		tc := tc
 
 		t.Run(tc.name, func(t *testing.T) {
-			got := cropAndResize(tc.src, tc.w, tc.h, tc.a, tc.f)
+			got := cropAndResize(tc.src, tc.w, tc.h, anchorPos(tc.a), tc.f)
 			if !compareNRGBA(got, tc.want, 0) {
 				t.Fatalf("got result %#v want %#v", got, tc.want)
 			}
@@ -947,3 +949,381 @@ func BenchmarkFill(b *testing.B) {
 		}
 	}
 }
+
+func TestResizeSignatures(t *testing.T) {
+	t.Parallel()
+
+	// The resizing functions without options can be stored as function values
+	// of their original types.
+	var (
+		_ func(image.Image, int, int, ResampleFilter) *image.NRGBA           = Resize
+		_ func(image.Image, int, int, ResampleFilter) *image.NRGBA           = Fit
+		_ func(image.Image, int, int, Anchor, ResampleFilter) *image.NRGBA   = Fill
+		_ func(image.Image, int, int, ResampleFilter) *image.NRGBA           = Thumbnail
+		_ func(image.Image, int, int, ResampleFilter) *image.NRGBA64         = Resize16
+		_ func(image.Image, int, int, ResampleFilter) *image.NRGBA64         = Fit16
+		_ func(image.Image, int, int, Anchor, ResampleFilter) *image.NRGBA64 = Fill16
+	)
+}
+
+func TestResize16(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		w, h int
+		f    ResampleFilter
+	}{
+		{100, 0, Lanczos},
+		{0, 50, Box},
+		{300, 200, Linear},
+		{37, 91, CatmullRom},
+		{40, 30, NearestNeighbor},
+		{400, 300, NearestNeighbor},
+	} {
+		got := Clone(Resize16(testdataBranchesPNG, tc.w, tc.h, tc.f))
+		want := Resize(testdataBranchesPNG, tc.w, tc.h, tc.f)
+		if !compareNRGBA(got, want, 1) {
+			t.Fatalf("Resize16(%d, %d) does not match Resize", tc.w, tc.h)
+		}
+	}
+
+	for _, size := range [][2]int{{-1, 10}, {0, 0}} {
+		if got := Resize16(testdataBranchesPNG, size[0], size[1], Lanczos); got.Rect != (image.Rectangle{}) {
+			t.Fatalf("Resize16(%d, %d): got bounds %v want empty", size[0], size[1], got.Rect)
+		}
+	}
+
+	src := makeGradient16(1024, 4)
+	got := Resize16(src, 512, 2, Lanczos)
+	if got.Rect != image.Rect(0, 0, 512, 2) {
+		t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, 512, 2))
+	}
+	if levels := countLevels16(got); levels < 500 {
+		t.Fatalf("got %d levels of the 16-bit gradient, want at least 500", levels)
+	}
+	if levels := countLevels16(Clone16(Resize(src, 512, 2, Lanczos))); levels > 256 {
+		t.Fatalf("got %d levels after an 8-bit resize, want at most 256", levels)
+	}
+}
+
+func TestFit16(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range [][2]int{{100, 100}, {50, 200}, {1000, 1000}} {
+		got := Clone(Fit16(testdataBranchesPNG, size[0], size[1], Lanczos))
+		want := Fit(testdataBranchesPNG, size[0], size[1], Lanczos)
+		if !compareNRGBA(got, want, 1) {
+			t.Fatalf("Fit16(%d, %d) does not match Fit", size[0], size[1])
+		}
+	}
+	if got := Fit16(testdataBranchesPNG, 0, 10, Lanczos); got.Rect != (image.Rectangle{}) {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+	if levels := countLevels16(Fit16(makeGradient16(1024, 4), 512, 512, Linear)); levels < 500 {
+		t.Fatalf("got %d levels of the 16-bit gradient, want at least 500", levels)
+	}
+}
+
+func TestFill16(t *testing.T) {
+	t.Parallel()
+
+	for _, src := range []image.Image{testdataBranchesPNG, testdataFlowersSmallPNG, Crop(testdataBranchesPNG, image.Rect(0, 0, 90, 40))} {
+		for _, anchor := range []Anchor{Center, TopLeft, BottomRight} {
+			for _, size := range [][2]int{{100, 100}, {60, 20}, {150, 300}} {
+				got := Clone(Fill16(src, size[0], size[1], anchor, Lanczos))
+				want := Fill(src, size[0], size[1], anchor, Lanczos)
+				// Fill rounds to 8 bits between the horizontal and vertical passes.
+				if !compareNRGBA(got, want, 2) {
+					t.Fatalf("Fill16(%d, %d, %d) does not match Fill", size[0], size[1], anchor)
+				}
+			}
+		}
+	}
+	if got := Fill16(testdataBranchesPNG, 10, -1, Center, Lanczos); got.Rect != (image.Rectangle{}) {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
+
+// makeCheckerboard returns an image with alternating black and white pixels.
+func makeCheckerboard(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{0, 0, 0, 0xff}
+			if (x+y)%2 == 0 {
+				c = color.NRGBA{0xff, 0xff, 0xff, 0xff}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestResizeLinearLight(t *testing.T) {
+	t.Parallel()
+
+	src := makeCheckerboard(64, 64)
+	// Averaging sRGB values gives 50% gray (0x80) while averaging in linear light gives 0xbc.
+	testCases := []struct {
+		name string
+		img  *image.NRGBA
+		want uint8
+	}{
+		{"Resize", Resize(src, 32, 32, Box), 0x80},
+		{"Resize linear", ResizeWithOptions(src, 32, 32, Box, LinearLight(true)), 0xbc},
+		{"Resize linear disabled", ResizeWithOptions(src, 32, 32, Box, LinearLight(true), LinearLight(false)), 0x80},
+		{"Fit linear", FitWithOptions(src, 32, 40, Box, LinearLight(true)), 0xbc},
+		{"Fill linear", FillWithOptions(src, 32, 16, Center, Box, LinearLight(true)), 0xbc},
+		{"Fill small linear", FillWithOptions(makeCheckerboard(40, 40), 20, 10, Center, Box, LinearLight(true)), 0xbc},
+		{"Thumbnail linear", ThumbnailWithOptions(src, 16, 32, Box, LinearLight(true)), 0xbc},
+		{"Resize16 linear", Clone(Resize16WithOptions(src, 32, 32, Box, LinearLight(true))), 0xbc},
+		{"Fit16 linear", Clone(Fit16WithOptions(src, 32, 40, Box, LinearLight(true))), 0xbc},
+		{"Fill16 linear", Clone(Fill16WithOptions(src, 32, 16, Center, Box, LinearLight(true))), 0xbc},
+	}
+	for _, tc := range testCases {
+		for i := 0; i < len(tc.img.Pix); i += 4 {
+			want := []uint8{tc.want, tc.want, tc.want, 0xff}
+			if !compareBytes(tc.img.Pix[i:i+4], want, 1) {
+				t.Fatalf("%s: got %v want %v", tc.name, tc.img.Pix[i:i+4], want)
+			}
+		}
+	}
+
+	// Colors are preserved when converting to linear light and back.
+	gradient := image.NewNRGBA(image.Rect(0, 0, 256, 1))
+	for i := 0; i < 256; i++ {
+		gradient.SetNRGBA(i, 0, color.NRGBA{uint8(i), uint8(255 - i), uint8(i / 2), uint8(i)})
+	}
+	if got := fromLinear(toLinear16(gradient)); !compareNRGBA(got, gradient, 0) {
+		t.Fatalf("got %v want %v", got.Pix, gradient.Pix)
+	}
+	got := ResizeWithOptions(testdataBranchesPNG, 300, 0, Lanczos, LinearLight(true))
+	want := Resize(testdataBranchesPNG, 300, 0, Lanczos)
+	if got.Rect != want.Rect || compareNRGBA(got, want, 0) {
+		t.Fatal("linear light resizing gave the same result as sRGB resizing")
+	}
+
+	// The nearest-neighbor filter doesn't mix colors.
+	got = ResizeWithOptions(src, 32, 32, NearestNeighbor, LinearLight(true))
+	want = Resize(src, 32, 32, NearestNeighbor)
+	if !compareNRGBA(got, want, 0) {
+		t.Fatal("LinearLight changed the result of the NearestNeighbor filter")
+	}
+}
+
+// makeDirtyNRGBA returns an image filled with a color that mustn't be left in the results.
+func makeDirtyNRGBA(r image.Rectangle) *image.NRGBA {
+	img := image.NewNRGBA(r)
+	for i := range img.Pix {
+		img.Pix[i] = 0x5a
+	}
+	return img
+}
+
+func TestResizeInto(t *testing.T) {
+	t.Parallel()
+
+	// The transparent pixels of the source image must be cleared in the destination.
+	src := Clone(testdataFlowersSmallPNG)
+	draw.Draw(src, image.Rect(0, 0, 100, 50), image.Transparent, image.Point{}, draw.Src)
+
+	for _, tc := range []struct {
+		w, h int
+		f    ResampleFilter
+		opts []ResizeOption
+	}{
+		{100, 50, Lanczos, nil},
+		{300, 200, Linear, nil},
+		{240, 60, Box, nil},
+		{50, 160, CatmullRom, nil},
+		{240, 160, Lanczos, nil},
+		{60, 40, NearestNeighbor, nil},
+		{500, 300, NearestNeighbor, nil},
+		{100, 80, Lanczos, []ResizeOption{LinearLight(true)}},
+	} {
+		want := ResizeWithOptions(src, tc.w, tc.h, tc.f, tc.opts...)
+		dst := makeDirtyNRGBA(image.Rect(0, 0, tc.w, tc.h))
+		if got := ResizeInto(dst, src, tc.f, tc.opts...); got != dst {
+			t.Fatal("ResizeInto didn't return dst")
+		}
+		if !compareNRGBA(dst, want, 0) {
+			t.Fatalf("ResizeInto(%d, %d) does not match Resize", tc.w, tc.h)
+		}
+	}
+
+	// Only the sub-image is modified.
+	buf := makeDirtyNRGBA(image.Rect(0, 0, 120, 100))
+	dst := buf.SubImage(image.Rect(10, 20, 110, 70)).(*image.NRGBA)
+	ResizeInto(dst, src, Lanczos)
+	if !compareNRGBA(Clone(dst), Resize(src, 100, 50, Lanczos), 0) {
+		t.Fatal("ResizeInto of a sub-image does not match Resize")
+	}
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 120; x++ {
+			if image.Pt(x, y).In(dst.Rect) {
+				continue
+			}
+			if c := buf.NRGBAAt(x, y); c != (color.NRGBA{0x5a, 0x5a, 0x5a, 0x5a}) {
+				t.Fatalf("pixel (%d, %d) outside of dst changed to %v", x, y, c)
+			}
+		}
+	}
+
+	empty := &image.NRGBA{}
+	if got := ResizeInto(empty, src, Lanczos); got != empty {
+		t.Fatal("ResizeInto didn't return dst")
+	}
+}
+
+func TestFitInto(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range [][2]int{{100, 100}, {50, 200}, {240, 160}, {1000, 1000}, {300, 1}} {
+		want := Fit(testdataFlowersSmallPNG, size[0], size[1], Lanczos)
+		buf := makeDirtyNRGBA(image.Rect(-5, -5, size[0]-5, size[1]-5))
+		got := FitInto(buf, testdataFlowersSmallPNG, Lanczos)
+		if got.Rect.Min != buf.Rect.Min || got.Rect.Size() != want.Rect.Size() {
+			t.Fatalf("FitInto(%d, %d): got bounds %v want size %v", size[0], size[1], got.Rect, want.Rect.Size())
+		}
+		if !compareNRGBA(Clone(got), want, 0) {
+			t.Fatalf("FitInto(%d, %d) does not match Fit", size[0], size[1])
+		}
+	}
+	if got := FitInto(&image.NRGBA{}, testdataFlowersSmallPNG, Lanczos); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
+
+func BenchmarkResizeInto(b *testing.B) {
+	dst := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	b.Run("Resize", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Resize(testdataBranchesJPG, 100, 100, Lanczos)
+		}
+	})
+	b.Run("ResizeInto", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ResizeInto(dst, testdataBranchesJPG, Lanczos)
+		}
+	})
+}
+
+func TestFillFocal(t *testing.T) {
+	t.Parallel()
+
+	// A red square on the right of a white image.
+	img := New(200, 100, color.White)
+	draw.Draw(img, image.Rect(150, 40, 170, 60), image.NewUniform(color.NRGBA{0xff, 0, 0, 0xff}), image.Point{}, draw.Src)
+	for _, size := range [][2]int{{100, 100}, {50, 50}, {20, 40}} {
+		got := FillFocal(img, size[0], size[1], 0.8, 0.5, NearestNeighbor)
+		if c := got.NRGBAAt(size[0]/2, size[1]/2); c != (color.NRGBA{0xff, 0, 0, 0xff}) {
+			t.Fatalf("Fill(%d, %d): got center color %v want red", size[0], size[1], c)
+		}
+		if c := Fill(img, size[0], size[1], Center, NearestNeighbor).NRGBAAt(size[0]/2, size[1]/2); c.G != 0xff {
+			t.Fatalf("Fill(%d, %d): got center color %v with Center anchor want white", size[0], size[1], c)
+		}
+		got16 := FillFocal16(img, size[0], size[1], 0.8, 0.5, NearestNeighbor)
+		if !compareNRGBA(Clone(got16), got, 0) {
+			t.Fatalf("FillFocal16(%d, %d) does not match FillFocal", size[0], size[1])
+		}
+	}
+
+	got := ThumbnailWithOptions(img, 100, 100, Lanczos, ThumbnailFocalPoint(0.8, 0.5))
+	if !compareNRGBA(got, FillFocal(img, 100, 100, 0.8, 0.5, Lanczos), 0) {
+		t.Fatal("Thumbnail with ThumbnailFocalPoint does not match FillFocal")
+	}
+	if !compareNRGBA(Thumbnail(img, 100, 100, Lanczos), Fill(img, 100, 100, Center, Lanczos), 0) {
+		t.Fatal("Thumbnail does not crop around the center by default")
+	}
+}
+
+func TestPad(t *testing.T) {
+	t.Parallel()
+
+	img := testdataBranchesPNG // 600x400
+	bg := color.NRGBA{0x10, 0x20, 0x30, 0xff}
+	testCases := []struct {
+		name          string
+		width, height int
+		anchor        Anchor
+		want          image.Rectangle
+	}{
+		{"Letterbox", 300, 300, Center, image.Rect(0, 50, 300, 250)},
+		{"LetterboxTop", 300, 300, Top, image.Rect(0, 0, 300, 200)},
+		{"Pillarbox", 1200, 400, Center, image.Rect(300, 0, 900, 400)},
+		{"PillarboxRight", 1200, 630, Right, image.Rect(255, 0, 1200, 630)},
+		{"Same", 600, 400, Center, image.Rect(0, 0, 600, 400)},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := Pad(img, tc.width, tc.height, tc.anchor, bg, Linear)
+			if got.Rect != image.Rect(0, 0, tc.width, tc.height) {
+				t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, tc.width, tc.height))
+			}
+			want := Resize(img, tc.want.Dx(), tc.want.Dy(), Linear)
+			if !compareNRGBA(Crop(got, tc.want), want, 0) {
+				t.Fatal("padded image does not match the resized image")
+			}
+			for y := 0; y < tc.height; y++ {
+				for x := 0; x < tc.width; x++ {
+					if !image.Pt(x, y).In(tc.want) && got.NRGBAAt(x, y) != bg {
+						t.Fatalf("got color %v at (%d, %d) want background", got.NRGBAAt(x, y), x, y)
+					}
+				}
+			}
+		})
+	}
+
+	for _, size := range [][2]int{{0, 10}, {10, -1}} {
+		if got := Pad(img, size[0], size[1], Center, bg, Linear); !got.Rect.Empty() {
+			t.Fatalf("got bounds %v want empty", got.Rect)
+		}
+	}
+	if got := Pad(&image.NRGBA{}, 10, 10, Center, bg, Linear); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
+
+func TestPadTransparent(t *testing.T) {
+	t.Parallel()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+	got := Pad(img, 30, 30, Center, color.White, Box)
+	if !compareNRGBA(got, New(30, 30, color.White), 0) {
+		t.Fatal("transparent image hides the background")
+	}
+	got = Pad(img, 30, 30, Center, color.White, Box, PadBlur(2))
+	if !compareNRGBA(got, New(30, 30, color.White), 0) {
+		t.Fatal("transparent image hides the background with PadBlur")
+	}
+}
+
+func TestPadBlur(t *testing.T) {
+	t.Parallel()
+
+	img := testdataFlowersSmallPNG
+	want := Pad(img, 200, 100, Center, color.Black, Lanczos)
+	got := Pad(img, 200, 100, Center, color.Black, Lanczos, PadBlur(5))
+	if got.Rect != want.Rect {
+		t.Fatalf("got bounds %v want %v", got.Rect, want.Rect)
+	}
+	inner := image.Rect(25, 0, 175, 100)
+	if !compareNRGBA(Crop(got, inner), Crop(want, inner), 0) {
+		t.Fatal("PadBlur changed the image")
+	}
+	expected := Overlay(New(200, 100, color.Black), Blur(Fill(img, 200, 100, Center, Lanczos), 5), image.Pt(0, 0), 1.0)
+	outer := image.Rect(0, 0, 25, 100)
+	if !compareNRGBA(Crop(got, outer), Crop(expected, outer), 0) {
+		t.Fatal("background does not match the blurred image")
+	}
+	if compareNRGBA(Crop(got, outer), Crop(want, outer), 0) {
+		t.Fatal("background is flat")
+	}
+	if !compareNRGBA(Pad(img, 200, 100, Center, color.Black, Lanczos, PadBlur(0)), want, 0) {
+		t.Fatal("PadBlur(0) changed the background")
+	}
+}
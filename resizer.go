@@ -0,0 +1,159 @@
+package imaging
+
+import (
+	"container/list"
+	"image"
+	"image/color"
+	"sync"
+)
+
+// defaultResizerCapacity is the default number of cached weight tables of a Resizer.
+const defaultResizerCapacity = 16
+
+// Resizer resizes images using a fixed resampling filter and keeps the resampling weights
+// computed for each pair of source and destination sizes, so they're not recomputed when
+// many images of the same size are resized to the same dimensions. The least recently used
+// weights are dropped when the cache is full.
+//
+// A Resizer is safe for concurrent use by multiple goroutines.
+//
+// Example:
+//
+//	resizer := imaging.NewResizer(imaging.Lanczos, 0)
+//	for _, img := range images {
+//		thumbs = append(thumbs, resizer.Resize(img, 200, 0))
+//	}
+type Resizer struct {
+	filter  ResampleFilter
+	weights *weightCache
+}
+
+// NewResizer returns a Resizer that uses the specified resampling filter and caches up to
+// capacity weight tables, one per pair of source and destination sizes of an axis.
+// If capacity is less than 1, a default capacity of 16 is used.
+func NewResizer(filter ResampleFilter, capacity int) *Resizer {
+	if capacity < 1 {
+		capacity = defaultResizerCapacity
+	}
+	return &Resizer{
+		filter:  filter,
+		weights: newWeightCache(capacity),
+	}
+}
+
+// options returns the options with the weight cache of the resizer appended.
+func (r *Resizer) options(opts []ResizeOption) []ResizeOption {
+	out := make([]ResizeOption, 0, len(opts)+1)
+	out = append(out, opts...)
+	return append(out, func(c *resizeConfig) {
+		c.weights = r.weights
+	})
+}
+
+// Resize is like the Resize function but uses the filter and the weight cache of the resizer.
+func (r *Resizer) Resize(img image.Image, width, height int, opts ...ResizeOption) *image.NRGBA {
+	return ResizeWithOptions(img, width, height, r.filter, r.options(opts)...)
+}
+
+// ResizeInto is like the ResizeInto function but uses the filter and the weight cache of the resizer.
+func (r *Resizer) ResizeInto(dst *image.NRGBA, img image.Image, opts ...ResizeOption) *image.NRGBA {
+	return ResizeInto(dst, img, r.filter, r.options(opts)...)
+}
+
+// Fit is like the Fit function but uses the filter and the weight cache of the resizer.
+func (r *Resizer) Fit(img image.Image, width, height int, opts ...ResizeOption) *image.NRGBA {
+	return FitWithOptions(img, width, height, r.filter, r.options(opts)...)
+}
+
+// FitInto is like the FitInto function but uses the filter and the weight cache of the resizer.
+func (r *Resizer) FitInto(dst *image.NRGBA, img image.Image, opts ...ResizeOption) *image.NRGBA {
+	return FitInto(dst, img, r.filter, r.options(opts)...)
+}
+
+// Fill is like the Fill function but uses the filter and the weight cache of the resizer.
+func (r *Resizer) Fill(img image.Image, width, height int, anchor Anchor, opts ...ResizeOption) *image.NRGBA {
+	return FillWithOptions(img, width, height, anchor, r.filter, r.options(opts)...)
+}
+
+// FillFocal is like the FillFocal function but uses the filter and the weight cache of the resizer.
+func (r *Resizer) FillFocal(img image.Image, width, height int, x, y float64, opts ...ResizeOption) *image.NRGBA {
+	return FillFocal(img, width, height, x, y, r.filter, r.options(opts)...)
+}
+
+// Thumbnail is like the Thumbnail function but uses the filter and the weight cache of the resizer.
+func (r *Resizer) Thumbnail(img image.Image, width, height int, opts ...ResizeOption) *image.NRGBA {
+	return ThumbnailWithOptions(img, width, height, r.filter, r.options(opts)...)
+}
+
+// Pad is like the Pad function but uses the filter and the weight cache of the resizer.
+func (r *Resizer) Pad(img image.Image, width, height int, anchor Anchor, bg color.Color, opts ...ResizeOption) *image.NRGBA {
+	return Pad(img, width, height, anchor, bg, r.filter, r.options(opts)...)
+}
+
+// Resize16 is like the Resize16 function but uses the filter and the weight cache of the resizer.
+func (r *Resizer) Resize16(img image.Image, width, height int, opts ...ResizeOption) *image.NRGBA64 {
+	return Resize16WithOptions(img, width, height, r.filter, r.options(opts)...)
+}
+
+// weightKey identifies the resampling weights of one axis.
+type weightKey struct {
+	dstSize int
+	srcSize int
+}
+
+// weightEntry is a cached weight table.
+type weightEntry struct {
+	key     weightKey
+	weights [][]indexWeight
+}
+
+// weightCache is a concurrency-safe LRU cache of resampling weights.
+// The cached weights are shared by the callers and must not be modified.
+type weightCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[weightKey]*list.Element
+	lru      *list.List
+}
+
+// newWeightCache returns an empty weight cache holding up to capacity weight tables.
+func newWeightCache(capacity int) *weightCache {
+	return &weightCache{
+		capacity: capacity,
+		entries:  make(map[weightKey]*list.Element, capacity),
+		lru:      list.New(),
+	}
+}
+
+// get returns the resampling weights of the sizes, computing them on a cache miss.
+// The filter must be the same for all calls.
+func (c *weightCache) get(dstSize, srcSize int, filter ResampleFilter) [][]indexWeight {
+	key := weightKey{dstSize: dstSize, srcSize: srcSize}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		weights := e.Value.(*weightEntry).weights
+		c.mu.Unlock()
+		return weights
+	}
+	c.mu.Unlock()
+
+	// Compute the weights without holding the lock. Concurrent misses of the same
+	// key may compute them more than once, the first stored result is kept.
+	weights := precomputeWeights(dstSize, srcSize, filter)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*weightEntry).weights
+	}
+	c.entries[key] = c.lru.PushFront(&weightEntry{key: key, weights: weights})
+	if c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*weightEntry).key)
+	}
+	return weights
+}
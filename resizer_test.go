@@ -0,0 +1,138 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"sync"
+	"testing"
+)
+
+func TestResizer(t *testing.T) {
+	t.Parallel()
+
+	r := NewResizer(Lanczos, 0)
+	if r.weights.capacity != defaultResizerCapacity {
+		t.Fatalf("got capacity %d want %d", r.weights.capacity, defaultResizerCapacity)
+	}
+
+	img := testdataFlowersSmallPNG
+	for i := 0; i < 2; i++ {
+		if !compareNRGBA(r.Resize(img, 50, 40), Resize(img, 50, 40, Lanczos), 0) {
+			t.Fatal("Resize does not match")
+		}
+		if !compareNRGBA(r.Resize(img, 100, 0, LinearLight(true)), ResizeWithOptions(img, 100, 0, Lanczos, LinearLight(true)), 0) {
+			t.Fatal("Resize with LinearLight does not match")
+		}
+		dst := image.NewNRGBA(image.Rect(0, 0, 30, 70))
+		if !compareNRGBA(r.ResizeInto(dst, img), Resize(img, 30, 70, Lanczos), 0) {
+			t.Fatal("ResizeInto does not match")
+		}
+		if !compareNRGBA(r.Fit(img, 60, 60), Fit(img, 60, 60, Lanczos), 0) {
+			t.Fatal("Fit does not match")
+		}
+		dst = image.NewNRGBA(image.Rect(0, 0, 60, 60))
+		if !compareNRGBA(Clone(r.FitInto(dst, img)), Fit(img, 60, 60, Lanczos), 0) {
+			t.Fatal("FitInto does not match")
+		}
+		if !compareNRGBA(r.Fill(img, 40, 60, TopLeft), Fill(img, 40, 60, TopLeft, Lanczos), 0) {
+			t.Fatal("Fill does not match")
+		}
+		if !compareNRGBA(r.FillFocal(img, 40, 60, 0.2, 0.7), FillFocal(img, 40, 60, 0.2, 0.7, Lanczos), 0) {
+			t.Fatal("FillFocal does not match")
+		}
+		if !compareNRGBA(r.Thumbnail(img, 40, 40), Thumbnail(img, 40, 40, Lanczos), 0) {
+			t.Fatal("Thumbnail does not match")
+		}
+		if !compareNRGBA(r.Pad(img, 80, 40, Left, color.White), Pad(img, 80, 40, Left, color.White, Lanczos), 0) {
+			t.Fatal("Pad does not match")
+		}
+		if !compareNRGBA64(r.Resize16(img, 50, 40), Resize16(img, 50, 40, Lanczos), 0) {
+			t.Fatal("Resize16 does not match")
+		}
+	}
+	if n := r.weights.lru.Len(); n == 0 || n > defaultResizerCapacity {
+		t.Fatalf("got %d cached weight tables", n)
+	}
+}
+
+func TestResizerConcurrent(t *testing.T) {
+	t.Parallel()
+
+	r := NewResizer(CatmullRom, 2)
+	sizes := [][2]int{{30, 20}, {60, 40}, {90, 10}}
+	want := make([]*image.NRGBA, len(sizes))
+	for i, size := range sizes {
+		want[i] = Resize(testdataBranchesPNG, size[0], size[1], CatmullRom)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan int, 16*len(sizes))
+	for g := 0; g < 16; g++ {
+		for i, size := range sizes {
+			i, size := i, size
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if !compareNRGBA(r.Resize(testdataBranchesPNG, size[0], size[1]), want[i], 0) {
+					errs <- i
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for i := range errs {
+		t.Fatalf("concurrent Resize to %v does not match", sizes[i])
+	}
+	if n := r.weights.lru.Len(); n != 2 {
+		t.Fatalf("got %d cached weight tables want 2", n)
+	}
+}
+
+func TestWeightCache(t *testing.T) {
+	t.Parallel()
+
+	c := newWeightCache(2)
+	a := c.get(10, 100, Linear)
+	if want := precomputeWeights(10, 100, Linear); len(a) != len(want) || a[3][0] != want[3][0] {
+		t.Fatal("cached weights do not match precomputeWeights")
+	}
+	if b := c.get(10, 100, Linear); &b[0] != &a[0] {
+		t.Fatal("weights not reused")
+	}
+
+	c.get(20, 100, Linear)
+	c.get(10, 100, Linear) // Mark as recently used.
+	c.get(30, 100, Linear) // Evicts {20, 100}.
+	if c.lru.Len() != 2 {
+		t.Fatalf("got %d entries want 2", c.lru.Len())
+	}
+	for key, want := range map[weightKey]bool{
+		{dstSize: 10, srcSize: 100}: true,
+		{dstSize: 20, srcSize: 100}: false,
+		{dstSize: 30, srcSize: 100}: true,
+	} {
+		if _, ok := c.entries[key]; ok != want {
+			t.Fatalf("%+v: got cached %v want %v", key, ok, want)
+		}
+	}
+	if b := c.get(10, 100, Linear); &b[0] != &a[0] {
+		t.Fatal("recently used weights evicted")
+	}
+}
+
+func BenchmarkResizer(b *testing.B) {
+	b.Run("Resize", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Resize(testdataBranchesJPG, 100, 100, Lanczos)
+		}
+	})
+	b.Run("Resizer", func(b *testing.B) {
+		r := NewResizer(Lanczos, 0)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.Resize(testdataBranchesJPG, 100, 100)
+		}
+	})
+}
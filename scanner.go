@@ -51,8 +51,18 @@ func (s *scanner) scan(x1, y1, x2, y2 int, dst []uint8) {
 		s.scanGray16(img, x1, y1, x2, y2, dst)
 	case *image.YCbCr:
 		s.scanYCbCr(img, x1, y1, x2, y2, dst)
+	case *image.NYCbCrA:
+		s.scanNYCbCrA(img, x1, y1, x2, y2, dst)
 	case *image.Paletted:
 		s.scanPaletted(img, x1, y1, x2, y2, dst)
+	case *image.Alpha:
+		s.scanAlpha(img, x1, y1, x2, y2, dst)
+	case *image.Alpha16:
+		s.scanAlpha16(img, x1, y1, x2, y2, dst)
+	case *image.CMYK:
+		s.scanCMYK(img, x1, y1, x2, y2, dst)
+	case image.RGBA64Image:
+		s.scanRGBA64Image(img, x1, y1, x2, y2, dst)
 	default:
 		s.scanDefault(x1, y1, x2, y2, dst)
 	}
@@ -278,6 +288,28 @@ func (s *scanner) scanYCbCr(img *image.YCbCr, x1, y1, x2, y2 int, dst []uint8) {
 	}
 }
 
+// scanNYCbCrA scans the given rectangular region of the NYCbCrA image into dst.
+// The color values are converted like in scanYCbCr and are not premultiplied by alpha.
+func (s *scanner) scanNYCbCrA(img *image.NYCbCrA, x1, y1, x2, y2 int, dst []uint8) {
+	s.scanYCbCr(&img.YCbCr, x1, y1, x2, y2, dst)
+	j := 0
+	for y := y1; y < y2; y++ {
+		i := y*img.AStride + x1
+		for x := x1; x < x2; x++ {
+			d := dst[j : j+4 : j+4]
+			a := img.A[i]
+			if a == 0 {
+				d[0] = 0
+				d[1] = 0
+				d[2] = 0
+			}
+			d[3] = a
+			j += 4
+			i++
+		}
+	}
+}
+
 // scanPaletted scans the given rectangular region of the Paletted image into dst.
 func (s *scanner) scanPaletted(img *image.Paletted, x1, y1, x2, y2 int, dst []uint8) {
 	j := 0
@@ -296,6 +328,92 @@ func (s *scanner) scanPaletted(img *image.Paletted, x1, y1, x2, y2 int, dst []ui
 	}
 }
 
+// scanAlpha scans the given rectangular region of the Alpha image into dst.
+func (s *scanner) scanAlpha(img *image.Alpha, x1, y1, x2, y2 int, dst []uint8) {
+	j := 0
+	for y := y1; y < y2; y++ {
+		i := y*img.Stride + x1
+		for x := x1; x < x2; x++ {
+			d := dst[j : j+4 : j+4]
+			a := img.Pix[i]
+			if a == 0 {
+				d[0] = 0
+				d[1] = 0
+				d[2] = 0
+			} else {
+				d[0] = 0xff
+				d[1] = 0xff
+				d[2] = 0xff
+			}
+			d[3] = a
+			j += 4
+			i++
+		}
+	}
+}
+
+// scanAlpha16 scans the given rectangular region of the Alpha16 image into dst.
+func (s *scanner) scanAlpha16(img *image.Alpha16, x1, y1, x2, y2 int, dst []uint8) {
+	j := 0
+	for y := y1; y < y2; y++ {
+		i := y*img.Stride + x1*2
+		for x := x1; x < x2; x++ {
+			d := dst[j : j+4 : j+4]
+			p := img.Pix[i : i+2 : i+2]
+			if p[0] == 0 && p[1] == 0 {
+				d[0] = 0
+				d[1] = 0
+				d[2] = 0
+			} else {
+				d[0] = 0xff
+				d[1] = 0xff
+				d[2] = 0xff
+			}
+			d[3] = p[0]
+			j += 4
+			i += 2
+		}
+	}
+}
+
+// scanCMYK scans the given rectangular region of the CMYK image into dst.
+// The conversion matches color.CMYK.RGBA.
+func (s *scanner) scanCMYK(img *image.CMYK, x1, y1, x2, y2 int, dst []uint8) {
+	j := 0
+	for y := y1; y < y2; y++ {
+		i := y*img.Stride + x1*4
+		for x := x1; x < x2; x++ {
+			p := img.Pix[i : i+4 : i+4]
+			w := 0xffff - uint32(p[3])*0x101
+			d := dst[j : j+4 : j+4]
+			d[0] = uint8((0xffff - uint32(p[0])*0x101) * w / 0xffff >> 8)
+			d[1] = uint8((0xffff - uint32(p[1])*0x101) * w / 0xffff >> 8)
+			d[2] = uint8((0xffff - uint32(p[2])*0x101) * w / 0xffff >> 8)
+			d[3] = 0xff
+			j += 4
+			i += 4
+		}
+	}
+}
+
+// scanRGBA64Image scans the given rectangular region of an image implementing
+// image.RGBA64Image into dst. Unlike At, RGBA64At doesn't allocate a color.Color value.
+func (s *scanner) scanRGBA64Image(img image.RGBA64Image, x1, y1, x2, y2 int, dst []uint8) {
+	j := 0
+	b := img.Bounds()
+	x1 += b.Min.X
+	x2 += b.Min.X
+	y1 += b.Min.Y
+	y2 += b.Min.Y
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			c := img.RGBA64At(x, y)
+			storeRGBA64(dst[j:j+4:j+4], uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A))
+			j += 4
+		}
+	}
+}
+
 // scanDefault scans the given rectangular region of the image using the default case.
 func (s *scanner) scanDefault(x1, y1, x2, y2 int, dst []uint8) {
 	j := 0
@@ -307,25 +425,132 @@ func (s *scanner) scanDefault(x1, y1, x2, y2 int, dst []uint8) {
 	for y := y1; y < y2; y++ {
 		for x := x1; x < x2; x++ {
 			r16, g16, b16, a16 := s.image.At(x, y).RGBA()
-			d := dst[j : j+4 : j+4]
-			switch a16 {
-			case 0xffff:
-				d[0] = uint8(r16 >> 8)
-				d[1] = uint8(g16 >> 8)
-				d[2] = uint8(b16 >> 8)
-				d[3] = 0xff
-			case 0:
-				d[0] = 0
-				d[1] = 0
-				d[2] = 0
-				d[3] = 0
-			default:
-				d[0] = uint8(((r16 * 0xffff) / a16) >> 8)
-				d[1] = uint8(((g16 * 0xffff) / a16) >> 8)
-				d[2] = uint8(((b16 * 0xffff) / a16) >> 8)
-				d[3] = uint8(a16 >> 8)
-			}
+			storeRGBA64(dst[j:j+4:j+4], r16, g16, b16, a16)
 			j += 4
 		}
 	}
 }
+
+// storeRGBA64 stores the alpha-premultiplied 16-bit color as a non-premultiplied 8-bit color in d.
+func storeRGBA64(d []uint8, r16, g16, b16, a16 uint32) {
+	switch a16 {
+	case 0xffff:
+		d[0] = uint8(r16 >> 8)
+		d[1] = uint8(g16 >> 8)
+		d[2] = uint8(b16 >> 8)
+		d[3] = 0xff
+	case 0:
+		d[0] = 0
+		d[1] = 0
+		d[2] = 0
+		d[3] = 0
+	default:
+		d[0] = uint8(((r16 * 0xffff) / a16) >> 8)
+		d[1] = uint8(((g16 * 0xffff) / a16) >> 8)
+		d[2] = uint8(((b16 * 0xffff) / a16) >> 8)
+		d[3] = uint8(a16 >> 8)
+	}
+}
+
+// scan16 scans the given rectangular region of the image into dst with 16 bits per channel.
+// The pixels are stored in the layout of image.NRGBA64.Pix (8 bytes per pixel, big-endian),
+// so dst must have room for 8 bytes per pixel.
+func (s *scanner) scan16(x1, y1, x2, y2 int, dst []uint8) {
+	switch img := s.image.(type) {
+	case *image.NRGBA64:
+		s.scan16NRGBA64(img, x1, y1, x2, y2, dst)
+	case *image.RGBA64:
+		s.scan16RGBA64(img, x1, y1, x2, y2, dst)
+	case *image.Gray16:
+		s.scan16Gray16(img, x1, y1, x2, y2, dst)
+	default:
+		if bitDepth(img.ColorModel()) == 16 {
+			s.scan16Default(x1, y1, x2, y2, dst)
+			return
+		}
+		// 8-bit images are scanned into the first half of dst and expanded in place,
+		// starting from the end so that no value is overwritten before it's read.
+		n := (x2 - x1) * (y2 - y1) * 4
+		s.scan(x1, y1, x2, y2, dst[:n])
+		for i := n - 1; i >= 0; i-- {
+			dst[i*2] = dst[i]
+			dst[i*2+1] = dst[i]
+		}
+	}
+}
+
+// scan16NRGBA64 scans the given rectangular region of the NRGBA64 image into dst with 16 bits per channel.
+func (s *scanner) scan16NRGBA64(img *image.NRGBA64, x1, y1, x2, y2 int, dst []uint8) {
+	size := (x2 - x1) * 8
+	j := 0
+	for y := y1; y < y2; y++ {
+		i := y*img.Stride + x1*8
+		copy(dst[j:j+size], img.Pix[i:i+size])
+		j += size
+	}
+}
+
+// scan16RGBA64 scans the given rectangular region of the RGBA64 image into dst with 16 bits per channel.
+func (s *scanner) scan16RGBA64(img *image.RGBA64, x1, y1, x2, y2 int, dst []uint8) {
+	j := 0
+	for y := y1; y < y2; y++ {
+		i := y*img.Stride + x1*8
+		for x := x1; x < x2; x++ {
+			r, g, b, a := pixel16(img.Pix[i : i+8 : i+8])
+			storeRGBA64To16(dst[j:j+8:j+8], uint32(r), uint32(g), uint32(b), uint32(a))
+			j += 8
+			i += 8
+		}
+	}
+}
+
+// scan16Gray16 scans the given rectangular region of the Gray16 image into dst with 16 bits per channel.
+func (s *scanner) scan16Gray16(img *image.Gray16, x1, y1, x2, y2 int, dst []uint8) {
+	j := 0
+	for y := y1; y < y2; y++ {
+		i := y*img.Stride + x1*2
+		for x := x1; x < x2; x++ {
+			c := uint16(img.Pix[i])<<8 | uint16(img.Pix[i+1])
+			setPixel16(dst[j:j+8:j+8], c, c, c, 0xffff)
+			j += 8
+			i += 2
+		}
+	}
+}
+
+// scan16Default scans the given rectangular region of the image into dst with 16 bits per channel
+// using the default case.
+func (s *scanner) scan16Default(x1, y1, x2, y2 int, dst []uint8) {
+	j := 0
+	b := s.image.Bounds()
+	x1 += b.Min.X
+	x2 += b.Min.X
+	y1 += b.Min.Y
+	y2 += b.Min.Y
+	img, ok := s.image.(image.RGBA64Image)
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			var r16, g16, b16, a16 uint32
+			if ok {
+				c := img.RGBA64At(x, y)
+				r16, g16, b16, a16 = uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+			} else {
+				r16, g16, b16, a16 = s.image.At(x, y).RGBA()
+			}
+			storeRGBA64To16(dst[j:j+8:j+8], r16, g16, b16, a16)
+			j += 8
+		}
+	}
+}
+
+// storeRGBA64To16 stores the alpha-premultiplied 16-bit color as a non-premultiplied 16-bit color in d.
+func storeRGBA64To16(d []uint8, r16, g16, b16, a16 uint32) {
+	switch a16 {
+	case 0xffff:
+		setPixel16(d, uint16(r16), uint16(g16), uint16(b16), 0xffff)
+	case 0:
+		setPixel16(d, 0, 0, 0, 0)
+	default:
+		setPixel16(d, uint16(r16*0xffff/a16), uint16(g16*0xffff/a16), uint16(b16*0xffff/a16), uint16(a16))
+	}
+}
@@ -66,6 +66,18 @@ func TestScanner(t *testing.T) {
 			name: "YCbCr-411",
 			img:  makeYCbCrImage(rect, colors, image.YCbCrSubsampleRatio411),
 		},
+		{
+			name: "NYCbCrA-444",
+			img:  makeNYCbCrAImage(rect, colors, image.YCbCrSubsampleRatio444),
+		},
+		{
+			name: "NYCbCrA-420",
+			img:  makeNYCbCrAImage(rect, colors, image.YCbCrSubsampleRatio420),
+		},
+		{
+			name: "NYCbCrA-410",
+			img:  makeNYCbCrAImage(rect, colors, image.YCbCrSubsampleRatio410),
+		},
 		{
 			name: "Paletted",
 			img:  makePalettedImage(rect, colors),
@@ -78,10 +90,18 @@ func TestScanner(t *testing.T) {
 			name: "Alpha16",
 			img:  makeAlpha16Image(rect, colors),
 		},
+		{
+			name: "CMYK",
+			img:  makeCMYKImage(rect, colors),
+		},
 		{
 			name: "Generic",
 			img:  makeGenericImage(rect, colors),
 		},
+		{
+			name: "Default",
+			img:  makeDefaultImage(rect, colors),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -111,6 +131,55 @@ func TestScanner(t *testing.T) {
 	}
 }
 
+func TestScanner16(t *testing.T) {
+	t.Parallel()
+
+	rect := image.Rect(-1, -1, 15, 15)
+	colors := palette.Plan9
+	testCases := []struct {
+		name  string
+		img   image.Image
+		delta int
+	}{
+		{"NRGBA64", makeNRGBA64Image(rect, colors), 0},
+		{"RGBA64", makeRGBA64Image(rect, colors), 0},
+		{"Gray16", makeGray16Image(rect, colors), 0},
+		{"Alpha16", makeAlpha16Image(rect, colors), 0},
+		{"RGBA64Image", &struct{ *image.RGBA64 }{makeRGBA64Image(rect, colors)}, 0},
+		{"Default", &defaultImage{makeRGBA64Image(rect, colors)}, 0},
+		// 8-bit images are scanned with 8-bit precision.
+		{"NRGBA", makeNRGBAImage(rect, colors), 0x101},
+		{"RGBA", makeRGBAImage(rect, colors), 0x101},
+		{"YCbCr", makeYCbCrImage(rect, colors, image.YCbCrSubsampleRatio420), 0x101},
+		{"Paletted", makePalettedImage(rect, colors), 0x101},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := tc.img.Bounds()
+			s := newScanner(tc.img)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				buf := make([]byte, r.Dx()*8)
+				s.scan16(0, y-r.Min.Y, r.Dx(), y+1-r.Min.Y, buf)
+				wantBuf := readRow16(tc.img, y)
+				if !compareBytes16(buf, wantBuf, tc.delta) {
+					t.Fatalf("scan16 horizontal line (y=%d): got %v want %v", y, buf, wantBuf)
+				}
+			}
+			for x := r.Min.X; x < r.Max.X; x++ {
+				buf := make([]byte, r.Dy()*8)
+				s.scan16(x-r.Min.X, 0, x+1-r.Min.X, r.Dy(), buf)
+				wantBuf := readColumn16(tc.img, x)
+				if !compareBytes16(buf, wantBuf, tc.delta) {
+					t.Fatalf("scan16 vertical line (x=%d): got %v want %v", x, buf, wantBuf)
+				}
+			}
+		})
+	}
+}
+
 func makeYCbCrImage(rect image.Rectangle, colors []color.Color, sr image.YCbCrSubsampleRatio) *image.YCbCr {
 	img := image.NewYCbCr(rect, sr)
 	j := 0
@@ -126,6 +195,22 @@ func makeYCbCrImage(rect image.Rectangle, colors []color.Color, sr image.YCbCrSu
 	return img
 }
 
+func makeNYCbCrAImage(rect image.Rectangle, colors []color.Color, sr image.YCbCrSubsampleRatio) *image.NYCbCrA {
+	img := image.NewNYCbCrA(rect, sr)
+	j := 0
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			iy := img.YOffset(x, y)
+			ic := img.COffset(x, y)
+			c := color.NRGBAModel.Convert(colors[j]).(color.NRGBA)
+			img.Y[iy], img.Cb[ic], img.Cr[ic] = color.RGBToYCbCr(c.R, c.G, c.B)
+			img.A[img.AOffset(x, y)] = uint8(j % 256)
+			j++
+		}
+	}
+	return img
+}
+
 func makeNRGBAImage(rect image.Rectangle, colors []color.Color) *image.NRGBA {
 	img := image.NewNRGBA(rect)
 	fillDrawImage(img, colors)
@@ -180,6 +265,12 @@ func makeAlpha16Image(rect image.Rectangle, colors []color.Color) *image.Alpha16
 	return img
 }
 
+func makeCMYKImage(rect image.Rectangle, colors []color.Color) *image.CMYK {
+	img := image.NewCMYK(rect)
+	fillDrawImage(img, colors)
+	return img
+}
+
 func makeGenericImage(rect image.Rectangle, colors []color.Color) image.Image {
 	img := image.NewRGBA(rect)
 	fillDrawImage(img, colors)
@@ -187,6 +278,17 @@ func makeGenericImage(rect image.Rectangle, colors []color.Color) image.Image {
 	return &genericImage{img}
 }
 
+// defaultImage implements only the image.Image interface.
+type defaultImage struct{ img image.Image }
+
+func (i *defaultImage) ColorModel() color.Model { return i.img.ColorModel() }
+func (i *defaultImage) Bounds() image.Rectangle { return i.img.Bounds() }
+func (i *defaultImage) At(x, y int) color.Color { return i.img.At(x, y) }
+
+func makeDefaultImage(rect image.Rectangle, colors []color.Color) image.Image {
+	return &defaultImage{makeRGBAImage(rect, colors)}
+}
+
 func fillDrawImage(img draw.Image, colors []color.Color) {
 	colorsNRGBA := make([]color.NRGBA, len(colors))
 	for i, c := range colors {
@@ -231,3 +333,76 @@ func readColumn(img image.Image, x int) []uint8 {
 	}
 	return column
 }
+
+func BenchmarkScan(b *testing.B) {
+	rect := image.Rect(0, 0, 256, 256)
+	colors := make([]color.Color, rect.Dx()*rect.Dy())
+	for i := range colors {
+		colors[i] = palette.Plan9[i%len(palette.Plan9)]
+	}
+	testCases := []struct {
+		name string
+		img  image.Image
+	}{
+		{"NYCbCrA", makeNYCbCrAImage(rect, colors, image.YCbCrSubsampleRatio420)},
+		{"Alpha", makeAlphaImage(rect, colors)},
+		{"Alpha16", makeAlpha16Image(rect, colors)},
+		{"CMYK", makeCMYKImage(rect, colors)},
+		{"RGBA64Image", makeGenericImage(rect, colors)},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s := newScanner(tc.img)
+		dst := make([]uint8, rect.Dx()*rect.Dy()*4)
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.scan(0, 0, s.w, s.h, dst)
+			}
+		})
+		b.Run(tc.name+" default", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.scanDefault(0, 0, s.w, s.h, dst)
+			}
+		})
+	}
+}
+
+func readRow16(img image.Image, y int) []uint8 {
+	row := make([]byte, img.Bounds().Dx()*8)
+	i := 0
+	for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+		c := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+		setPixel16(row[i:i+8], c.R, c.G, c.B, c.A)
+		i += 8
+	}
+	return row
+}
+
+func readColumn16(img image.Image, x int) []uint8 {
+	column := make([]byte, img.Bounds().Dy()*8)
+	i := 0
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		c := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+		setPixel16(column[i:i+8], c.R, c.G, c.B, c.A)
+		i += 8
+	}
+	return column
+}
+
+// compareBytes16 compares the big-endian 16-bit values of a and b.
+func compareBytes16(a, b []uint8, delta int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i+1 < len(a); i += 2 {
+		va := int(a[i])<<8 | int(a[i+1])
+		vb := int(b[i])<<8 | int(b[i+1])
+		if absInt(va-vb) > delta {
+			return false
+		}
+	}
+	return true
+}
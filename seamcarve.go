@@ -0,0 +1,382 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// seamMaskEnergy is the energy added to protected pixels and subtracted from pixels
+// marked for removal. It exceeds the energy of any seam made of unmarked pixels.
+const seamMaskEnergy = 1e9
+
+// seamCarveConfig holds the optional parameters for the SeamCarve function.
+type seamCarveConfig struct {
+	// protect marks the pixels that seams avoid.
+	protect image.Image
+	// remove marks the pixels that seams go through first.
+	remove image.Image
+}
+
+// defaultSeamCarveConfig is the default seam carve config.
+var defaultSeamCarveConfig = seamCarveConfig{
+	protect: nil,
+	remove:  nil,
+}
+
+// SeamCarveOption sets an optional parameter for the SeamCarve function.
+type SeamCarveOption func(*seamCarveConfig)
+
+// newSeamCarveConfig returns the seam carve config with the options applied.
+func newSeamCarveConfig(opts []SeamCarveOption) seamCarveConfig {
+	cfg := defaultSeamCarveConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// SeamProtectMask returns a SeamCarveOption that protects the regions of the image marked
+// by the mask from being removed or stretched. The mask is aligned with the top-left corner
+// of the image and a pixel is marked if its luminance is at least 50%, so the regions to
+// protect are typically painted white on a black or transparent background.
+//
+// Example:
+//
+//	dstImage := imaging.SeamCarve(srcImage, 600, 400, imaging.SeamProtectMask(faces))
+func SeamProtectMask(mask image.Image) SeamCarveOption {
+	return func(c *seamCarveConfig) {
+		c.protect = mask
+	}
+}
+
+// SeamRemoveMask returns a SeamCarveOption that makes the seams go through the regions of
+// the image marked by the mask first. The mask is marked as with SeamProtectMask. To erase
+// an object from the image, mark it with the mask and reduce the width (or height) of the
+// image by at least the width (or height) of the object.
+//
+// Example:
+//
+//	dstImage := imaging.SeamCarve(srcImage, srcImage.Bounds().Dx()-50, 0, imaging.SeamRemoveMask(object))
+func SeamRemoveMask(mask image.Image) SeamCarveOption {
+	return func(c *seamCarveConfig) {
+		c.remove = mask
+	}
+}
+
+// SeamCarve resizes the image to the specified width and height using content-aware seam carving
+// and returns the transformed image. Instead of scaling the whole image, paths of connected pixels
+// of the lowest energy (seams) are removed or duplicated one at a time, so the detailed regions
+// of the image keep their proportions. The energy of a pixel is the gradient magnitude of its
+// colors. If one of width or height is 0, the image aspect ratio is preserved.
+//
+// The width is changed first by vertical seams, then the height by horizontal seams.
+// When the image is enlarged by more than half of its size, the seams are inserted in
+// several passes.
+//
+// Example:
+//
+//	dstImage := imaging.SeamCarve(srcImage, 800, 600)
+func SeamCarve(img image.Image, width, height int, opts ...SeamCarveOption) *image.NRGBA {
+	dstW, dstH, ok := resizeSize(img.Bounds(), width, height)
+	if !ok {
+		return &image.NRGBA{}
+	}
+
+	c := newCarver(img, newSeamCarveConfig(opts))
+	c = c.resizeWidth(dstW)
+	if dstH != c.h {
+		c = c.transpose().resizeWidth(dstH).transpose()
+	}
+	return c.image()
+}
+
+// carver holds the state of the seam carving of an image. The pixels are stored
+// in rows of stride pixels, of which only the first w are used.
+type carver struct {
+	w, h   int
+	stride int
+	// pix holds the pixels in the NRGBA format.
+	pix []uint8
+	// mask holds 1 for protected pixels and -1 for pixels to remove.
+	mask []int8
+	// cols holds the original column of the pixels, if tracked.
+	cols   []int
+	energy []float64
+	cost   []float64
+	seam   []int
+}
+
+// newCarver returns a carver for the image with the masks of the config.
+func newCarver(img image.Image, cfg seamCarveConfig) *carver {
+	src := newScanner(img)
+	c := &carver{
+		w:      src.w,
+		h:      src.h,
+		stride: src.w,
+		pix:    make([]uint8, src.w*src.h*4),
+		mask:   make([]int8, src.w*src.h),
+	}
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * src.w * 4
+			src.scan(0, y, src.w, y+1, c.pix[i:i+src.w*4])
+		}
+	})
+	c.applyMask(cfg.protect, 1)
+	c.applyMask(cfg.remove, -1)
+	return c
+}
+
+// applyMask sets the marked pixels of the mask to the value.
+func (c *carver) applyMask(mask image.Image, value int8) {
+	if mask == nil {
+		return
+	}
+	src := newScanner(mask)
+	w := src.w
+	if w > c.w {
+		w = c.w
+	}
+	h := src.h
+	if h > c.h {
+		h = c.h
+	}
+	if w <= 0 || h <= 0 {
+		return
+	}
+	parallel(0, h, func(ys <-chan int) {
+		scanLine := getPix(w * 4)
+		defer putPix(scanLine)
+		for y := range ys {
+			src.scan(0, y, w, y+1, scanLine)
+			for x := 0; x < w; x++ {
+				s := scanLine[x*4 : x*4+4 : x*4+4]
+				f := 0.299*float64(s[0]) + 0.587*float64(s[1]) + 0.114*float64(s[2])
+				if f*float64(s[3])/255 >= 127.5 {
+					c.mask[y*c.stride+x] = value
+				}
+			}
+		}
+	})
+}
+
+// resizeWidth removes or inserts vertical seams until the image has the specified width.
+// It returns the carver holding the result.
+func (c *carver) resizeWidth(width int) *carver {
+	for c.w > width {
+		c.computeEnergy()
+		c.findSeam()
+		c.removeSeam()
+	}
+	for c.w < width {
+		// Each pass duplicates every column at most once, so only the lowest energy
+		// half of the image is stretched.
+		n := width - c.w
+		if n > (c.w+1)/2 {
+			n = (c.w + 1) / 2
+		}
+		c = c.insertSeams(n)
+	}
+	return c
+}
+
+// computeEnergy computes the energy map of the image.
+func (c *carver) computeEnergy() {
+	if len(c.energy) < c.stride*c.h {
+		c.energy = make([]float64, c.stride*c.h)
+	}
+	parallel(0, c.h, func(ys <-chan int) {
+		for y := range ys {
+			up := y - 1
+			if up < 0 {
+				up = 0
+			}
+			down := y + 1
+			if down > c.h-1 {
+				down = c.h - 1
+			}
+			for x := 0; x < c.w; x++ {
+				left := x - 1
+				if left < 0 {
+					left = 0
+				}
+				right := x + 1
+				if right > c.w-1 {
+					right = c.w - 1
+				}
+				l := c.pix[(y*c.stride+left)*4:]
+				r := c.pix[(y*c.stride+right)*4:]
+				u := c.pix[(up*c.stride+x)*4:]
+				d := c.pix[(down*c.stride+x)*4:]
+				var e float64
+				for k := 0; k < 4; k++ {
+					e += math.Abs(float64(r[k])-float64(l[k])) + math.Abs(float64(d[k])-float64(u[k]))
+				}
+				i := y*c.stride + x
+				c.energy[i] = e + float64(c.mask[i])*seamMaskEnergy
+			}
+		}
+	})
+}
+
+// findSeam finds the vertical seam of the lowest total energy and stores
+// the column of each row in c.seam.
+func (c *carver) findSeam() {
+	if len(c.cost) < c.stride*c.h {
+		c.cost = make([]float64, c.stride*c.h)
+	}
+	if len(c.seam) < c.h {
+		c.seam = make([]int, c.h)
+	}
+	copy(c.cost[:c.w], c.energy[:c.w])
+	for y := 1; y < c.h; y++ {
+		prev := c.cost[(y-1)*c.stride : (y-1)*c.stride+c.w]
+		i := y * c.stride
+		for x := 0; x < c.w; x++ {
+			m := prev[x]
+			if x > 0 && prev[x-1] < m {
+				m = prev[x-1]
+			}
+			if x < c.w-1 && prev[x+1] < m {
+				m = prev[x+1]
+			}
+			c.cost[i+x] = c.energy[i+x] + m
+		}
+	}
+
+	last := c.cost[(c.h-1)*c.stride : (c.h-1)*c.stride+c.w]
+	seamX := 0
+	for x := 1; x < c.w; x++ {
+		if last[x] < last[seamX] {
+			seamX = x
+		}
+	}
+	c.seam[c.h-1] = seamX
+	for y := c.h - 2; y >= 0; y-- {
+		row := c.cost[y*c.stride : y*c.stride+c.w]
+		x := seamX
+		if x > 0 && row[x-1] < row[seamX] {
+			seamX = x - 1
+		}
+		if x < c.w-1 && row[x+1] < row[seamX] {
+			seamX = x + 1
+		}
+		c.seam[y] = seamX
+	}
+}
+
+// removeSeam removes the pixels of the seam found by findSeam.
+func (c *carver) removeSeam() {
+	parallel(0, c.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y*c.stride + c.seam[y]
+			j := y*c.stride + c.w
+			copy(c.pix[i*4:j*4], c.pix[(i+1)*4:j*4])
+			copy(c.mask[i:j], c.mask[i+1:j])
+			if c.cols != nil {
+				copy(c.cols[i:j], c.cols[i+1:j])
+			}
+		}
+	})
+	c.w--
+}
+
+// insertSeams returns a carver holding the image enlarged by n vertical seams.
+// The n seams of the lowest energy are found by removing them from a copy of the
+// image, then each of them is duplicated by inserting the average of the seam pixel
+// and its right neighbor.
+func (c *carver) insertSeams(n int) *carver {
+	tmp := &carver{
+		w:      c.w,
+		h:      c.h,
+		stride: c.stride,
+		pix:    make([]uint8, len(c.pix)),
+		mask:   make([]int8, len(c.mask)),
+		cols:   make([]int, len(c.mask)),
+	}
+	copy(tmp.pix, c.pix)
+	copy(tmp.mask, c.mask)
+	for i := range tmp.cols {
+		tmp.cols[i] = i % c.stride
+	}
+	dup := make([]bool, len(c.mask))
+	for k := 0; k < n; k++ {
+		tmp.computeEnergy()
+		tmp.findSeam()
+		for y := 0; y < c.h; y++ {
+			dup[y*c.stride+tmp.cols[y*tmp.stride+tmp.seam[y]]] = true
+		}
+		tmp.removeSeam()
+	}
+
+	w := c.w + n
+	dst := &carver{
+		w:      w,
+		h:      c.h,
+		stride: w,
+		pix:    make([]uint8, w*c.h*4),
+		mask:   make([]int8, w*c.h),
+	}
+	parallel(0, c.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * c.stride
+			j := y * dst.stride
+			for x := 0; x < c.w; x++ {
+				copy(dst.pix[j*4:j*4+4], c.pix[(i+x)*4:(i+x)*4+4])
+				dst.mask[j] = c.mask[i+x]
+				j++
+				if !dup[i+x] {
+					continue
+				}
+				next := x + 1
+				if next > c.w-1 {
+					next = c.w - 1
+				}
+				s1 := c.pix[(i+x)*4 : (i+x)*4+4 : (i+x)*4+4]
+				s2 := c.pix[(i+next)*4 : (i+next)*4+4 : (i+next)*4+4]
+				d := dst.pix[j*4 : j*4+4 : j*4+4]
+				d[0] = uint8((int(s1[0]) + int(s2[0]) + 1) / 2)
+				d[1] = uint8((int(s1[1]) + int(s2[1]) + 1) / 2)
+				d[2] = uint8((int(s1[2]) + int(s2[2]) + 1) / 2)
+				d[3] = uint8((int(s1[3]) + int(s2[3]) + 1) / 2)
+				dst.mask[j] = c.mask[i+x]
+				j++
+			}
+		}
+	})
+	return dst
+}
+
+// transpose returns a carver holding the transposed image.
+func (c *carver) transpose() *carver {
+	dst := &carver{
+		w:      c.h,
+		h:      c.w,
+		stride: c.h,
+		pix:    make([]uint8, c.w*c.h*4),
+		mask:   make([]int8, c.w*c.h),
+	}
+	parallel(0, dst.h, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < dst.w; x++ {
+				i := x*c.stride + y
+				j := y*dst.stride + x
+				copy(dst.pix[j*4:j*4+4], c.pix[i*4:i*4+4])
+				dst.mask[j] = c.mask[i]
+			}
+		}
+	})
+	return dst
+}
+
+// image returns the carved image.
+func (c *carver) image() *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, c.w, c.h))
+	parallel(0, c.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * c.stride * 4
+			copy(dst.Pix[y*dst.Stride:y*dst.Stride+c.w*4], c.pix[i:i+c.w*4])
+		}
+	})
+	return dst
+}
@@ -0,0 +1,142 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// makeSeamTestImage returns an image that is flat white on the left and noisy on the right.
+func makeSeamTestImage(w, h, flatW int) *image.NRGBA {
+	rnd := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+			if x >= flatW {
+				c = color.NRGBA{uint8(rnd.Intn(200)), uint8(rnd.Intn(200)), 0, 0xff}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// countWhite returns the number of white pixels in each row of the image.
+func countWhite(img *image.NRGBA) []int {
+	counts := make([]int, img.Rect.Dy())
+	for y := range counts {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if img.NRGBAAt(x, y) == (color.NRGBA{0xff, 0xff, 0xff, 0xff}) {
+				counts[y]++
+			}
+		}
+	}
+	return counts
+}
+
+func TestSeamCarve(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		width, height int
+		want          image.Point
+	}{
+		{"Shrink", 14, 9, image.Pt(14, 9)},
+		{"Enlarge", 26, 12, image.Pt(26, 12)},
+		{"EnlargeTwice", 50, 30, image.Pt(50, 30)},
+		{"PreserveRatio", 10, 0, image.Pt(10, 5)},
+		{"Same", 20, 10, image.Pt(20, 10)},
+		{"Zero", 0, 0, image.Pt(0, 0)},
+		{"Negative", -1, 10, image.Pt(0, 0)},
+	}
+	img := makeSeamTestImage(20, 10, 8)
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := SeamCarve(img, tc.width, tc.height)
+			if got.Rect.Size() != tc.want {
+				t.Fatalf("got size %v want %v", got.Rect.Size(), tc.want)
+			}
+		})
+	}
+
+	// The seams go through the flat region.
+	for _, width := range []int{14, 26} {
+		for y, n := range countWhite(SeamCarve(img, width, 0)) {
+			if want := 8 + width - 20; n != want {
+				t.Fatalf("width %d: row %d: got %d white pixels want %d", width, y, n, want)
+			}
+		}
+	}
+
+	// The width and the height are carved the same way.
+	got := SeamCarve(img, 20, 7)
+	want := Transpose(SeamCarve(Transpose(img), 7, 20))
+	if !compareNRGBA(got, want, 0) {
+		t.Fatal("carving the height does not match carving the width of the transposed image")
+	}
+}
+
+func TestSeamCarveMasks(t *testing.T) {
+	t.Parallel()
+
+	img := makeSeamTestImage(20, 10, 8)
+	flat := image.NewNRGBA(image.Rect(0, 0, 8, 10))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0xff
+	}
+
+	// The protected flat region is kept.
+	for _, width := range []int{14, 26} {
+		got := SeamCarve(img, width, 0, SeamProtectMask(flat))
+		for y, n := range countWhite(got) {
+			if n != 8 {
+				t.Fatalf("width %d: row %d: got %d white pixels want 8", width, y, n)
+			}
+		}
+	}
+
+	// The pixels marked for removal are removed first, even in a noisy region.
+	obj := image.Rect(12, 3, 15, 8)
+	marked := Clone(img)
+	for y := obj.Min.Y; y < obj.Max.Y; y++ {
+		for x := obj.Min.X; x < obj.Max.X; x++ {
+			c := marked.NRGBAAt(x, y)
+			c.B = 0xff
+			marked.SetNRGBA(x, y, c)
+		}
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, 20, 10))
+	for y := obj.Min.Y; y < obj.Max.Y; y++ {
+		for x := obj.Min.X; x < obj.Max.X; x++ {
+			mask.SetAlpha(x, y, color.Alpha{0xff})
+		}
+	}
+	got := SeamCarve(marked, 17, 10, SeamRemoveMask(mask), SeamProtectMask(flat))
+	for y := 0; y < got.Rect.Dy(); y++ {
+		for x := 0; x < got.Rect.Dx(); x++ {
+			if c := got.NRGBAAt(x, y); c.B == 0xff && c.R != 0xff {
+				t.Fatalf("marked pixel left at (%d, %d)", x, y)
+			}
+		}
+	}
+
+	// Empty masks are ignored.
+	want := SeamCarve(img, 15, 8)
+	got = SeamCarve(img, 15, 8, SeamProtectMask(&image.NRGBA{}), SeamRemoveMask(nil))
+	if !compareNRGBA(got, want, 0) {
+		t.Fatal("empty masks changed the result")
+	}
+}
+
+func BenchmarkSeamCarve(b *testing.B) {
+	img := Resize(testdataBranchesJPG, 200, 0, Box)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		SeamCarve(img, 180, 120)
+	}
+}
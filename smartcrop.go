@@ -0,0 +1,260 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	// smartCropAnalysisSize is the maximum size of the downscaled image used to score the crop windows.
+	smartCropAnalysisSize = 256
+	// smartCropTileSize is the size of the tiles the local entropy is computed for.
+	smartCropTileSize = 8
+	// smartCropSteps is the maximum number of window positions tried along each axis.
+	smartCropSteps = 40
+)
+
+// Weights of the features in the interest score of a pixel.
+const (
+	smartCropEdgeWeight       = 1.0
+	smartCropEntropyWeight    = 0.5
+	smartCropSkinWeight       = 1.5
+	smartCropSaturationWeight = 0.5
+)
+
+// SmartCrop cuts out the width x height region of the image that is most likely to
+// contain its subject and returns the cropped image along with the chosen rectangle
+// in the coordinates of the image. The region is clipped to the image bounds.
+//
+// The candidate regions are scored by the density of edges, the local entropy of the
+// luminance, skin tones and color saturation of their pixels. Regions that score the same,
+// such as the regions of a flat image, are resolved in favor of the centered region.
+//
+// Example:
+//
+//	dstImage, rect := imaging.SmartCrop(srcImage, 300, 300)
+func SmartCrop(img image.Image, width, height int) (*image.NRGBA, image.Rectangle) {
+	srcBounds := img.Bounds()
+	if width > srcBounds.Dx() {
+		width = srcBounds.Dx()
+	}
+	if height > srcBounds.Dy() {
+		height = srcBounds.Dy()
+	}
+	if width <= 0 || height <= 0 {
+		return &image.NRGBA{}, image.Rectangle{}
+	}
+	r := smartCropRect(img, width, height)
+	return Crop(img, r), r
+}
+
+// SmartFill is like Fill but chooses the region to keep with the heuristics of SmartCrop
+// instead of an anchor point. It returns the transformed image along with the cropped
+// rectangle in the coordinates of the source image.
+//
+// Example:
+//
+//	dstImage, rect := imaging.SmartFill(srcImage, 200, 200, imaging.Lanczos)
+func SmartFill(img image.Image, width, height int, filter ResampleFilter, opts ...ResizeOption) (*image.NRGBA, image.Rectangle) {
+	dstW, dstH := width, height
+	srcBounds := img.Bounds()
+	if dstW <= 0 || dstH <= 0 || srcBounds.Empty() {
+		return &image.NRGBA{}, image.Rectangle{}
+	}
+	if srcBounds.Dx() == dstW && srcBounds.Dy() == dstH {
+		return Clone(img), srcBounds
+	}
+
+	cropW, cropH := fillCropSize(srcBounds, dstW, dstH)
+	r := smartCropRect(img, cropW, cropH)
+	return ResizeWithOptions(Crop(img, r), dstW, dstH, filter, opts...), r
+}
+
+// smartCropRect returns the width x height region of the non-empty image with the highest
+// interest score. The size must fit in the image.
+func smartCropRect(img image.Image, width, height int) image.Rectangle {
+	srcBounds := img.Bounds()
+	srcW, srcH := srcBounds.Dx(), srcBounds.Dy()
+	if width == srcW && height == srcH {
+		return srcBounds
+	}
+
+	analysis := img
+	if srcW > smartCropAnalysisSize || srcH > smartCropAnalysisSize {
+		analysis = Fit(img, smartCropAnalysisSize, smartCropAnalysisSize, Box)
+	}
+	sum, stride := smartCropScores(analysis)
+	aw, ah := analysis.Bounds().Dx(), analysis.Bounds().Dy()
+	sx := float64(aw) / float64(srcW)
+	sy := float64(ah) / float64(srcH)
+
+	// score returns the total interest of the region at (x, y) of the source image.
+	score := func(x, y int) float64 {
+		x0 := int(math.Floor(float64(x)*sx + 0.5))
+		y0 := int(math.Floor(float64(y)*sy + 0.5))
+		x1 := int(math.Floor(float64(x+width)*sx + 0.5))
+		y1 := int(math.Floor(float64(y+height)*sy + 0.5))
+		return sum[y1*stride+x1] - sum[y0*stride+x1] - sum[y1*stride+x0] + sum[y0*stride+x0]
+	}
+
+	// Scores are compared with a tolerance for the rounding errors of the summed-area table.
+	const eps = 1e-9
+	maxX, maxY := srcW-width, srcH-height
+	best := image.Pt(maxX/2, maxY/2)
+	bestScore := score(best.X, best.Y)
+	bestDist := maxX%2 + maxY%2
+	stepX := (maxX + smartCropSteps - 1) / smartCropSteps
+	if stepX < 1 {
+		stepX = 1
+	}
+	stepY := (maxY + smartCropSteps - 1) / smartCropSteps
+	if stepY < 1 {
+		stepY = 1
+	}
+	for y := 0; y <= maxY; y += stepY {
+		for x := 0; x <= maxX; x += stepX {
+			s := score(x, y)
+			dist := absInt(2*x-maxX) + absInt(2*y-maxY)
+			if s > bestScore+eps || (s >= bestScore-eps && dist < bestDist) {
+				best = image.Pt(x, y)
+				bestScore = s
+				bestDist = dist
+			}
+		}
+	}
+	return image.Rect(0, 0, width, height).Add(best).Add(srcBounds.Min)
+}
+
+// smartCropScores computes the interest score of each pixel of the image and returns
+// its summed-area table along with the stride of the table rows.
+func smartCropScores(img image.Image) ([]float64, int) {
+	src := newScanner(img)
+	w, h := src.w, src.h
+	pix := make([]uint8, w*h*4)
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			src.scan(0, y, w, y+1, pix[y*w*4:(y+1)*w*4])
+		}
+	})
+
+	lum := make([]float64, w*h)
+	for i := range lum {
+		s := pix[i*4 : i*4+4 : i*4+4]
+		lum[i] = (0.299*float64(s[0]) + 0.587*float64(s[1]) + 0.114*float64(s[2])) / 255
+	}
+	entropy := smartCropEntropy(lum, w, h)
+	tilesX := (w + smartCropTileSize - 1) / smartCropTileSize
+
+	scores := make([]float64, w*h)
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			up := y - 1
+			if up < 0 {
+				up = 0
+			}
+			down := y + 1
+			if down > h-1 {
+				down = h - 1
+			}
+			for x := 0; x < w; x++ {
+				left := x - 1
+				if left < 0 {
+					left = 0
+				}
+				right := x + 1
+				if right > w-1 {
+					right = w - 1
+				}
+				edge := math.Abs(lum[y*w+right]-lum[y*w+left]) + math.Abs(lum[down*w+x]-lum[up*w+x])
+				if edge > 1 {
+					edge = 1
+				}
+
+				i := y*w + x
+				s := pix[i*4 : i*4+4 : i*4+4]
+				var skin float64
+				if isSkinTone(s[0], s[1], s[2]) {
+					skin = 1
+				}
+				_, sat, l := rgbToHSL(s[0], s[1], s[2])
+				// Saturated colors are only visible away from black and white.
+				sat *= 1 - math.Abs(2*l-1)
+
+				tile := (y/smartCropTileSize)*tilesX + x/smartCropTileSize
+				score := smartCropEdgeWeight*edge +
+					smartCropEntropyWeight*entropy[tile] +
+					smartCropSkinWeight*skin +
+					smartCropSaturationWeight*sat
+				scores[i] = score * float64(s[3]) / 255
+			}
+		}
+	})
+
+	// Summed-area table with a leading row and column of zeros.
+	stride := w + 1
+	sum := make([]float64, stride*(h+1))
+	for y := 0; y < h; y++ {
+		var row float64
+		for x := 0; x < w; x++ {
+			row += scores[y*w+x]
+			sum[(y+1)*stride+x+1] = sum[y*stride+x+1] + row
+		}
+	}
+	return sum, stride
+}
+
+// smartCropEntropy returns the entropy of the luminance histogram of each tile of the image,
+// normalized to the range [0, 1]. The tiles are stored in rows.
+func smartCropEntropy(lum []float64, w, h int) []float64 {
+	const bins = 16
+	tilesX := (w + smartCropTileSize - 1) / smartCropTileSize
+	tilesY := (h + smartCropTileSize - 1) / smartCropTileSize
+	entropy := make([]float64, tilesX*tilesY)
+	parallel(0, tilesY, func(ts <-chan int) {
+		for ty := range ts {
+			for tx := 0; tx < tilesX; tx++ {
+				var hist [bins]int
+				var n int
+				for y := ty * smartCropTileSize; y < h && y < (ty+1)*smartCropTileSize; y++ {
+					for x := tx * smartCropTileSize; x < w && x < (tx+1)*smartCropTileSize; x++ {
+						b := int(lum[y*w+x] * bins)
+						if b > bins-1 {
+							b = bins - 1
+						}
+						hist[b]++
+						n++
+					}
+				}
+				var e float64
+				for _, c := range hist {
+					if c > 0 {
+						p := float64(c) / float64(n)
+						e -= p * math.Log2(p)
+					}
+				}
+				entropy[ty*tilesX+tx] = e / math.Log2(bins)
+			}
+		}
+	})
+	return entropy
+}
+
+// isSkinTone reports whether the color is in the range of human skin tones.
+func isSkinTone(r, g, b uint8) bool {
+	max := r
+	if g > max {
+		max = g
+	}
+	if b > max {
+		max = b
+	}
+	min := r
+	if g < min {
+		min = g
+	}
+	if b < min {
+		min = b
+	}
+	return r > 95 && g > 40 && b > 20 && r > g && r > b &&
+		int(max)-int(min) > 15 && int(r)-int(g) > 15
+}
@@ -0,0 +1,170 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// makeSubjectImage returns a flat gray image with a detailed subject in the rectangle r.
+func makeSubjectImage(bounds, r image.Rectangle) *image.NRGBA {
+	rnd := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBA{0x80, 0x80, 0x80, 0xff}
+			if image.Pt(x, y).In(r) {
+				c = color.NRGBA{uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), 0xff}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestSmartCrop(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		img           *image.NRGBA
+		width, height int
+		subject       image.Rectangle
+		want          image.Rectangle
+	}{
+		{
+			name:    "Flat",
+			img:     makeSubjectImage(image.Rect(0, 0, 100, 200), image.Rectangle{}),
+			width:   100,
+			height:  100,
+			subject: image.Rectangle{},
+			want:    image.Rect(0, 50, 100, 150),
+		},
+		{
+			name:    "Top",
+			img:     makeSubjectImage(image.Rect(0, 0, 100, 200), image.Rect(30, 10, 70, 50)),
+			width:   100,
+			height:  100,
+			subject: image.Rect(30, 10, 70, 50),
+		},
+		{
+			name:    "Right",
+			img:     makeSubjectImage(image.Rect(-50, 20, 350, 120), image.Rect(280, 40, 340, 100)),
+			width:   100,
+			height:  100,
+			subject: image.Rect(280, 40, 340, 100),
+		},
+		{
+			name:    "Large",
+			img:     makeSubjectImage(image.Rect(0, 0, 1000, 400), image.Rect(100, 100, 200, 300)),
+			width:   300,
+			height:  300,
+			subject: image.Rect(100, 100, 200, 300),
+		},
+		{
+			name:    "Clipped",
+			img:     makeSubjectImage(image.Rect(10, 10, 60, 40), image.Rect(20, 20, 30, 30)),
+			width:   100,
+			height:  100,
+			subject: image.Rect(20, 20, 30, 30),
+			want:    image.Rect(10, 10, 60, 40),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, r := SmartCrop(tc.img, tc.width, tc.height)
+			if !tc.want.Empty() && r != tc.want {
+				t.Fatalf("got rectangle %v want %v", r, tc.want)
+			}
+			if !tc.subject.In(r) {
+				t.Fatalf("rectangle %v does not contain the subject %v", r, tc.subject)
+			}
+			if !r.In(tc.img.Rect) {
+				t.Fatalf("rectangle %v is out of the image bounds %v", r, tc.img.Rect)
+			}
+			if !compareNRGBA(got, Crop(tc.img, r), 0) {
+				t.Fatal("cropped image does not match the rectangle")
+			}
+		})
+	}
+
+	if got, r := SmartCrop(testdataBranchesPNG, 0, 10); !got.Rect.Empty() || !r.Empty() {
+		t.Fatalf("got %v and %v want empty", got.Rect, r)
+	}
+}
+
+func TestSmartCropSkinTone(t *testing.T) {
+	t.Parallel()
+
+	// A skin-colored region outweighs an equally sized gray one.
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 100))
+	draw := func(r image.Rectangle, c color.NRGBA) {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	draw(img.Rect, color.NRGBA{0x20, 0x20, 0x20, 0xff})
+	draw(image.Rect(20, 20, 80, 80), color.NRGBA{0xa0, 0xa0, 0xa0, 0xff})
+	draw(image.Rect(220, 20, 280, 80), color.NRGBA{0xe0, 0xac, 0x90, 0xff})
+	_, r := SmartCrop(img, 100, 100)
+	if !image.Rect(220, 20, 280, 80).In(r) {
+		t.Fatalf("rectangle %v does not contain the skin-colored region", r)
+	}
+
+	for _, tc := range []struct {
+		c    color.NRGBA
+		want bool
+	}{
+		{color.NRGBA{0xe0, 0xac, 0x90, 0xff}, true},
+		{color.NRGBA{0x8d, 0x55, 0x24, 0xff}, true},
+		{color.NRGBA{0xa0, 0xa0, 0xa0, 0xff}, false},
+		{color.NRGBA{0x20, 0x40, 0xe0, 0xff}, false},
+		{color.NRGBA{0xff, 0xff, 0xff, 0xff}, false},
+	} {
+		if got := isSkinTone(tc.c.R, tc.c.G, tc.c.B); got != tc.want {
+			t.Fatalf("isSkinTone(%v): got %v want %v", tc.c, got, tc.want)
+		}
+	}
+}
+
+func TestSmartFill(t *testing.T) {
+	t.Parallel()
+
+	img := makeSubjectImage(image.Rect(0, 0, 400, 200), image.Rect(300, 50, 380, 150))
+	got, r := SmartFill(img, 50, 50, Lanczos)
+	if got.Rect.Size() != image.Pt(50, 50) {
+		t.Fatalf("got size %v want 50x50", got.Rect.Size())
+	}
+	if r.Dx() != 200 || r.Dy() != 200 || !image.Rect(300, 50, 380, 150).In(r) {
+		t.Fatalf("got rectangle %v", r)
+	}
+	if !compareNRGBA(got, Resize(Crop(img, r), 50, 50, Lanczos), 0) {
+		t.Fatal("result does not match the resized rectangle")
+	}
+
+	got, r = SmartFill(img, 400, 200, Lanczos)
+	if r != img.Rect || !compareNRGBA(got, img, 0) {
+		t.Fatal("same size SmartFill changed the image")
+	}
+
+	got, r = SmartFill(testdataBranchesJPG, 100, 150, Linear, LinearLight(true))
+	if got.Rect.Size() != image.Pt(100, 150) || !r.In(testdataBranchesJPG.Bounds()) {
+		t.Fatalf("got size %v and rectangle %v", got.Rect.Size(), r)
+	}
+
+	if got, r := SmartFill(img, -1, 10, Lanczos); !got.Rect.Empty() || !r.Empty() {
+		t.Fatalf("got %v and %v want empty", got.Rect, r)
+	}
+}
+
+func BenchmarkSmartFill(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		SmartFill(testdataBranchesJPG, 100, 100, Lanczos)
+	}
+}
@@ -0,0 +1,548 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"regexp"
+)
+
+// StripMode selects the metadata removed by StripMetadata.
+type StripMode int
+
+const (
+	// StripAll removes the EXIF data, the XMP packets, the IPTC data and the comments.
+	StripAll StripMode = iota
+	// StripGPS removes the GPS directory of the EXIF data and the exif:GPS properties
+	// of the XMP packets. The extended XMP packet of a JPEG image is removed as a whole
+	// if it holds GPS properties. Other metadata is kept.
+	StripGPS
+	// StripKeepTags removes the XMP packets, the IPTC data, the comments, the
+	// thumbnail and all the EXIF tags but the ones listed in StripPolicy.KeepTags.
+	StripKeepTags
+)
+
+// StripPolicy specifies the metadata removed by StripMetadata.
+type StripPolicy struct {
+	// Mode selects the metadata removed.
+	Mode StripMode
+	// KeepTags lists the tags of the primary image directory (IFD0) and of the Exif
+	// directory kept in the StripKeepTags mode, e.g. TagCopyright and TagOrientation.
+	// Listing TagGPSIFDPointer or TagInteropIFDPointer keeps the whole directory.
+	KeepTags []uint16
+}
+
+// stripAction is the action taken on an EXIF tag by StripMetadata.
+type stripAction int
+
+const (
+	// stripKeep keeps the tag, and the whole directory it points to.
+	stripKeep stripAction = iota
+	// stripRemove removes the tag, and the whole directory it points to.
+	stripRemove
+	// stripFilter keeps the pointer tag and filters the tags of the directory it points to.
+	stripFilter
+)
+
+// action returns the action taken on the EXIF tag according to the policy.
+func (p StripPolicy) action(ifd IFD, id uint16) stripAction {
+	switch p.Mode {
+	case StripGPS:
+		if ifd == IFD0 && id == TagGPSIFDPointer {
+			return stripRemove
+		}
+		return stripKeep
+	case StripKeepTags:
+		if ifd == IFD0 && id == TagExifIFDPointer {
+			return stripFilter
+		}
+		for _, keep := range p.KeepTags {
+			if keep == id {
+				return stripKeep
+			}
+		}
+	}
+	return stripRemove
+}
+
+var (
+	// xmpGPSProperty matches the GPS properties of an XMP packet.
+	xmpGPSProperty = newXMPProperty("exif:GPS", `exif:GPS\w*`) //nolint
+	// xmpExtendedProperty matches the reference of an XMP packet to its extended XMP packet.
+	xmpExtendedProperty = newXMPProperty("xmpNote:HasExtendedXMP", `xmpNote:HasExtendedXMP`) //nolint
+)
+
+// xmpProperty matches the properties of an XMP packet with a given name,
+// written either as attributes or as elements.
+type xmpProperty struct {
+	prefix       string
+	attr         *regexp.Regexp
+	emptyElement *regexp.Regexp
+	startTag     *regexp.Regexp
+}
+
+// newXMPProperty returns an xmpProperty matching the qualified names that match the
+// regular expression name and start with prefix.
+func newXMPProperty(prefix, name string) *xmpProperty {
+	return &xmpProperty{
+		prefix:       prefix,
+		attr:         regexp.MustCompile(`\s+` + name + `\s*=\s*(?:"[^"]*"|'[^']*')`),
+		emptyElement: regexp.MustCompile(`\s*<` + name + `(?:\s[^>]*)?/>`),
+		startTag:     regexp.MustCompile(`\s*<(` + name + `)(?:\s[^>]*)?>`),
+	}
+}
+
+// remove returns a copy of the XMP packet without the properties, or nil
+// if they can't be removed because the packet is malformed.
+func (p *xmpProperty) remove(xmp []byte) []byte {
+	out := p.attr.ReplaceAll(xmp, nil)
+	out = p.emptyElement.ReplaceAll(out, nil)
+	for {
+		loc := p.startTag.FindSubmatchIndex(out)
+		if loc == nil {
+			break
+		}
+		endTag := "</" + string(out[loc[2]:loc[3]]) + ">"
+		end := bytes.Index(out[loc[1]:], []byte(endTag))
+		if end < 0 {
+			return nil
+		}
+		end += loc[1] + len(endTag)
+		out = append(out[:loc[0]], out[end:]...)
+	}
+	if bytes.Contains(out, []byte(p.prefix)) {
+		return nil
+	}
+	return out
+}
+
+// stripXMP returns the XMP packet without the properties removed by the policy,
+// or nil if the whole packet is removed. If dropExtended is true, the reference
+// to the extended XMP packet is removed too.
+func (p StripPolicy) stripXMP(xmp []byte, dropExtended bool) []byte {
+	if p.Mode != StripGPS {
+		return nil
+	}
+	out := xmpGPSProperty.remove(xmp)
+	if out != nil && dropExtended {
+		out = xmpExtendedProperty.remove(out)
+	}
+	return out
+}
+
+// StripMetadata copies the JPEG or PNG image data from r to w, removing the
+// metadata according to the policy. The pixel data is copied as is, without
+// being decoded and re-encoded. The ICC color profile is always kept.
+//
+// Removed EXIF tags and directories are cleared but the layout of the EXIF data
+// is kept, so that the offsets of the remaining tags stay valid.
+// Note that the orientation tag is removed too unless it is kept with StripKeepTags.
+//
+// Example:
+//
+//	// Remove everything but the copyright and the orientation tags.
+//	err := imaging.StripMetadata(r, w, imaging.StripPolicy{
+//		Mode:     imaging.StripKeepTags,
+//		KeepTags: []uint16{imaging.TagCopyright, imaging.TagOrientation},
+//	})
+func StripMetadata(r io.Reader, w io.Writer, policy StripPolicy) error {
+	format, r, err := DetectFormat(r)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	switch format {
+	case JPEG:
+		out, err = stripJPEGMetadata(data, policy)
+	case PNG:
+		out, err = stripPNGMetadata(data, policy)
+	default:
+		return ErrUnsupportedFormat
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// xmpExtensionHeader is the header of the JPEG APP1 segments holding extended XMP data.
+const xmpExtensionHeader = "http://ns.adobe.com/xmp/extension/\x00"
+
+// stripJPEGMetadata returns the JPEG image data without the metadata
+// segments removed by the policy.
+func stripJPEGMetadata(data []byte, policy StripPolicy) ([]byte, error) {
+	const (
+		markerAPP1  = 0xe1
+		markerAPP13 = 0xed
+		markerCOM   = 0xfe
+	)
+
+	segments, rest, err := readJPEGSegments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// The extended XMP packet is split across several segments with a checksum of
+	// the whole packet, so all the segments are removed if it holds GPS properties.
+	dropExtended := policy.Mode == StripGPS && extendedXMPHasGPS(segments)
+
+	out := append([]byte(nil), data[:2]...)
+	for _, s := range segments {
+		switch {
+		case s.marker == markerAPP1 && bytes.HasPrefix(s.payload, []byte(exifHeader)):
+			exif, err := stripEXIF(s.payload[len(exifHeader):], policy)
+			if err != nil {
+				return nil, err
+			}
+			if exif != nil {
+				out = append(out, s.data[:len(s.data)-len(s.payload)]...)
+				out = append(out, exifHeader...)
+				out = append(out, exif...)
+			}
+		case s.marker == markerAPP1 && bytes.HasPrefix(s.payload, []byte(xmpHeader)):
+			xmp := policy.stripXMP(s.payload[len(xmpHeader):], dropExtended)
+			if xmp != nil {
+				out = append(out, 0xff, markerAPP1)
+				out = binary.BigEndian.AppendUint16(out, uint16(len(xmpHeader)+len(xmp)+2))
+				out = append(out, xmpHeader...)
+				out = append(out, xmp...)
+			}
+		case s.marker == markerAPP1 && bytes.HasPrefix(s.payload, []byte(xmpExtensionHeader)):
+			if policy.Mode == StripGPS && !dropExtended {
+				out = append(out, s.data...)
+			}
+		case s.marker == markerAPP13 || s.marker == markerCOM:
+			if policy.Mode == StripGPS {
+				out = append(out, s.data...)
+			}
+		default:
+			out = append(out, s.data...)
+		}
+	}
+	// The rest of the image is copied as is.
+	return append(out, rest...), nil
+}
+
+// jpegSegment is a segment of a JPEG image.
+type jpegSegment struct {
+	marker byte
+	// data is the whole segment, including the marker.
+	data []byte
+	// payload is the data of the segment after the length, nil for standalone markers.
+	payload []byte
+}
+
+// readJPEGSegments splits the JPEG image data into the segments before the SOS or EOI
+// marker and the rest of the data, starting at that marker.
+func readJPEGSegments(data []byte) ([]jpegSegment, []byte, error) {
+	const (
+		markerEOI = 0xd9
+		markerSOS = 0xda
+	)
+
+	r := bytes.NewReader(data)
+	if err := findJPEGSOIMarker(r); err != nil {
+		return nil, nil, err
+	}
+	var segments []jpegSegment
+	for {
+		start := len(data) - r.Len()
+		marker, err := readJPEGMarker(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if marker == markerSOS || marker == markerEOI {
+			return segments, data[start:], nil
+		}
+		// Standalone markers (TEM and RSTn) have no segment.
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			segments = append(segments, jpegSegment{marker: marker, data: data[start : len(data)-r.Len()]})
+			continue
+		}
+
+		var size uint16
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, nil, err
+		}
+		if size < 2 || int(size-2) > r.Len() {
+			return nil, nil, errors.New("invalid block size")
+		}
+		dataStart := len(data) - r.Len()
+		end := dataStart + int(size-2)
+		if _, err := r.Seek(int64(size-2), io.SeekCurrent); err != nil {
+			return nil, nil, err
+		}
+		segments = append(segments, jpegSegment{marker: marker, data: data[start:end], payload: data[dataStart:end]})
+	}
+}
+
+// extendedXMPHasGPS reports whether the extended XMP packets of the JPEG segments hold
+// GPS properties, or can't be reassembled to check it.
+func extendedXMPHasGPS(segments []jpegSegment) bool {
+	const markerAPP1 = 0xe1
+	// Each segment holds the GUID of the packet, the length of the packet
+	// and the offset of its part of the packet.
+	const headerSize = len(xmpExtensionHeader) + 32 + 4 + 4
+
+	var parts [][]byte
+	total := 0
+	for _, s := range segments {
+		if s.marker != markerAPP1 || !bytes.HasPrefix(s.payload, []byte(xmpExtensionHeader)) {
+			continue
+		}
+		if len(s.payload) < headerSize {
+			return true
+		}
+		parts = append(parts, s.payload[len(xmpExtensionHeader):])
+		total += len(s.payload) - headerSize
+	}
+
+	packets := map[string][]byte{}
+	for _, part := range parts {
+		guid := string(part[:32])
+		size := binary.BigEndian.Uint32(part[32:36])
+		offset := binary.BigEndian.Uint32(part[36:40])
+		data := part[40:]
+		// A packet larger than all the parts together is incomplete.
+		if int64(size) > int64(total) || int64(offset)+int64(len(data)) > int64(size) {
+			return true
+		}
+		packet, ok := packets[guid]
+		if !ok {
+			packet = make([]byte, size)
+			packets[guid] = packet
+		}
+		if len(packet) != int(size) {
+			return true
+		}
+		copy(packet[offset:], data)
+	}
+	for _, packet := range packets {
+		if bytes.Contains(packet, []byte(xmpGPSProperty.prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripPNGMetadata returns the PNG image data without the metadata
+// chunks removed by the policy.
+func stripPNGMetadata(data []byte, policy StripPolicy) ([]byte, error) {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []byte(pngSignature)
+	for _, c := range chunks {
+		switch c.typ {
+		case "eXIf":
+			exif, err := stripEXIF(bytes.TrimPrefix(c.data, []byte(exifHeader)), policy)
+			if err != nil {
+				return nil, err
+			}
+			if exif != nil {
+				out = appendPNGChunk(out, c.typ, exif)
+			}
+			continue
+		case "iTXt":
+			xmp, err := pngXMP(c.data)
+			if err != nil {
+				return nil, err
+			}
+			if xmp != nil {
+				if xmp := policy.stripXMP(xmp, false); xmp != nil {
+					text := append([]byte(xmpKeyword+"\x00\x00\x00\x00\x00"), xmp...)
+					out = appendPNGChunk(out, c.typ, text)
+				}
+				continue
+			}
+			if policy.Mode != StripGPS {
+				continue
+			}
+		case "tEXt", "zTXt":
+			// Text chunks hold comments and raw EXIF or IPTC profiles.
+			if policy.Mode != StripGPS {
+				continue
+			}
+		}
+		out = appendPNGChunk(out, c.typ, c.data)
+	}
+	return out, nil
+}
+
+// stripEXIF returns a copy of the EXIF data without the tags removed by the
+// policy, or nil if no tag is kept.
+func stripEXIF(tiff []byte, policy StripPolicy) ([]byte, error) {
+	if policy.Mode == StripAll {
+		return nil, nil
+	}
+	e, err := parseEXIF(tiff)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &exifStripper{
+		tiff:    append([]byte(nil), tiff...),
+		order:   e.ByteOrder,
+		policy:  policy,
+		visited: map[uint32]bool{},
+	}
+	offset := s.order.Uint32(s.tiff[4:8])
+	kept, err := s.filter(IFD0, offset)
+	if err != nil {
+		return nil, err
+	}
+	if kept == 0 {
+		return nil, nil
+	}
+	return s.tiff, nil
+}
+
+// exifStripper removes the tags of the EXIF data in place.
+type exifStripper struct {
+	tiff    []byte
+	order   binary.ByteOrder
+	policy  StripPolicy
+	visited map[uint32]bool
+}
+
+// exifSubIFD returns the directory the pointer tag points to.
+func exifSubIFD(id uint16) (IFD, bool) {
+	switch id {
+	case TagExifIFDPointer:
+		return ExifIFD, true
+	case TagGPSIFDPointer:
+		return GPSIFD, true
+	case TagInteropIFDPointer:
+		return InteropIFD, true
+	}
+	return 0, false
+}
+
+// entries returns the position of the tags of the directory at the offset.
+func (s *exifStripper) entries(offset uint32) (start, end uint64, err error) {
+	if s.visited[offset] || uint64(offset)+2 > uint64(len(s.tiff)) {
+		return 0, 0, errInvalidEXIF
+	}
+	s.visited[offset] = true
+	start = uint64(offset) + 2
+	end = start + uint64(s.order.Uint16(s.tiff[offset:]))*12
+	if end > uint64(len(s.tiff)) {
+		return 0, 0, errInvalidEXIF
+	}
+	return start, end, nil
+}
+
+// filter removes the tags of the directory at the offset according to the
+// policy and returns the number of tags kept. The remaining tags are moved to
+// the start of the directory.
+func (s *exifStripper) filter(ifd IFD, offset uint32) (int, error) {
+	start, end, err := s.entries(offset)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := start
+	for i := start; i < end; i += 12 {
+		entry := s.tiff[i : i+12]
+		id := s.order.Uint16(entry)
+		action := s.policy.action(ifd, id)
+		if action == stripFilter {
+			sub, _ := exifSubIFD(id)
+			n, err := s.filter(sub, s.order.Uint32(entry[8:]))
+			if err != nil {
+				return 0, err
+			}
+			if n == 0 {
+				action = stripRemove
+			}
+		}
+		if action == stripRemove {
+			s.clearTag(entry)
+			continue
+		}
+		copy(s.tiff[kept:], entry)
+		kept += 12
+	}
+
+	// The thumbnail directory is not kept in the StripKeepTags mode.
+	var next []byte
+	if end+4 <= uint64(len(s.tiff)) {
+		next = s.tiff[end : end+4]
+		if s.policy.Mode == StripKeepTags && ifd == IFD0 {
+			if offset := s.order.Uint32(next); offset != 0 {
+				s.clearIFD(IFD1, offset)
+			}
+			next = []byte{0, 0, 0, 0}
+		}
+		next = append([]byte(nil), next...)
+	}
+
+	removed := s.tiff[kept:end]
+	for i := range removed {
+		removed[i] = 0
+	}
+	copy(s.tiff[kept:], next)
+	s.order.PutUint16(s.tiff[offset:], uint16((kept-start)/12))
+	return int((kept - start) / 12), nil
+}
+
+// clearTag clears the value of the tag entry and the directory it points to.
+// The entry itself is removed by the caller.
+func (s *exifStripper) clearTag(entry []byte) {
+	id := s.order.Uint16(entry)
+	typ := EXIFType(s.order.Uint16(entry[2:]))
+	size := uint64(typ.size()) * uint64(s.order.Uint32(entry[4:]))
+	if ifd, ok := exifSubIFD(id); ok {
+		s.clearIFD(ifd, s.order.Uint32(entry[8:]))
+	} else if size > 4 {
+		s.clearRange(uint64(s.order.Uint32(entry[8:])), size)
+	}
+}
+
+// clearIFD clears the directory at the offset, the values of its tags and the
+// directories they point to. The thumbnail data of IFD1 is cleared too.
+func (s *exifStripper) clearIFD(ifd IFD, offset uint32) {
+	start, end, err := s.entries(offset)
+	if err != nil {
+		return
+	}
+
+	var thumbOffset, thumbSize uint64
+	for i := start; i < end; i += 12 {
+		entry := s.tiff[i : i+12]
+		switch id := s.order.Uint16(entry); {
+		case ifd == IFD1 && id == TagJPEGInterchangeFormat:
+			thumbOffset = uint64(s.order.Uint32(entry[8:]))
+		case ifd == IFD1 && id == TagJPEGInterchangeFormatLength:
+			thumbSize = uint64(s.order.Uint32(entry[8:]))
+		}
+		s.clearTag(entry)
+	}
+	if thumbSize > 0 {
+		s.clearRange(thumbOffset, thumbSize)
+	}
+	s.clearRange(uint64(offset), end-uint64(offset)+4)
+}
+
+// clearRange zeroes the bytes of the EXIF data in the range, clipped to the data.
+func (s *exifStripper) clearRange(offset, size uint64) {
+	if offset >= uint64(len(s.tiff)) {
+		return
+	}
+	if end := uint64(len(s.tiff)); offset+size > end || offset+size < offset {
+		size = end - offset
+	}
+	data := s.tiff[offset : offset+size]
+	for i := range data {
+		data[i] = 0
+	}
+}
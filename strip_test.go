@@ -0,0 +1,523 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// testXMPGPS is an XMP packet with GPS properties written as attributes and elements,
+// and copyright properties.
+const testXMPGPS = `<x:xmpmeta><rdf:Description exif:GPSLongitude="151,12.5E" dc:format="image/jpeg">` +
+	`<exif:GPSLatitude>33,51.6S</exif:GPSLatitude>` +
+	`<exif:GPSVersionID/>` +
+	`<dc:rights><rdf:Alt><rdf:li xml:lang="x-default">(c) XMP Rights</rdf:li></rdf:Alt></dc:rights>` +
+	`</rdf:Description></x:xmpmeta>`
+
+// testXMPGUID is the GUID of the extended XMP packets of the tests.
+const testXMPGUID = "0123456789ABCDEF0123456789ABCDEF"
+
+// makeExtendedXMPSegments returns the JPEG APP1 segments holding the extended XMP packet
+// split at the given offsets.
+func makeExtendedXMPSegments(packet string, splits ...int) []byte {
+	var segments []byte
+	offsets := append(append([]int{0}, splits...), len(packet))
+	for i := 0; i+1 < len(offsets); i++ {
+		payload := []byte(xmpExtensionHeader + testXMPGUID)
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(packet)))
+		payload = binary.BigEndian.AppendUint32(payload, uint32(offsets[i]))
+		payload = append(payload, packet[offsets[i]:offsets[i+1]]...)
+		segments = append(segments, 0xff, 0xe1)
+		segments = binary.BigEndian.AppendUint16(segments, uint16(len(payload)+2))
+		segments = append(segments, payload...)
+	}
+	return segments
+}
+
+// makeStripTestEXIF returns the EXIF data of a typical camera picture
+// with copyright, serial number and maker note tags.
+func makeStripTestEXIF(order binary.ByteOrder) []byte {
+	root := makeTestEXIF(order)
+	root.entries = append(root.entries, withID(TagCopyright, testASCII("(c) Jane Doe")))
+	exif := root.subs[TagExifIFDPointer]
+	exif.entries = append(exif.entries,
+		withID(TagBodySerialNumber, testASCII("SN-0123456789")),
+		withID(TagMakerNote, testIFDEntry{typ: EXIFUndefined, count: 12, data: []byte("private note")}),
+	)
+	// The thumbnail offset points to the value of the private tag 0xff08.
+	tiff := makeTestTIFF(order, root)
+	thumbnailOffset := bytes.Index(tiff, []byte("\xff\xd8thumbnail"))
+	root.next.entries[0] = withID(TagJPEGInterchangeFormat, testValues(order, EXIFLong, uint64(thumbnailOffset)))
+	return makeTestTIFF(order, root)
+}
+
+// makeStripTestJPEG returns a JPEG image with EXIF, ICC profile, XMP, IPTC and comment segments.
+func makeStripTestJPEG(t *testing.T, order binary.ByteOrder, xmp string) []byte {
+	t.Helper()
+	data := makeMetadataJPEG(t, &Metadata{
+		EXIF: makeStripTestEXIF(order),
+		ICC:  []byte("icc profile"),
+		XMP:  []byte(xmp),
+	})
+	var segments []byte
+	for _, s := range []struct {
+		marker  string
+		payload string
+	}{
+		{"\xff\xed", "Photoshop 3.0\x008BIM"},
+		{"\xff\xfe", "test comment\x00"},
+	} {
+		segments = append(segments, s.marker...)
+		segments = binary.BigEndian.AppendUint16(segments, uint16(len(s.payload)+2))
+		segments = append(segments, s.payload...)
+	}
+	// Extended XMP segment preceded by a fill byte.
+	segments = append(segments, 0xff)
+	segments = append(segments, makeExtendedXMPSegments(`<rdf:Description dc:source="ext"/>`)...)
+	return append(data[:2], append(segments, data[2:]...)...)
+}
+
+// jpegImageData returns the JPEG data from the SOS marker.
+func jpegImageData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	i := bytes.Index(data, []byte{0xff, 0xda})
+	if i < 0 {
+		t.Fatal("missing SOS marker")
+	}
+	return data[i:]
+}
+
+func TestStripMetadataJPEG(t *testing.T) {
+	t.Parallel()
+
+	const (
+		xmpGPS   = testXMPGPS
+		xmpNoGPS = "<x:xmpmeta><dc:rights>(c) Jane Doe</dc:rights></x:xmpmeta>"
+	)
+
+	testCases := []struct {
+		name   string
+		xmp    string
+		policy StripPolicy
+		// tags are the tags kept, nil if the EXIF data is removed.
+		tags []uint16
+		// keep are the markers of the segments kept.
+		keep []string
+		// removed are strings that must not be found in the output.
+		removed []string
+	}{
+		{
+			name:    "all",
+			xmp:     xmpNoGPS,
+			policy:  StripPolicy{Mode: StripAll},
+			removed: []string{"Exif\x00\x00", "Canon", "SN-0123456789", "Jane Doe", "Photoshop", "test comment", "ns.adobe.com"},
+		},
+		{
+			name:   "GPS",
+			xmp:    xmpGPS,
+			policy: StripPolicy{Mode: StripGPS},
+			tags: []uint16{
+				TagMake, TagModel, TagOrientation, TagCopyright, TagExposureTime,
+				TagBodySerialNumber, TagMakerNote, TagJPEGInterchangeFormat,
+			},
+			keep:    []string{"Photoshop 3.0", "test comment", "ICC_PROFILE", "(c) XMP Rights", `dc:format="image/jpeg"`},
+			removed: []string{"exif:GPS", "151,12.5E", "33,51.6S"},
+		},
+		{
+			name:    "GPS keeps XMP",
+			xmp:     xmpNoGPS,
+			policy:  StripPolicy{Mode: StripGPS},
+			tags:    []uint16{TagMake, TagCopyright, TagBodySerialNumber},
+			keep:    []string{xmpHeader, xmpNoGPS, xmpExtensionHeader},
+			removed: nil,
+		},
+		{
+			name:    "keep tags",
+			xmp:     xmpNoGPS,
+			policy:  StripPolicy{Mode: StripKeepTags, KeepTags: []uint16{TagCopyright, TagOrientation}},
+			tags:    []uint16{TagCopyright, TagOrientation},
+			keep:    []string{"ICC_PROFILE"},
+			removed: []string{"Canon", "SN-0123456789", "private note", "thumbnail", "R98", "Photoshop", "test comment", "ns.adobe.com"},
+		},
+		{
+			name: "keep Exif and GPS tags",
+			xmp:  xmpNoGPS,
+			policy: StripPolicy{
+				Mode:     StripKeepTags,
+				KeepTags: []uint16{TagDateTimeOriginal, TagGPSIFDPointer},
+			},
+			tags:    []uint16{TagExifIFDPointer, TagDateTimeOriginal, TagGPSIFDPointer, TagGPSLatitude},
+			removed: []string{"Canon", "SN-0123456789", "private note", "thumbnail", "R98", "Jane Doe"},
+		},
+		{
+			name:    "keep unknown tags",
+			xmp:     xmpNoGPS,
+			policy:  StripPolicy{Mode: StripKeepTags, KeepTags: []uint16{TagArtist}},
+			removed: []string{"Exif\x00\x00", "Canon", "SN-0123456789", "Jane Doe"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+				data := makeStripTestJPEG(t, order, tc.xmp)
+				buf := &bytes.Buffer{}
+				if err := StripMetadata(bytes.NewReader(data), buf, tc.policy); err != nil {
+					t.Fatalf("StripMetadata: %v", err)
+				}
+				out := buf.Bytes()
+
+				if !bytes.Equal(jpegImageData(t, out), jpegImageData(t, data)) {
+					t.Fatal("image data modified")
+				}
+				if _, err := Decode(bytes.NewReader(out)); err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				for _, s := range tc.keep {
+					if !bytes.Contains(out, []byte(s)) {
+						t.Fatalf("%q not found", s)
+					}
+				}
+				for _, s := range tc.removed {
+					if bytes.Contains(out, []byte(s)) {
+						t.Fatalf("%q not removed", s)
+					}
+				}
+				checkStrippedEXIF(t, out, tc.policy.Mode, tc.tags)
+			}
+		})
+	}
+}
+
+// checkStrippedEXIF checks that the EXIF data of the image has the tags. In the
+// StripKeepTags mode, the IFD0 and Exif IFD must have no other tags.
+// The GPS directory must be removed unless the GPS pointer tag is listed.
+func checkStrippedEXIF(t *testing.T, data []byte, mode StripMode, tags []uint16) {
+	t.Helper()
+
+	x, err := ReadEXIF(bytes.NewReader(data))
+	if tags == nil {
+		if !errors.Is(err, ErrNoEXIF) {
+			t.Fatalf("got error %v want %v", err, ErrNoEXIF)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("ReadEXIF: %v", err)
+	}
+	for _, id := range tags {
+		found := false
+		for _, tag := range x.Tags {
+			found = found || tag.ID == id
+		}
+		if !found {
+			t.Fatalf("tag 0x%04x not found", id)
+		}
+	}
+	keepGPS := false
+	for _, id := range tags {
+		keepGPS = keepGPS || id == TagGPSIFDPointer
+	}
+	if _, _, err := x.GPS(); (err == nil) != keepGPS {
+		t.Fatalf("got GPS error %v, GPS directory kept: %v", err, keepGPS)
+	}
+	if mode != StripKeepTags {
+		return
+	}
+	if _, err := x.Thumbnail(); err == nil {
+		t.Fatal("thumbnail not removed")
+	}
+	for _, tag := range x.Tags {
+		if tag.IFD != IFD0 && tag.IFD != ExifIFD {
+			continue
+		}
+		found := false
+		for _, id := range tags {
+			found = found || tag.ID == id
+		}
+		if !found {
+			t.Fatalf("tag 0x%04x of IFD %d not removed", tag.ID, tag.IFD)
+		}
+	}
+}
+
+func TestStripMetadataPNG(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	m := &Metadata{
+		EXIF: makeStripTestEXIF(binary.BigEndian),
+		ICC:  []byte("icc profile"),
+		XMP:  []byte(testXMPGPS),
+	}
+	if err := Encode(buf, New(2, 2, color.White), PNG, WithMetadata(m)); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	data := buf.Bytes()
+	const ihdrEnd = len(pngSignature) + 12 + 13
+	src := appendPNGChunk(append([]byte(nil), data[:ihdrEnd]...), "tEXt", []byte("Comment\x00test comment"))
+	src = appendPNGChunk(src, "iTXt", []byte("Comment\x00\x00\x00\x00\x00other comment"))
+	src = append(src, data[ihdrEnd:]...)
+
+	testCases := []struct {
+		name    string
+		policy  StripPolicy
+		tags    []uint16
+		keep    []string
+		removed []string
+	}{
+		{
+			name:    "all",
+			policy:  StripPolicy{Mode: StripAll},
+			keep:    []string{"iCCP"},
+			removed: []string{"eXIf", "iTXt", "tEXt", "Canon"},
+		},
+		{
+			name:    "GPS",
+			policy:  StripPolicy{Mode: StripGPS},
+			tags:    []uint16{TagMake, TagCopyright, TagBodySerialNumber},
+			keep:    []string{"iCCP", "eXIf", "test comment", "other comment", "(c) XMP Rights"},
+			removed: []string{"exif:GPS", "151,12.5E", "33,51.6S"},
+		},
+		{
+			name:    "keep tags",
+			policy:  StripPolicy{Mode: StripKeepTags, KeepTags: []uint16{TagCopyright}},
+			tags:    []uint16{TagCopyright},
+			keep:    []string{"iCCP", "eXIf"},
+			removed: []string{"iTXt", "tEXt", "Canon", "SN-0123456789"},
+		},
+	}
+
+	want, err := Decode(bytes.NewReader(src))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			if err := StripMetadata(bytes.NewReader(src), buf, tc.policy); err != nil {
+				t.Fatalf("StripMetadata: %v", err)
+			}
+			out := buf.Bytes()
+
+			img, err := Decode(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !compareNRGBA(Clone(img), Clone(want), 0) {
+				t.Fatal("image data modified")
+			}
+			for _, s := range tc.keep {
+				if !bytes.Contains(out, []byte(s)) {
+					t.Fatalf("%q not found", s)
+				}
+			}
+			for _, s := range tc.removed {
+				if bytes.Contains(out, []byte(s)) {
+					t.Fatalf("%q not removed", s)
+				}
+			}
+			checkStrippedEXIF(t, out, tc.policy.Mode, tc.tags)
+		})
+	}
+}
+
+func TestStripMetadataExtendedXMP(t *testing.T) {
+	t.Parallel()
+
+	const (
+		main = `<x:xmpmeta><rdf:Description xmpNote:HasExtendedXMP="` + testXMPGUID + `">` +
+			`<dc:rights>(c) Jane Doe</dc:rights></rdf:Description></x:xmpmeta>`
+		extGPS   = `<rdf:Description exif:GPSLatitude="33,51.6S" dc:source="extended"/>`
+		extNoGPS = `<rdf:Description dc:source="extended"/>`
+	)
+
+	testCases := []struct {
+		name    string
+		ext     string
+		splits  []int
+		keep    []string
+		removed []string
+	}{
+		{
+			name: "GPS split across segments",
+			ext:  extGPS,
+			// The property name is split after "exif:G".
+			splits:  []int{strings.Index(extGPS, "PSLatitude"), len(extGPS) - 10},
+			keep:    []string{"(c) Jane Doe"},
+			removed: []string{"PSLatitude", "33,51.6S", "extended", xmpExtensionHeader, "HasExtendedXMP"},
+		},
+		{
+			name:    "GPS in one segment",
+			ext:     extGPS,
+			keep:    []string{"(c) Jane Doe"},
+			removed: []string{"GPSLatitude", "extended", xmpExtensionHeader, "HasExtendedXMP"},
+		},
+		{
+			name:   "no GPS",
+			ext:    extNoGPS,
+			splits: []int{10, 20},
+			keep:   []string{"(c) Jane Doe", xmpExtensionHeader, `xmpNote:HasExtendedXMP="` + testXMPGUID + `"`},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := makeMetadataJPEG(t, &Metadata{XMP: []byte(main)})
+			ext := makeExtendedXMPSegments(tc.ext, tc.splits...)
+			data = append(data[:2], append(ext, data[2:]...)...)
+			buf := &bytes.Buffer{}
+			if err := StripMetadata(bytes.NewReader(data), buf, StripPolicy{Mode: StripGPS}); err != nil {
+				t.Fatalf("StripMetadata: %v", err)
+			}
+			out := buf.Bytes()
+			if _, err := Decode(bytes.NewReader(out)); err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			for _, s := range tc.keep {
+				if !bytes.Contains(out, []byte(s)) {
+					t.Fatalf("%q not found", s)
+				}
+			}
+			for _, s := range tc.removed {
+				if bytes.Contains(out, []byte(s)) {
+					t.Fatalf("%q not removed", s)
+				}
+			}
+			if len(tc.removed) == 0 && !bytes.Contains(out, ext) {
+				t.Fatal("extended XMP segments modified")
+			}
+		})
+	}
+}
+
+func TestExtendedXMPHasGPS(t *testing.T) {
+	t.Parallel()
+
+	segments := func(data []byte) []jpegSegment {
+		data = append([]byte{0xff, 0xd8}, append(data, 0xff, 0xd9)...)
+		s, _, err := readJPEGSegments(data)
+		if err != nil {
+			t.Fatalf("readJPEGSegments: %v", err)
+		}
+		return s
+	}
+	// The first of two segments, holding the first 10 bytes of the packet.
+	incomplete := makeExtendedXMPSegments("<dc:source>ext</dc:source>", 10)
+	incomplete = incomplete[:4+len(xmpExtensionHeader)+40+10]
+
+	testCases := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"none", nil, false},
+		{"no GPS", makeExtendedXMPSegments("<dc:source>ext</dc:source>", 5, 12), false},
+		{"split GPS", makeExtendedXMPSegments("<exif:GPSAltitude>1</exif:GPSAltitude>", 8), true},
+		{"short segment", []byte("\xff\xe1\x00\x2a" + xmpExtensionHeader + "short"), true},
+		{"incomplete packet", incomplete, true},
+	}
+	for _, tc := range testCases {
+		if got := extendedXMPHasGPS(segments(tc.data)); got != tc.want {
+			t.Fatalf("%s: got %v want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestStripMetadataErrors(t *testing.T) {
+	t.Parallel()
+
+	gifData := &bytes.Buffer{}
+	if err := Encode(gifData, New(2, 2, color.White), GIF); err != nil {
+		t.Fatalf("failed to encode GIF: %v", err)
+	}
+	invalidEXIF := makeMetadataJPEG(t, &Metadata{})
+	invalidEXIF = append(invalidEXIF[:2], append([]byte("\xff\xe1\x00\x0eExif\x00\x00MM\x00\x2a"), invalidEXIF[2:]...)...)
+
+	testCases := []struct {
+		name   string
+		data   string
+		policy StripPolicy
+	}{
+		{"invalid data", "invalid data", StripPolicy{}},
+		{"unsupported format", gifData.String(), StripPolicy{}},
+		{"truncated JPEG", "\xff\xd8\xff", StripPolicy{}},
+		{"invalid JPEG segment size", "\xff\xd8\xff\xe0\x00\x01", StripPolicy{}},
+		{"truncated JPEG segment", "\xff\xd8\xff\xe0\x00\x10\x00", StripPolicy{}},
+		{"truncated PNG", pngSignature + "\x00\x00", StripPolicy{}},
+		{"invalid EXIF", string(invalidEXIF), StripPolicy{Mode: StripGPS}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := StripMetadata(strings.NewReader(tc.data), &bytes.Buffer{}, tc.policy); err == nil {
+				t.Fatal("expected error got nil")
+			}
+		})
+	}
+}
+
+func TestStripXMPGPS(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		xmp  string
+		want string
+	}{
+		{
+			name: "attributes",
+			xmp:  `<rdf:Description exif:GPSLatitude="33,51.6S"  exif:GPSLongitude = '151,12.5E' dc:format="image/png"/>`,
+			want: `<rdf:Description dc:format="image/png"/>`,
+		},
+		{
+			name: "elements",
+			xmp: "<rdf:Description>\n <exif:GPSAltitude>10/1</exif:GPSAltitude>\n <exif:GPSVersionID />\n" +
+				" <exif:GPSTimeStamp rdf:parseType=\"Resource\"><rdf:value>x</rdf:value></exif:GPSTimeStamp>\n" +
+				" <dc:rights>(c) Jane Doe</dc:rights>\n</rdf:Description>",
+			want: "<rdf:Description>\n <dc:rights>(c) Jane Doe</dc:rights>\n</rdf:Description>",
+		},
+		{
+			name: "no GPS",
+			xmp:  "<dc:creator>Jane Doe</dc:creator>",
+			want: "<dc:creator>Jane Doe</dc:creator>",
+		},
+		{
+			name: "unterminated element",
+			xmp:  "<exif:GPSAltitude>10/1<dc:rights>(c) Jane Doe</dc:rights>",
+			want: "",
+		},
+	}
+	for _, tc := range testCases {
+		got := xmpGPSProperty.remove([]byte(tc.xmp))
+		if tc.want == "" && got != nil {
+			t.Fatalf("%s: got %q want nil", tc.name, got)
+		}
+		if string(got) != tc.want {
+			t.Fatalf("%s: got %q want %q", tc.name, got, tc.want)
+		}
+	}
+
+	xmp := "<rdf:Description><xmpNote:HasExtendedXMP>" + testXMPGUID + "</xmpNote:HasExtendedXMP>" +
+		"<dc:format>image/jpeg</dc:format></rdf:Description>"
+	want := "<rdf:Description><dc:format>image/jpeg</dc:format></rdf:Description>"
+	if got := xmpExtendedProperty.remove([]byte(xmp)); string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
@@ -27,13 +27,22 @@ func New(width, height int, fillColor color.Color) *image.NRGBA {
 
 // Clone returns a copy of the given image.
 func Clone(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	cropInto(dst, img, dst.Rect)
+	return dst
+}
+
+// Clone16 returns a copy of the given image with 16 bits per channel.
+// Unlike Clone, it preserves the precision of 16-bit images.
+func Clone16(img image.Image) *image.NRGBA64 {
 	src := newScanner(img)
-	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
-	size := src.w * 4
+	dst := image.NewNRGBA64(image.Rect(0, 0, src.w, src.h))
+	size := src.w * 8
 	parallel(0, src.h, func(ys <-chan int) {
 		for y := range ys {
 			i := y * dst.Stride
-			src.scan(0, y, src.w, y+1, dst.Pix[i:i+size])
+			src.scan16(0, y, src.w, y+1, dst.Pix[i:i+size])
 		}
 	})
 	return dst
@@ -55,6 +64,50 @@ const (
 	BottomRight
 )
 
+// regionPos returns the top-left corner of a w x h region placed within the bounds b.
+type regionPos func(b image.Rectangle, w, h int) image.Point
+
+// anchorPos returns a regionPos that places the region at the anchor point.
+func anchorPos(anchor Anchor) regionPos {
+	return func(b image.Rectangle, w, h int) image.Point {
+		return anchorPt(b, w, h, anchor)
+	}
+}
+
+// focalPos returns a regionPos that places the region around the fractional focal point (x, y).
+// The coordinates are clamped to the range [0, 1], NaN is treated as 0.5.
+func focalPos(x, y float64) regionPos {
+	x, y = clampFocal(x), clampFocal(y)
+	return func(b image.Rectangle, w, h int) image.Point {
+		return image.Pt(b.Min.X+focalOffset(b.Dx(), w, x), b.Min.Y+focalOffset(b.Dy(), h, y))
+	}
+}
+
+// clampFocal clamps a fractional focal point coordinate to the range [0, 1].
+func clampFocal(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0.5
+	}
+	return math.Max(0, math.Min(1, v))
+}
+
+// focalOffset returns the offset of a region of the specified size within the size of the image
+// that puts the fractional focal point as close to the center of the region as possible.
+func focalOffset(size, regionSize int, f float64) int {
+	if regionSize >= size {
+		return (size - regionSize) / 2
+	}
+	// Round to the nearest integer, halves down as with the Center anchor.
+	offset := int(math.Ceil(f*float64(size) - float64(regionSize)/2 - 0.5))
+	if offset < 0 {
+		return 0
+	}
+	if offset > size-regionSize {
+		return size - regionSize
+	}
+	return offset
+}
+
 func anchorPt(b image.Rectangle, w, h int, anchor Anchor) image.Point {
 	var x, y int
 	switch anchor {
@@ -100,8 +153,40 @@ func Crop(img image.Image, rect image.Rectangle) *image.NRGBA {
 		return Clone(img)
 	}
 
-	src := newScanner(img)
 	dst := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	cropInto(dst, img, r)
+	return dst
+}
+
+// CropInto is like Crop but stores the cropped region in the top-left corner of dst
+// instead of allocating a new image. The region is clipped to the size of dst.
+// It returns the sub-image of dst holding the result.
+//
+// Example:
+//
+//	buf := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+//	dstImage := imaging.CropInto(buf, srcImage, image.Rect(50, 50, 150, 150))
+func CropInto(dst *image.NRGBA, img image.Image, rect image.Rectangle) *image.NRGBA {
+	r := rect.Intersect(img.Bounds()).Sub(img.Bounds().Min)
+	if r.Dx() > dst.Rect.Dx() {
+		r.Max.X = r.Min.X + dst.Rect.Dx()
+	}
+	if r.Dy() > dst.Rect.Dy() {
+		r.Max.Y = r.Min.Y + dst.Rect.Dy()
+	}
+	if r.Empty() {
+		return &image.NRGBA{}
+	}
+
+	out := dst.SubImage(image.Rect(0, 0, r.Dx(), r.Dy()).Add(dst.Rect.Min)).(*image.NRGBA)
+	cropInto(out, img, r)
+	return out
+}
+
+// cropInto scans the region r of the image, in coordinates relative to the image bounds,
+// into dst, which must have the size of the region.
+func cropInto(dst *image.NRGBA, img image.Image, r image.Rectangle) {
+	src := newScanner(img)
 	rowSize := r.Dx() * 4
 	parallel(r.Min.Y, r.Max.Y, func(ys <-chan int) {
 		for y := range ys {
@@ -109,17 +194,64 @@ func Crop(img image.Image, rect image.Rectangle) *image.NRGBA {
 			src.scan(r.Min.X, y, r.Max.X, y+1, dst.Pix[i:i+rowSize])
 		}
 	})
-	return dst
 }
 
 // CropAnchor cuts out a rectangular region with the specified size
 // from the image using the specified anchor point and returns the cropped image.
 func CropAnchor(img image.Image, width, height int, anchor Anchor) *image.NRGBA {
-	srcBounds := img.Bounds()
-	pt := anchorPt(srcBounds, width, height, anchor)
-	r := image.Rect(0, 0, width, height).Add(pt)
-	b := srcBounds.Intersect(r)
-	return Crop(img, b)
+	return Crop(img, cropRegion(img.Bounds(), width, height, anchorPos(anchor)))
+}
+
+// CropFocal cuts out a rectangular region with the specified size from the image around
+// the fractional focal point (x, y) and returns the cropped image. (0, 0) is the top-left
+// corner and (1, 1) is the bottom-right corner of the image. The region is placed to keep
+// the focal point as close to its center as the image bounds allow.
+//
+// Example:
+//
+//	// Keep the face at 30% from the left and 20% from the top of the photo.
+//	dstImage := imaging.CropFocal(srcImage, 200, 200, 0.3, 0.2)
+func CropFocal(img image.Image, width, height int, x, y float64) *image.NRGBA {
+	return Crop(img, cropRegion(img.Bounds(), width, height, focalPos(x, y)))
+}
+
+// cropRegion returns the width x height region placed within the bounds b by pos,
+// clipped to the bounds.
+func cropRegion(b image.Rectangle, width, height int, pos regionPos) image.Rectangle {
+	r := image.Rect(0, 0, width, height).Add(pos(b, width, height))
+	return b.Intersect(r)
+}
+
+// Crop16 is like Crop but returns an image with 16 bits per channel.
+func Crop16(img image.Image, rect image.Rectangle) *image.NRGBA64 {
+	r := rect.Intersect(img.Bounds()).Sub(img.Bounds().Min)
+	if r.Empty() {
+		return &image.NRGBA64{}
+	}
+	if r.Eq(img.Bounds().Sub(img.Bounds().Min)) {
+		return Clone16(img)
+	}
+
+	src := newScanner(img)
+	dst := image.NewNRGBA64(image.Rect(0, 0, r.Dx(), r.Dy()))
+	rowSize := r.Dx() * 8
+	parallel(r.Min.Y, r.Max.Y, func(ys <-chan int) {
+		for y := range ys {
+			i := (y - r.Min.Y) * dst.Stride
+			src.scan16(r.Min.X, y, r.Max.X, y+1, dst.Pix[i:i+rowSize])
+		}
+	})
+	return dst
+}
+
+// CropAnchor16 is like CropAnchor but returns an image with 16 bits per channel.
+func CropAnchor16(img image.Image, width, height int, anchor Anchor) *image.NRGBA64 {
+	return Crop16(img, cropRegion(img.Bounds(), width, height, anchorPos(anchor)))
+}
+
+// CropFocal16 is like CropFocal but returns an image with 16 bits per channel.
+func CropFocal16(img image.Image, width, height int, x, y float64) *image.NRGBA64 {
+	return Crop16(img, cropRegion(img.Bounds(), width, height, focalPos(x, y)))
 }
 
 // CropCenter cuts out a rectangular region with the specified size
@@ -173,6 +305,142 @@ func PasteCenter(background, img image.Image) *image.NRGBA {
 	return Paste(background, img, image.Pt(x0, y0))
 }
 
+// edgeKind is the way EdgeMode fills the pixels outside the image.
+type edgeKind int
+
+const (
+	edgeConstant edgeKind = iota
+	edgeClamp
+	edgeReflect
+	edgeWrap
+)
+
+// EdgeMode specifies how the pixels outside of the image bounds are filled by ExtendCanvas.
+// The zero value fills them with transparent pixels.
+type EdgeMode struct {
+	kind  edgeKind
+	color color.NRGBA
+}
+
+// Edge modes.
+var (
+	// EdgeClamp repeats the pixels on the edges of the image: aaa|abcd|ddd.
+	EdgeClamp = EdgeMode{kind: edgeClamp}
+	// EdgeReflect mirrors the image at its edges: cba|abcd|dcb.
+	EdgeReflect = EdgeMode{kind: edgeReflect}
+	// EdgeWrap repeats the image as a tile: bcd|abcd|abc.
+	EdgeWrap = EdgeMode{kind: edgeWrap}
+)
+
+// EdgeConstant returns an EdgeMode that fills the pixels outside of the image with the specified color.
+func EdgeConstant(c color.Color) EdgeMode {
+	return EdgeMode{
+		kind:  edgeConstant,
+		color: color.NRGBAModel.Convert(c).(color.NRGBA),
+	}
+}
+
+// edgeIndex maps the index i of a pixel outside of a row or column of n pixels to the index
+// of the pixel providing its color, or -1 for the constant mode.
+func edgeIndex(i, n int, kind edgeKind) int {
+	if i >= 0 && i < n {
+		return i
+	}
+	switch kind {
+	case edgeClamp:
+		if i < 0 {
+			return 0
+		}
+		return n - 1
+	case edgeReflect:
+		i %= 2 * n
+		if i < 0 {
+			i += 2 * n
+		}
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+		return i
+	case edgeWrap:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+		return i
+	}
+	return -1
+}
+
+// ExtendCanvas adds the specified number of pixels to the top, right, bottom and left sides
+// of the image and returns the extended image. The added pixels are filled according to
+// the edge mode. Negative sizes are treated as 0.
+//
+// Extending the image before filtering it, for example with Convolve3x3, and cropping the result
+// afterwards controls how the pixels near the edges are filtered.
+//
+// Examples:
+//
+//	// Add a 10 pixel white border.
+//	dstImage := imaging.ExtendCanvas(srcImage, 10, 10, 10, 10, imaging.EdgeConstant(color.White))
+//
+//	// Mirror the image by 2 pixels before applying a 5x5 kernel.
+//	tmp := imaging.ExtendCanvas(srcImage, 2, 2, 2, 2, imaging.EdgeReflect)
+//	tmp = imaging.Convolve5x5(tmp, kernel, nil)
+//	dstImage := imaging.Crop(tmp, image.Rect(2, 2, tmp.Bounds().Dx()-2, tmp.Bounds().Dy()-2))
+func ExtendCanvas(img image.Image, top, right, bottom, left int, mode EdgeMode) *image.NRGBA {
+	src := newScanner(img)
+	if src.w <= 0 || src.h <= 0 {
+		return &image.NRGBA{}
+	}
+	if top < 0 {
+		top = 0
+	}
+	if right < 0 {
+		right = 0
+	}
+	if bottom < 0 {
+		bottom = 0
+	}
+	if left < 0 {
+		left = 0
+	}
+
+	dstW := src.w + left + right
+	dstH := src.h + top + bottom
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	fill := []uint8{mode.color.R, mode.color.G, mode.color.B, mode.color.A}
+	parallel(0, dstH, func(ys <-chan int) {
+		for y := range ys {
+			row := dst.Pix[y*dst.Stride : y*dst.Stride+dstW*4]
+			srcY := edgeIndex(y-top, src.h, mode.kind)
+			if srcY < 0 {
+				for i := 0; i < len(row); i += 4 {
+					copy(row[i:i+4], fill)
+				}
+				continue
+			}
+
+			// Scan the source row into the middle, then fill the sides from it.
+			src.scan(0, srcY, src.w, srcY+1, row[left*4:(left+src.w)*4])
+			fillSide := func(x1, x2 int) {
+				for x := x1; x < x2; x++ {
+					i := x * 4
+					srcX := edgeIndex(x-left, src.w, mode.kind)
+					if srcX < 0 {
+						copy(row[i:i+4], fill)
+						continue
+					}
+					j := (left + srcX) * 4
+					copy(row[i:i+4], row[j:j+4])
+				}
+			}
+			fillSide(0, left)
+			fillSide(left+src.w, dstW)
+		}
+	})
+	return dst
+}
+
 // Overlay draws the img image over the background image at given position
 // and returns the combined image. Opacity parameter is the opacity of the img
 // image layer, used to compose the images, it must be from 0.0 to 1.0.
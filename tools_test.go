@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"image"
 	"image/color"
+	"math"
 	"testing"
 )
 
@@ -1211,3 +1212,281 @@ func TestOverlayCenter(t *testing.T) {
 		})
 	}
 }
+
+func TestClone16(t *testing.T) {
+	t.Parallel()
+
+	src := makeGradient16(20, 10)
+	got := Clone16(src)
+	if !compareNRGBA64(got, src, 0) {
+		t.Fatal("Clone16 of *image.NRGBA64 does not match the source image")
+	}
+	if &got.Pix[0] == &src.Pix[0] {
+		t.Fatal("Clone16 returned an image sharing pixels with the source image")
+	}
+
+	sub := src.SubImage(image.Rect(5, 2, 15, 8))
+	want := Crop16(src, image.Rect(5, 2, 15, 8))
+	if got := Clone16(sub); !compareNRGBA64(got, want, 0) || got.Rect != image.Rect(0, 0, 10, 6) {
+		t.Fatal("Clone16 of a sub-image does not match the cropped image")
+	}
+
+	got8 := Clone(Clone16(testdataBranchesPNG))
+	if !compareNRGBA(got8, Clone(testdataBranchesPNG), 0) {
+		t.Fatal("Clone16 of an 8-bit image lost precision")
+	}
+}
+
+func TestCrop16(t *testing.T) {
+	t.Parallel()
+
+	src := makeGradient16(20, 10)
+	got := Crop16(src, image.Rect(2, 3, 12, 20))
+	if got.Rect != image.Rect(0, 0, 10, 7) {
+		t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, 10, 7))
+	}
+	for y := 0; y < 7; y++ {
+		for x := 0; x < 10; x++ {
+			if got.NRGBA64At(x, y) != src.NRGBA64At(x+2, y+3) {
+				t.Fatalf("(%d, %d): got %v want %v", x, y, got.NRGBA64At(x, y), src.NRGBA64At(x+2, y+3))
+			}
+		}
+	}
+
+	if got := Crop16(src, image.Rect(30, 30, 40, 40)); got.Rect != (image.Rectangle{}) {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+
+	rect := image.Rect(10, 20, 60, 50)
+	want := Crop(testdataBranchesPNG, rect)
+	if !compareNRGBA(Clone(Crop16(testdataBranchesPNG, rect)), want, 0) {
+		t.Fatal("Crop16 does not match Crop")
+	}
+	for _, anchor := range []Anchor{Center, TopLeft, Bottom, BottomRight} {
+		want := CropAnchor(testdataBranchesPNG, 30, 20, anchor)
+		if !compareNRGBA(Clone(CropAnchor16(testdataBranchesPNG, 30, 20, anchor)), want, 0) {
+			t.Fatalf("CropAnchor16 does not match CropAnchor for anchor %d", anchor)
+		}
+	}
+}
+
+func TestCropInto(t *testing.T) {
+	t.Parallel()
+
+	for _, rect := range []image.Rectangle{
+		image.Rect(10, 20, 60, 50),
+		image.Rect(-10, -10, 30, 30),
+		image.Rect(500, 300, 700, 500),
+		image.Rect(0, 0, 600, 400),
+	} {
+		want := Crop(testdataBranchesPNG, rect)
+		buf := image.NewNRGBA(image.Rect(3, 4, 703, 504))
+		got := CropInto(buf, testdataBranchesPNG, rect)
+		if got.Rect != want.Rect.Add(buf.Rect.Min) {
+			t.Fatalf("CropInto(%v): got bounds %v want %v", rect, got.Rect, want.Rect.Add(buf.Rect.Min))
+		}
+		if !compareNRGBA(Clone(got), want, 0) {
+			t.Fatalf("CropInto(%v) does not match Crop", rect)
+		}
+	}
+
+	// The region is clipped to the size of dst.
+	buf := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+	got := CropInto(buf, testdataBranchesPNG, image.Rect(10, 20, 60, 50))
+	if !compareNRGBA(got, Crop(testdataBranchesPNG, image.Rect(10, 20, 30, 30)), 0) {
+		t.Fatal("clipped CropInto does not match Crop")
+	}
+
+	if got := CropInto(buf, testdataBranchesPNG, image.Rect(700, 700, 800, 800)); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
+
+func TestClampFocal(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		v, want float64
+	}{
+		{0, 0},
+		{1, 1},
+		{0.25, 0.25},
+		{-1, 0},
+		{2, 1},
+		{math.NaN(), 0.5},
+	} {
+		if got := clampFocal(tc.v); got != tc.want {
+			t.Fatalf("clampFocal(%v): got %v want %v", tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestCropFocal(t *testing.T) {
+	t.Parallel()
+
+	b := image.Rect(-10, 20, 190, 120)
+	testCases := []struct {
+		name   string
+		w, h   int
+		x, y   float64
+		want   image.Point
+		anchor Anchor
+	}{
+		{"Center", 100, 50, 0.5, 0.5, image.Pt(40, 45), Center},
+		{"TopLeft", 100, 50, 0, 0, image.Pt(-10, 20), TopLeft},
+		{"BottomRight", 100, 50, 1, 1, image.Pt(90, 70), BottomRight},
+		{"Inside", 40, 20, 0.3, 0.6, image.Pt(30, 70), -1},
+		{"ClampedLeft", 100, 50, 0.1, 0.5, image.Pt(-10, 45), -1},
+		{"ClampedBottom", 100, 50, 0.5, 0.9, image.Pt(40, 70), -1},
+		{"Larger", 300, 50, 0.1, 0.2, image.Pt(-60, 20), -1},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := focalPos(tc.x, tc.y)(b, tc.w, tc.h)
+			if got != tc.want {
+				t.Fatalf("got %v want %v", got, tc.want)
+			}
+			if tc.anchor >= 0 {
+				if want := anchorPt(b, tc.w, tc.h, tc.anchor); got != want {
+					t.Fatalf("got %v want %v as with anchor %d", got, want, tc.anchor)
+				}
+			}
+		})
+	}
+
+	img := testdataBranchesPNG
+	want := Crop(img, image.Rect(300, 100, 400, 200))
+	if got := CropFocal(img, 100, 100, 350.0/600, 150.0/400); !compareNRGBA(got, want, 0) {
+		t.Fatal("CropFocal does not crop around the focal point")
+	}
+	if got := CropFocal16(img, 100, 100, 350.0/600, 150.0/400); !compareNRGBA(Clone(got), want, 0) {
+		t.Fatal("CropFocal16 does not crop around the focal point")
+	}
+}
+
+func TestEdgeIndex(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		kind edgeKind
+		want []int // Indexes from -6 to 8 for a row of 3 pixels.
+	}{
+		{"Constant", edgeConstant, []int{-1, -1, -1, -1, -1, -1, 0, 1, 2, -1, -1, -1, -1, -1, -1}},
+		{"Clamp", edgeClamp, []int{0, 0, 0, 0, 0, 0, 0, 1, 2, 2, 2, 2, 2, 2, 2}},
+		{"Reflect", edgeReflect, []int{0, 1, 2, 2, 1, 0, 0, 1, 2, 2, 1, 0, 0, 1, 2}},
+		{"Wrap", edgeWrap, []int{0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2}},
+	}
+	for _, tc := range testCases {
+		for i, want := range tc.want {
+			if got := edgeIndex(i-6, 3, tc.kind); got != want {
+				t.Fatalf("%s: edgeIndex(%d): got %d want %d", tc.name, i-6, got, want)
+			}
+		}
+	}
+}
+
+func TestExtendCanvas(t *testing.T) {
+	t.Parallel()
+
+	// A 2x2 image with the pixels a, b on the first row and c, d on the second.
+	src := &image.NRGBA{
+		Rect:   image.Rect(-1, -1, 1, 1),
+		Stride: 2 * 4,
+		Pix: []uint8{
+			0x01, 0x01, 0x01, 0xff, 0x02, 0x02, 0x02, 0xff,
+			0x03, 0x03, 0x03, 0xff, 0x04, 0x04, 0x04, 0xff,
+		},
+	}
+	// makeImage returns a w x h image with the gray values of the pixels, 0 being the fill color.
+	makeImage := func(w, h int, values ...uint8) *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for i, v := range values {
+			c := color.NRGBA{v, v, v, 0xff}
+			if v == 0 {
+				c = color.NRGBA{0xff, 0, 0, 0x80}
+			}
+			img.SetNRGBA(i%w, i/w, c)
+		}
+		return img
+	}
+
+	testCases := []struct {
+		name                     string
+		top, right, bottom, left int
+		mode                     EdgeMode
+		want                     *image.NRGBA
+	}{
+		{
+			"Constant", 1, 2, 0, 1, EdgeConstant(color.NRGBA{0xff, 0, 0, 0x80}),
+			makeImage(5, 3,
+				0, 0, 0, 0, 0,
+				0, 1, 2, 0, 0,
+				0, 3, 4, 0, 0,
+			),
+		},
+		{
+			"Clamp", 1, 1, 2, 0, EdgeClamp,
+			makeImage(3, 5,
+				1, 2, 2,
+				1, 2, 2,
+				3, 4, 4,
+				3, 4, 4,
+				3, 4, 4,
+			),
+		},
+		{
+			"Reflect", 0, 3, 1, 2, EdgeReflect,
+			makeImage(7, 3,
+				2, 1, 1, 2, 2, 1, 1,
+				4, 3, 3, 4, 4, 3, 3,
+				4, 3, 3, 4, 4, 3, 3,
+			),
+		},
+		{
+			"Wrap", 3, 1, 0, 1, EdgeWrap,
+			makeImage(4, 5,
+				4, 3, 4, 3,
+				2, 1, 2, 1,
+				4, 3, 4, 3,
+				2, 1, 2, 1,
+				4, 3, 4, 3,
+			),
+		},
+		{
+			"Negative", -1, 0, -5, 1, EdgeClamp,
+			makeImage(3, 2,
+				1, 1, 2,
+				3, 3, 4,
+			),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := ExtendCanvas(src, tc.top, tc.right, tc.bottom, tc.left, tc.mode)
+			if !compareNRGBA(got, tc.want, 0) {
+				t.Fatalf("got result %#v want %#v", got, tc.want)
+			}
+		})
+	}
+
+	got := ExtendCanvas(src, 1, 1, 1, 1, EdgeMode{})
+	if got.NRGBAAt(0, 0) != (color.NRGBA{}) || got.NRGBAAt(1, 1) != (color.NRGBA{1, 1, 1, 0xff}) {
+		t.Fatal("zero EdgeMode does not fill with transparent pixels")
+	}
+	if got := ExtendCanvas(&image.NRGBA{}, 1, 1, 1, 1, EdgeClamp); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+
+	// Filtering the extended image and cropping the result.
+	img := testdataFlowersSmallPNG
+	kernel := [9]float64{0, 0, 0, 0, 1, 0, 0, 0, 0}
+	tmp := Convolve3x3(ExtendCanvas(img, 1, 1, 1, 1, EdgeReflect), kernel, nil)
+	if !compareNRGBA(Crop(tmp, image.Rect(1, 1, 241, 161)), Clone(img), 0) {
+		t.Fatal("extended and cropped image does not match the image")
+	}
+}
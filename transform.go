@@ -266,3 +266,127 @@ func interpolatePoint(dst *image.NRGBA, dstX, dstY int, src *image.NRGBA, xf, yf
 		d[3] = clamp(a)
 	}
 }
+
+// Rotate16 is like Rotate but processes the image with 16 bits per channel and returns
+// an *image.NRGBA64, preserving the precision of 16-bit images.
+func Rotate16(img image.Image, angle float64, bgColor color.Color) *image.NRGBA64 {
+	angle = angle - math.Floor(angle/360)*360
+
+	switch angle {
+	case 0:
+		return Clone16(img)
+	case 90, 180, 270:
+		return rotateRightAngle16(img, int(angle))
+	}
+
+	src := toNRGBA64(img)
+	srcW := src.Bounds().Max.X
+	srcH := src.Bounds().Max.Y
+	dstW, dstH := rotatedSize(srcW, srcH, angle)
+	dst := image.NewNRGBA64(image.Rect(0, 0, dstW, dstH))
+
+	if dstW <= 0 || dstH <= 0 {
+		return dst
+	}
+
+	srcXOff := float64(srcW)/2 - 0.5
+	srcYOff := float64(srcH)/2 - 0.5
+	dstXOff := float64(dstW)/2 - 0.5
+	dstYOff := float64(dstH)/2 - 0.5
+
+	bgColorNRGBA64 := color.NRGBA64Model.Convert(bgColor).(color.NRGBA64)
+	sin, cos := math.Sincos(math.Pi * angle / 180)
+
+	parallel(0, dstH, func(ys <-chan int) {
+		for dstY := range ys {
+			for dstX := 0; dstX < dstW; dstX++ {
+				xf, yf := rotatePoint(float64(dstX)-dstXOff, float64(dstY)-dstYOff, sin, cos)
+				xf, yf = xf+srcXOff, yf+srcYOff
+				interpolatePoint16(dst, dstX, dstY, src, xf, yf, bgColorNRGBA64)
+			}
+		}
+	})
+
+	return dst
+}
+
+// rotateRightAngle16 rotates the image by 90, 180 or 270 degrees counter-clockwise
+// with 16 bits per channel.
+func rotateRightAngle16(img image.Image, angle int) *image.NRGBA64 {
+	src := toNRGBA64(img)
+	srcW := src.Bounds().Dx()
+	srcH := src.Bounds().Dy()
+	dstW, dstH := srcW, srcH
+	if angle != 180 {
+		dstW, dstH = srcH, srcW
+	}
+	dst := image.NewNRGBA64(image.Rect(0, 0, dstW, dstH))
+	parallel(0, dstH, func(ys <-chan int) {
+		for dstY := range ys {
+			j := dstY * dst.Stride
+			for dstX := 0; dstX < dstW; dstX++ {
+				var srcX, srcY int
+				switch angle {
+				case 90:
+					srcX, srcY = srcW-1-dstY, dstX
+				case 180:
+					srcX, srcY = srcW-1-dstX, srcH-1-dstY
+				default:
+					srcX, srcY = dstY, srcH-1-dstX
+				}
+				i := srcY*src.Stride + srcX*8
+				copy(dst.Pix[j:j+8], src.Pix[i:i+8])
+				j += 8
+			}
+		}
+	})
+	return dst
+}
+
+func interpolatePoint16(dst *image.NRGBA64, dstX, dstY int, src *image.NRGBA64, xf, yf float64, bgColor color.NRGBA64) {
+	j := dstY*dst.Stride + dstX*8
+	d := dst.Pix[j : j+8 : j+8]
+
+	x0 := int(math.Floor(xf))
+	y0 := int(math.Floor(yf))
+	bounds := src.Bounds()
+	if !image.Pt(x0, y0).In(image.Rect(bounds.Min.X-1, bounds.Min.Y-1, bounds.Max.X, bounds.Max.Y)) {
+		setPixel16(d, bgColor.R, bgColor.G, bgColor.B, bgColor.A)
+		return
+	}
+
+	xq := xf - float64(x0)
+	yq := yf - float64(y0)
+	points := [4]image.Point{
+		{x0, y0},
+		{x0 + 1, y0},
+		{x0, y0 + 1},
+		{x0 + 1, y0 + 1},
+	}
+	weights := [4]float64{
+		(1 - xq) * (1 - yq),
+		xq * (1 - yq),
+		(1 - xq) * yq,
+		xq * yq,
+	}
+
+	var r, g, b, a float64
+	for i := 0; i < 4; i++ {
+		p := points[i]
+		w := weights[i]
+		c := bgColor
+		if p.In(bounds) {
+			i := p.Y*src.Stride + p.X*8
+			c.R, c.G, c.B, c.A = pixel16(src.Pix[i : i+8 : i+8])
+		}
+		wa := float64(c.A) * w
+		r += float64(c.R) * wa
+		g += float64(c.G) * wa
+		b += float64(c.B) * wa
+		a += wa
+	}
+	if a != 0 {
+		aInv := 1 / a
+		setPixel16(d, clamp16(r*aInv), clamp16(g*aInv), clamp16(b*aInv), clamp16(a))
+	}
+}
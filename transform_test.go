@@ -675,3 +675,27 @@ func BenchmarkRotate(b *testing.B) {
 		Rotate(testdataBranchesJPG, 30, color.Transparent)
 	}
 }
+
+func TestRotate16(t *testing.T) {
+	t.Parallel()
+
+	for _, angle := range []float64{0, 90, 180, 270, -90, 30, 135.5, 359} {
+		got := Clone(Rotate16(testdataFlowersSmallPNG, angle, color.NRGBA{0, 0x80, 0, 0x80}))
+		want := Rotate(testdataFlowersSmallPNG, angle, color.NRGBA{0, 0x80, 0, 0x80})
+		if !compareNRGBA(got, want, 1) {
+			t.Fatalf("Rotate16(%v) does not match Rotate", angle)
+		}
+	}
+
+	src := makeGradient16(64, 32)
+	got := Rotate16(src, 90, color.Transparent)
+	if got.Rect != image.Rect(0, 0, 32, 64) {
+		t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, 32, 64))
+	}
+	if got.NRGBA64At(0, 0) != src.NRGBA64At(63, 0) {
+		t.Fatalf("got %v want %v", got.NRGBA64At(0, 0), src.NRGBA64At(63, 0))
+	}
+	if levels := countLevels16(Rotate16(src, 10, color.Black)); levels < 200 {
+		t.Fatalf("got %d levels of the 16-bit gradient, want at least 200", levels)
+	}
+}
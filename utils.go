@@ -3,6 +3,7 @@ package imaging
 import (
 	"image"
 	"math"
+	"math/bits"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -10,6 +11,17 @@ import (
 
 var maxProcs int64
 
+// poolClasses is the number of size classes of the buffer pools.
+const poolClasses = 48
+
+var (
+	// pixPools reuse the pixel buffers of scanlines and intermediate images.
+	// The pool of class c holds buffers with a capacity of at least 1<<c.
+	pixPools [poolClasses]sync.Pool //nolint
+	// floatPools reuse the buffers of floating-point scanlines, classed like pixPools.
+	floatPools [poolClasses]sync.Pool //nolint
+)
+
 // SetMaxProcs limits the number of concurrent processing goroutines to the given value.
 // A value <= 0 clears the limit.
 func SetMaxProcs(value int) {
@@ -49,6 +61,80 @@ func parallel(start, stop int, fn func(<-chan int)) {
 	wg.Wait()
 }
 
+// poolClass returns the size class of the pooled buffers a buffer of length n is taken from,
+// so that any buffer of the class fits it.
+func poolClass(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return bits.Len(uint(n - 1))
+}
+
+// putClass returns the size class a buffer of capacity c is put back to, or -1 if it's
+// too small or too large to be pooled.
+func putClass(c int) int {
+	class := bits.Len(uint(c)) - 1
+	if class >= poolClasses {
+		return -1
+	}
+	return class
+}
+
+// getPix returns a pixel buffer of length n, reusing a buffer from the pool if possible.
+// The buffer isn't zeroed.
+func getPix(n int) []uint8 {
+	class := poolClass(n)
+	if class >= poolClasses {
+		return make([]uint8, n)
+	}
+	if p, ok := pixPools[class].Get().(*[]uint8); ok {
+		return (*p)[:n]
+	}
+	return make([]uint8, n, 1<<class)
+}
+
+// putPix puts the pixel buffer back to the pool of its size class.
+func putPix(pix []uint8) {
+	if class := putClass(cap(pix)); class >= 0 {
+		pixPools[class].Put(&pix)
+	}
+}
+
+// getFloats returns a float64 buffer of length n, reusing a buffer from the pool if possible.
+// The buffer isn't zeroed.
+func getFloats(n int) []float64 {
+	class := poolClass(n)
+	if class >= poolClasses {
+		return make([]float64, n)
+	}
+	if p, ok := floatPools[class].Get().(*[]float64); ok {
+		return (*p)[:n]
+	}
+	return make([]float64, n, 1<<class)
+}
+
+// putFloats puts the float64 buffer back to the pool of its size class.
+func putFloats(buf []float64) {
+	if class := putClass(cap(buf)); class >= 0 {
+		floatPools[class].Put(&buf)
+	}
+}
+
+// getNRGBA returns an intermediate image with the given size and a pixel buffer from the pool.
+// The pixels aren't zeroed. The image must be released with putNRGBA.
+func getNRGBA(width, height int) *image.NRGBA {
+	return &image.NRGBA{
+		Pix:    getPix(width * height * 4),
+		Stride: width * 4,
+		Rect:   image.Rect(0, 0, width, height),
+	}
+}
+
+// putNRGBA puts the pixel buffer of the intermediate image back to the pool.
+func putNRGBA(img *image.NRGBA) {
+	putPix(img.Pix)
+}
+
 // absInt returns the absolute value of i.
 func absInt(i int) int {
 	if i < 0 {
@@ -69,6 +155,41 @@ func clamp(x float64) uint8 {
 	return uint8(v)
 }
 
+// clamp16 rounds and clamps float64 value to fit into uint16.
+func clamp16(x float64) uint16 {
+	v := int64(x + 0.5)
+	if v > 0xffff {
+		v = 0xffff
+	}
+	if v < 0 {
+		v = 0
+	}
+	return uint16(v)
+}
+
+// pixel16 returns the color channels of the pixel s stored in the layout of image.NRGBA64.Pix.
+func pixel16(s []uint8) (r, g, b, a uint16) {
+	s = s[:8:8]
+	r = uint16(s[0])<<8 | uint16(s[1])
+	g = uint16(s[2])<<8 | uint16(s[3])
+	b = uint16(s[4])<<8 | uint16(s[5])
+	a = uint16(s[6])<<8 | uint16(s[7])
+	return r, g, b, a
+}
+
+// setPixel16 stores the color channels into the pixel d in the layout of image.NRGBA64.Pix.
+func setPixel16(d []uint8, r, g, b, a uint16) {
+	d = d[:8:8]
+	d[0] = uint8(r >> 8)
+	d[1] = uint8(r)
+	d[2] = uint8(g >> 8)
+	d[3] = uint8(g)
+	d[4] = uint8(b >> 8)
+	d[5] = uint8(b)
+	d[6] = uint8(a >> 8)
+	d[7] = uint8(a)
+}
+
 // reverse reverses the order of pixels in the given slice.
 func reverse(pix []uint8) {
 	length := len(pix)
@@ -100,12 +221,30 @@ func toNRGBA(img image.Image) *image.NRGBA {
 	return Clone(img)
 }
 
+// toNRGBA64 convert image.Image to *image.NRGBA64.
+func toNRGBA64(img image.Image) *image.NRGBA64 {
+	if img, ok := img.(*image.NRGBA64); ok {
+		return &image.NRGBA64{
+			Pix:    img.Pix,
+			Stride: img.Stride,
+			Rect:   img.Rect.Sub(img.Rect.Min),
+		}
+	}
+	return Clone16(img)
+}
+
 // rgbToHSL converts a color from RGB to HSL.
 func rgbToHSL(r, g, b uint8) (h float64, s float64, l float64) {
-	rr := float64(r) / 255
-	gg := float64(g) / 255
-	bb := float64(b) / 255
+	return rgbToHSLFloat(float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// rgb16ToHSL converts a 16-bit color from RGB to HSL.
+func rgb16ToHSL(r, g, b uint16) (h float64, s float64, l float64) {
+	return rgbToHSLFloat(float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff)
+}
 
+// rgbToHSLFloat converts a color from RGB with channels in the range [0, 1] to HSL.
+func rgbToHSLFloat(rr, gg, bb float64) (h float64, s float64, l float64) {
 	max := math.Max(rr, math.Max(gg, bb))
 	min := math.Min(rr, math.Min(gg, bb))
 	l = (max + min) / 2
@@ -139,10 +278,20 @@ func rgbToHSL(r, g, b uint8) (h float64, s float64, l float64) {
 
 // hslToRGB converts a color from HSL to RGB.
 func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
-	var r, g, b float64
+	r, g, b := hslToRGBFloat(h, s, l)
+	return clamp(r * 255), clamp(g * 255), clamp(b * 255)
+}
+
+// hslToRGB16 converts a color from HSL to 16-bit RGB.
+func hslToRGB16(h, s, l float64) (uint16, uint16, uint16) {
+	r, g, b := hslToRGBFloat(h, s, l)
+	return clamp16(r * 0xffff), clamp16(g * 0xffff), clamp16(b * 0xffff)
+}
+
+// hslToRGBFloat converts a color from HSL to RGB with channels in the range [0, 1].
+func hslToRGBFloat(h, s, l float64) (float64, float64, float64) {
 	if s == 0 {
-		v := clamp(l * 255)
-		return v, v, v
+		return l, l, l
 	}
 
 	var q float64
@@ -153,11 +302,11 @@ func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
 	}
 	p := 2*l - q
 
-	r = hueToRGB(p, q, h+1.0/3.0)
-	g = hueToRGB(p, q, h)
-	b = hueToRGB(p, q, h-1.0/3.0)
+	r := hueToRGB(p, q, h+1.0/3.0)
+	g := hueToRGB(p, q, h)
+	b := hueToRGB(p, q, h-1.0/3.0)
 
-	return clamp(r * 255), clamp(g * 255), clamp(b * 255)
+	return r, g, b
 }
 
 // hueToRGB converts hue to RGB. Helper function for hslToRGB.
@@ -2,6 +2,7 @@ package imaging
 
 import (
 	"image"
+	"image/color"
 	"math"
 	"runtime"
 	"sync/atomic"
@@ -117,6 +118,47 @@ func TestClamp(t *testing.T) {
 	}
 }
 
+func TestClamp16(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		f float64
+		u uint16
+	}{
+		{0, 0},
+		{65535, 65535},
+		{32768, 32768},
+		{0.49, 0},
+		{0.50, 1},
+		{65534.9, 65535},
+		{65534.0, 65534},
+		{65536, 65535},
+		{1e6, 65535},
+		{-10, 0},
+	}
+
+	for _, tc := range testCases {
+		if clamp16(tc.f) != tc.u {
+			t.Fatalf("test [clamp16 %v %v] failed: %v", tc.f, tc.u, clamp16(tc.f))
+		}
+	}
+}
+
+func TestPixel16(t *testing.T) {
+	t.Parallel()
+
+	d := make([]uint8, 8)
+	setPixel16(d, 0x0102, 0x0304, 0xfffe, 0x8000)
+	want := []uint8{0x01, 0x02, 0x03, 0x04, 0xff, 0xfe, 0x80, 0x00}
+	if !compareBytes(d, want, 0) {
+		t.Fatalf("got %v want %v", d, want)
+	}
+	r, g, b, a := pixel16(d)
+	if r != 0x0102 || g != 0x0304 || b != 0xfffe || a != 0x8000 {
+		t.Fatalf("got (%#x, %#x, %#x, %#x)", r, g, b, a)
+	}
+}
+
 func TestReverse(t *testing.T) {
 	t.Parallel()
 
@@ -161,6 +203,13 @@ func compareNRGBA(img1, img2 *image.NRGBA, delta int) bool {
 	return compareBytes(img1.Pix, img2.Pix, delta)
 }
 
+func compareNRGBA64(img1, img2 *image.NRGBA64, delta int) bool {
+	if !img1.Rect.Eq(img2.Rect) {
+		return false
+	}
+	return compareBytes16(img1.Pix, img2.Pix, delta)
+}
+
 func compareBytes(a, b []uint8, delta int) bool {
 	if len(a) != len(b) {
 		return false
@@ -354,3 +403,124 @@ func TestHSLToRGB(t *testing.T) {
 		})
 	}
 }
+
+func TestRGB16ToHSL(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range rgbHSLTestCases {
+		h, s, l := rgb16ToHSL(uint16(tc.r)*0x101, uint16(tc.g)*0x101, uint16(tc.b)*0x101)
+		if !compareFloat64(h, tc.h, 0.001) || !compareFloat64(s, tc.s, 0.001) || !compareFloat64(l, tc.l, 0.001) {
+			t.Fatalf("(%d, %d, %d): got (%.3f, %.3f, %.3f) want (%.3f, %.3f, %.3f)", tc.r, tc.g, tc.b, h, s, l, tc.h, tc.s, tc.l)
+		}
+	}
+}
+
+func TestHSLToRGB16(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range rgbHSLTestCases {
+		r, g, b := hslToRGB16(tc.h, tc.s, tc.l)
+		for i, v := range [][2]uint16{{r, uint16(tc.r) * 0x101}, {g, uint16(tc.g) * 0x101}, {b, uint16(tc.b) * 0x101}} {
+			if absInt(int(v[0])-int(v[1])) > 0x101 {
+				t.Fatalf("(%.3f, %.3f, %.3f): channel %d: got %d want %d", tc.h, tc.s, tc.l, i, v[0], v[1])
+			}
+		}
+	}
+}
+
+// makeGradient16 returns an opaque horizontal gradient with 16-bit steps, too fine for 8-bit precision.
+func makeGradient16(w, h int) *image.NRGBA64 {
+	img := image.NewNRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint16(0x4000 + x*16)
+			img.SetNRGBA64(x, y, color.NRGBA64{v, v, 0xffff - v, 0xffff})
+		}
+	}
+	return img
+}
+
+// countLevels16 returns the number of distinct values of the red channel of the image.
+func countLevels16(img *image.NRGBA64) int {
+	levels := map[uint16]bool{}
+	for i := 0; i+8 <= len(img.Pix); i += 8 {
+		r, _, _, _ := pixel16(img.Pix[i:])
+		levels[r] = true
+	}
+	return len(levels)
+}
+
+func TestGetPix(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 10, 100, 5} {
+		pix := getPix(n)
+		if len(pix) != n {
+			t.Fatalf("got length %d want %d", len(pix), n)
+		}
+		putPix(pix)
+
+		buf := getFloats(n)
+		if len(buf) != n {
+			t.Fatalf("got length %d want %d", len(buf), n)
+		}
+		putFloats(buf)
+	}
+
+	img := getNRGBA(3, 2)
+	if img.Rect != image.Rect(0, 0, 3, 2) || img.Stride != 12 || len(img.Pix) != 24 {
+		t.Fatalf("got image %v with stride %d and %d bytes", img.Rect, img.Stride, len(img.Pix))
+	}
+	putNRGBA(img)
+}
+
+func TestPoolClass(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		n, get, put int
+	}{
+		{0, 0, -1},
+		{1, 0, 0},
+		{2, 1, 1},
+		{3, 2, 1},
+		{4, 2, 2},
+		{100, 7, 6},
+		{128, 7, 7},
+		{129, 8, 7},
+	}
+	for _, tc := range testCases {
+		if got := poolClass(tc.n); got != tc.get {
+			t.Errorf("poolClass(%d): got %d want %d", tc.n, got, tc.get)
+		}
+		if got := putClass(tc.n); got != tc.put {
+			t.Errorf("putClass(%d): got %d want %d", tc.n, got, tc.put)
+		}
+	}
+
+	// A buffer put back to a class fits any length taken from the class.
+	for c := 1; c < 1000; c++ {
+		if class := putClass(c); class >= 0 && 1<<class > c {
+			t.Fatalf("capacity %d put to class %d", c, class)
+		}
+	}
+	for n := 1; n < 1000; n++ {
+		pix := getPix(n)
+		if cap(pix) < 1<<poolClass(n) {
+			t.Fatalf("got capacity %d for length %d", cap(pix), n)
+		}
+		putPix(pix)
+	}
+}
+
+func BenchmarkGetPix(b *testing.B) {
+	// The intermediate image and a scanline of a 1000x800 image are taken and put back
+	// in turns. A single pool hands out the scanline buffer for the image.
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		img := getNRGBA(1000, 800)
+		scanLine := getPix(1000 * 4)
+		putPix(scanLine)
+		putNRGBA(img)
+	}
+}
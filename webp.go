@@ -0,0 +1,1034 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// This file implements a lossless WebP (VP8L) encoder.
+// The bitstream format is described in the WebP lossless bitstream specification:
+// https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification
+
+const (
+	// vp8lSignature is the first byte of every VP8L bitstream.
+	vp8lSignature = 0x2f
+	// vp8lMaxDimension is the maximum width and height of a VP8L image.
+	vp8lMaxDimension = 1 << 14
+
+	// vp8lNumLiterals, vp8lNumLengthCodes and vp8lNumDistanceCodes are the
+	// alphabet sizes of the prefix codes used for the entropy-coded image.
+	vp8lNumLiterals      = 256
+	vp8lNumLengthCodes   = 24
+	vp8lNumDistanceCodes = 40
+	// vp8lNumCodeLengthCodes is the alphabet size of the code length code.
+	vp8lNumCodeLengthCodes = 19
+
+	// vp8lMaxCodeLength is the maximum length of a prefix code.
+	vp8lMaxCodeLength = 15
+	// vp8lMaxCodeLengthCodeLength is the maximum length of a code in the code length code.
+	vp8lMaxCodeLengthCodeLength = 7
+
+	// vp8lMinMatch is the shortest backward reference the encoder emits.
+	vp8lMinMatch = 3
+	// vp8lMaxMatch is the longest backward reference allowed by the format.
+	vp8lMaxMatch = 4096
+	// vp8lWindowSize is the maximum backward reference distance allowed by the format.
+	vp8lWindowSize = 1<<20 - 120
+	// vp8lHashBits is the size of the hash table used to find backward references.
+	vp8lHashBits = 16
+
+	// vp8lMaxCacheBits is the largest color cache the encoder tries.
+	vp8lMaxCacheBits = 10
+	// vp8lColorCacheMultiplier is the multiplier of the color cache hash function.
+	vp8lColorCacheMultiplier = 0x1e35a7bd
+
+	// Transform types.
+	vp8lTransformPredictor     = 0
+	vp8lTransformSubtractGreen = 2
+	vp8lTransformColorIndexing = 3
+
+	// vp8lNumPredictors is the number of predictor modes of the predictor transform.
+	vp8lNumPredictors = 14
+)
+
+// vp8lCodeLengthCodeOrder is the order in which the code length code lengths are stored.
+var vp8lCodeLengthCodeOrder = [vp8lNumCodeLengthCodes]int{ //nolint
+	17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// vp8lDistanceMap lists the two-dimensional offsets of the 120 short distance codes.
+// Each entry is yOffset<<4 | (8 - xOffset).
+var vp8lDistanceMap = [120]uint8{ //nolint
+	0x18, 0x07, 0x17, 0x19, 0x28, 0x06, 0x27, 0x29, 0x16, 0x1a,
+	0x26, 0x2a, 0x38, 0x05, 0x37, 0x39, 0x15, 0x1b, 0x36, 0x3a,
+	0x25, 0x2b, 0x48, 0x04, 0x47, 0x49, 0x14, 0x1c, 0x35, 0x3b,
+	0x46, 0x4a, 0x24, 0x2c, 0x58, 0x45, 0x4b, 0x34, 0x3c, 0x03,
+	0x57, 0x59, 0x13, 0x1d, 0x56, 0x5a, 0x23, 0x2d, 0x44, 0x4c,
+	0x55, 0x5b, 0x33, 0x3d, 0x68, 0x02, 0x67, 0x69, 0x12, 0x1e,
+	0x66, 0x6a, 0x22, 0x2e, 0x54, 0x5c, 0x43, 0x4d, 0x65, 0x6b,
+	0x32, 0x3e, 0x78, 0x01, 0x77, 0x79, 0x53, 0x5d, 0x11, 0x1f,
+	0x64, 0x6c, 0x42, 0x4e, 0x76, 0x7a, 0x21, 0x2f, 0x75, 0x7b,
+	0x31, 0x3f, 0x63, 0x6d, 0x52, 0x5e, 0x00, 0x74, 0x7c, 0x41,
+	0x4f, 0x10, 0x20, 0x62, 0x6e, 0x30, 0x73, 0x7d, 0x51, 0x5f,
+	0x40, 0x72, 0x7e, 0x61, 0x6f, 0x50, 0x71, 0x7f, 0x60, 0x70,
+}
+
+// encodeWebP writes the image img to w as a lossless WebP image.
+// The effort parameter (0-9) trades encoding speed for output size.
+// If exact is false, the color values of fully transparent pixels are
+// not preserved, which usually produces smaller files.
+func encodeWebP(w io.Writer, img image.Image, effort int, exact bool) error {
+	src := toNRGBA(img)
+	width := src.Rect.Dx()
+	height := src.Rect.Dy()
+	if width <= 0 || height <= 0 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return fmt.Errorf("imaging: invalid WebP image size: %dx%d", width, height)
+	}
+
+	argb := make([]uint32, width*height)
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		i := y * src.Stride
+		for x := 0; x < width; x++ {
+			s := src.Pix[i : i+4 : i+4]
+			a := uint32(s[3])
+			if a != 0xff {
+				hasAlpha = true
+			}
+			if a == 0 && !exact {
+				argb[y*width+x] = 0
+			} else {
+				argb[y*width+x] = a<<24 | uint32(s[0])<<16 | uint32(s[1])<<8 | uint32(s[2])
+			}
+			i += 4
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.writeBits(vp8lSignature, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3) // version
+
+	newVP8LEncoder(effort).encode(bw, argb, width, height)
+	data := bw.bytes()
+
+	padding := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(12+len(data)+padding))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padding != 0 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bitWriter writes bits in the least-significant-bit-first order used by VP8L.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nBits uint
+}
+
+// writeBits writes the n least significant bits of v.
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v&(1<<n-1)) << w.nBits
+	w.nBits += n
+	for w.nBits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nBits -= 8
+	}
+}
+
+// bytes flushes the pending bits and returns the written data.
+func (w *bitWriter) bytes() []byte {
+	if w.nBits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc = 0
+		w.nBits = 0
+	}
+	return w.buf
+}
+
+// vp8lEncoder holds the effort-dependent parameters of the VP8L encoder.
+type vp8lEncoder struct {
+	// maxChain is the maximum number of hash chain candidates examined per pixel.
+	maxChain int
+	// cacheBits lists the color cache sizes to try (0 disables the cache).
+	cacheBits []int
+	// predictorBits is the log2 tile size of the predictor transform, or 0 to disable it.
+	predictorBits int
+	// predictorModes lists the predictor modes to try for each tile.
+	predictorModes []int
+	// palette enables the color indexing transform for images with at most 256 colors.
+	palette bool
+}
+
+// newVP8LEncoder returns an encoder configured for the given effort level.
+func newVP8LEncoder(effort int) *vp8lEncoder {
+	if effort < 0 {
+		effort = 0
+	}
+	if effort > 9 {
+		effort = 9
+	}
+
+	e := &vp8lEncoder{}
+	if effort > 0 {
+		e.maxChain = 1 << uint(effort)
+		e.palette = true
+		e.predictorBits = 5
+		e.predictorModes = []int{1, 2, 11}
+	}
+	switch {
+	case effort == 0:
+		e.cacheBits = []int{0}
+	case effort < 5:
+		e.cacheBits = []int{0, vp8lMaxCacheBits}
+	default:
+		for i := 0; i <= vp8lMaxCacheBits; i++ {
+			e.cacheBits = append(e.cacheBits, i)
+		}
+	}
+	if effort >= 3 {
+		e.predictorModes = e.predictorModes[:0]
+		for mode := 0; mode < vp8lNumPredictors; mode++ {
+			e.predictorModes = append(e.predictorModes, mode)
+		}
+	}
+	if effort >= 5 {
+		e.predictorBits = 4
+	}
+	return e
+}
+
+// encode applies the transforms and writes the image data of the top-level image.
+func (e *vp8lEncoder) encode(w *bitWriter, argb []uint32, width, height int) {
+	if e.palette {
+		if palette, ok := vp8lPalette(argb); ok {
+			argb, width = e.writeColorIndexing(w, argb, width, height, palette)
+			w.writeBits(0, 1) // no more transforms
+			e.writeImageStream(w, argb, width, true)
+			return
+		}
+	}
+
+	w.writeBits(1, 1)
+	w.writeBits(vp8lTransformSubtractGreen, 2)
+	for i, c := range argb {
+		g := (c >> 8) & 0xff
+		argb[i] = c&0xff00ff00 | ((c>>16&0xff-g)&0xff)<<16 | (c-g)&0xff
+	}
+
+	if e.predictorBits > 0 {
+		argb = e.writePredictor(w, argb, width, height)
+	}
+
+	w.writeBits(0, 1) // no more transforms
+	e.writeImageStream(w, argb, width, true)
+}
+
+// vp8lPalette returns the sorted list of distinct colors of the image
+// if there are at most 256 of them.
+func vp8lPalette(argb []uint32) ([]uint32, bool) {
+	seen := make(map[uint32]struct{}, 256)
+	for _, c := range argb {
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		if len(seen) == 256 {
+			return nil, false
+		}
+		seen[c] = struct{}{}
+	}
+	palette := make([]uint32, 0, len(seen))
+	for c := range seen {
+		palette = append(palette, c)
+	}
+	sort.Slice(palette, func(i, j int) bool { return palette[i] < palette[j] })
+	return palette, true
+}
+
+// writeColorIndexing writes the color indexing transform and returns
+// the packed index image and its width.
+func (e *vp8lEncoder) writeColorIndexing(w *bitWriter, argb []uint32, width, height int, palette []uint32) ([]uint32, int) {
+	w.writeBits(1, 1)
+	w.writeBits(vp8lTransformColorIndexing, 2)
+	w.writeBits(uint32(len(palette)-1), 8)
+
+	// The palette is stored delta-coded.
+	deltas := make([]uint32, len(palette))
+	deltas[0] = palette[0]
+	for i := 1; i < len(palette); i++ {
+		deltas[i] = vp8lSubPixels(palette[i], palette[i-1])
+	}
+	e.writeImageStream(w, deltas, len(palette), false)
+
+	index := make(map[uint32]uint32, len(palette))
+	for i, c := range palette {
+		index[c] = uint32(i)
+	}
+
+	var xBits uint
+	switch {
+	case len(palette) <= 2:
+		xBits = 3
+	case len(palette) <= 4:
+		xBits = 2
+	case len(palette) <= 16:
+		xBits = 1
+	}
+	bitsPerPixel := 8 >> xBits
+	packedWidth := (width + 1<<xBits - 1) >> xBits
+	packed := make([]uint32, packedWidth*height)
+	for y := 0; y < height; y++ {
+		row := packed[y*packedWidth : (y+1)*packedWidth]
+		for x := 0; x < width; x++ {
+			shift := uint(bitsPerPixel * (x & (1<<xBits - 1)))
+			row[x>>xBits] |= index[argb[y*width+x]] << shift
+		}
+		for i, v := range row {
+			row[i] = 0xff000000 | v<<8
+		}
+	}
+	return packed, packedWidth
+}
+
+// writePredictor chooses a predictor mode for every tile, writes the predictor
+// transform and returns the residuals.
+func (e *vp8lEncoder) writePredictor(w *bitWriter, argb []uint32, width, height int) []uint32 {
+	tileBits := e.predictorBits
+	tileSize := 1 << uint(tileBits)
+	tilesX := (width + tileSize - 1) >> uint(tileBits)
+	tilesY := (height + tileSize - 1) >> uint(tileBits)
+	modes := make([]uint32, tilesX*tilesY)
+	residuals := make([]uint32, len(argb))
+
+	parallel(0, tilesY, func(ys <-chan int) {
+		for ty := range ys {
+			for tx := 0; tx < tilesX; tx++ {
+				x0, y0 := tx*tileSize, ty*tileSize
+				x1, y1 := x0+tileSize, y0+tileSize
+				if x1 > width {
+					x1 = width
+				}
+				if y1 > height {
+					y1 = height
+				}
+
+				bestMode, bestCost := e.predictorModes[0], -1
+				for _, mode := range e.predictorModes {
+					cost := 0
+					for y := y0; y < y1; y++ {
+						for x := x0; x < x1; x++ {
+							i := y*width + x
+							cost += vp8lResidualCost(vp8lSubPixels(argb[i], vp8lPredict(argb, i, x, y, width, mode)))
+						}
+					}
+					if bestCost < 0 || cost < bestCost {
+						bestMode, bestCost = mode, cost
+					}
+				}
+
+				modes[ty*tilesX+tx] = 0xff000000 | uint32(bestMode)<<8
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						i := y*width + x
+						residuals[i] = vp8lSubPixels(argb[i], vp8lPredict(argb, i, x, y, width, bestMode))
+					}
+				}
+			}
+		}
+	})
+
+	w.writeBits(1, 1)
+	w.writeBits(vp8lTransformPredictor, 2)
+	w.writeBits(uint32(tileBits-2), 3)
+	e.writeImageStream(w, modes, tilesX, false)
+	return residuals
+}
+
+// vp8lResidualCost estimates the cost of coding a residual as the sum of the
+// magnitudes of its signed channel values.
+func vp8lResidualCost(c uint32) int {
+	cost := 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		v := int(int8(c >> shift))
+		if v < 0 {
+			v = -v
+		}
+		cost += v
+	}
+	return cost
+}
+
+// vp8lPredict returns the prediction of the pixel at index i (column x, row y)
+// using the given predictor mode. The first row and column use the fixed
+// predictors mandated by the format.
+func vp8lPredict(argb []uint32, i, x, y, width, mode int) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[i-1]
+	case x == 0:
+		return argb[i-width]
+	}
+
+	l := argb[i-1]
+	t := argb[i-width]
+	tl := argb[i-width-1]
+	// For the rightmost pixel, the top-right pixel is the leftmost pixel of the current row.
+	tr := argb[i-width+1]
+
+	switch mode {
+	case 0:
+		return 0xff000000
+	case 1:
+		return l
+	case 2:
+		return t
+	case 3:
+		return tr
+	case 4:
+		return tl
+	case 5:
+		return vp8lAverage2(vp8lAverage2(l, tr), t)
+	case 6:
+		return vp8lAverage2(l, tl)
+	case 7:
+		return vp8lAverage2(l, t)
+	case 8:
+		return vp8lAverage2(tl, t)
+	case 9:
+		return vp8lAverage2(t, tr)
+	case 10:
+		return vp8lAverage2(vp8lAverage2(l, tl), vp8lAverage2(t, tr))
+	case 11:
+		return vp8lSelect(l, t, tl)
+	case 12:
+		return vp8lClampAddSubtractFull(l, t, tl)
+	default:
+		return vp8lClampAddSubtractHalf(vp8lAverage2(l, t), tl)
+	}
+}
+
+// vp8lAverage2 returns the per-channel average of two pixels, rounded down.
+func vp8lAverage2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+// vp8lSelect implements the Select predictor.
+func vp8lSelect(l, t, tl uint32) uint32 {
+	pl, pt := 0, 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		cl := int(l >> shift & 0xff)
+		ct := int(t >> shift & 0xff)
+		ctl := int(tl >> shift & 0xff)
+		pl += absInt(ct - ctl)
+		pt += absInt(cl - ctl)
+	}
+	if pl < pt {
+		return l
+	}
+	return t
+}
+
+// vp8lClampAddSubtractFull implements the ClampAddSubtractFull predictor.
+func vp8lClampAddSubtractFull(a, b, c uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		v := int(a>>shift&0xff) + int(b>>shift&0xff) - int(c>>shift&0xff)
+		out |= vp8lClampChannel(v) << shift
+	}
+	return out
+}
+
+// vp8lClampAddSubtractHalf implements the ClampAddSubtractHalf predictor.
+func vp8lClampAddSubtractHalf(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		ca := int(a >> shift & 0xff)
+		cb := int(b >> shift & 0xff)
+		out |= vp8lClampChannel(ca+(ca-cb)/2) << shift
+	}
+	return out
+}
+
+// vp8lClampChannel clamps v to the range of a color channel.
+func vp8lClampChannel(v int) uint32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint32(v)
+}
+
+// vp8lSubPixels subtracts the pixel b from a per channel, modulo 256.
+func vp8lSubPixels(a, b uint32) uint32 {
+	alphaAndGreen := 0x00ff00ff + (a & 0xff00ff00) - (b & 0xff00ff00)
+	redAndBlue := 0xff00ff00 + (a & 0x00ff00ff) - (b & 0x00ff00ff)
+	return alphaAndGreen&0xff00ff00 | redAndBlue&0x00ff00ff
+}
+
+// vp8lToken kinds.
+const (
+	vp8lTokenLiteral = iota
+	vp8lTokenCache
+	vp8lTokenCopy
+)
+
+// vp8lToken is an element of the entropy-coded image: a literal pixel,
+// a color cache index or a backward reference.
+type vp8lToken struct {
+	kind uint8
+	// value is the pixel of a literal, the index of a color cache hit,
+	// or the length of a backward reference.
+	value uint32
+	// dist is the distance code of a backward reference.
+	dist uint32
+}
+
+// writeImageStream writes the entropy-coded image argb of the given width.
+// Only the top-level image may use meta prefix codes, which this encoder never does.
+func (e *vp8lEncoder) writeImageStream(w *bitWriter, argb []uint32, width int, topLevel bool) {
+	refs := e.backwardRefs(argb, width)
+
+	cacheBits, bestCost := 0, math.Inf(1)
+	for _, b := range e.cacheBits {
+		tokens := vp8lApplyCache(refs, argb, b)
+		if cost := vp8lEstimateCost(vp8lHistograms(tokens, b)); cost < bestCost {
+			cacheBits, bestCost = b, cost
+		}
+	}
+	tokens := vp8lApplyCache(refs, argb, cacheBits)
+
+	if cacheBits > 0 {
+		w.writeBits(1, 1)
+		w.writeBits(uint32(cacheBits), 4)
+	} else {
+		w.writeBits(0, 1)
+	}
+	if topLevel {
+		w.writeBits(0, 1) // single prefix code group
+	}
+
+	var codes [5]*huffmanCode
+	for i, h := range vp8lHistograms(tokens, cacheBits) {
+		codes[i] = newHuffmanCode(h, vp8lMaxCodeLength)
+		writeHuffmanCode(w, codes[i])
+	}
+
+	for _, t := range tokens {
+		switch t.kind {
+		case vp8lTokenLiteral:
+			codes[0].write(w, int(t.value>>8&0xff))
+			codes[1].write(w, int(t.value>>16&0xff))
+			codes[2].write(w, int(t.value&0xff))
+			codes[3].write(w, int(t.value>>24))
+		case vp8lTokenCache:
+			codes[0].write(w, vp8lNumLiterals+vp8lNumLengthCodes+int(t.value))
+		case vp8lTokenCopy:
+			symbol, nBits, extra := vp8lPrefixEncode(t.value)
+			codes[0].write(w, vp8lNumLiterals+symbol)
+			w.writeBits(extra, nBits)
+			symbol, nBits, extra = vp8lPrefixEncode(t.dist)
+			codes[4].write(w, symbol)
+			w.writeBits(extra, nBits)
+		}
+	}
+}
+
+// backwardRefs splits argb into literals and LZ77 backward references using hash chains.
+func (e *vp8lEncoder) backwardRefs(argb []uint32, width int) []vp8lToken {
+	n := len(argb)
+	distCodes := vp8lDistanceCodes(width)
+	tokens := make([]vp8lToken, 0, n/2+1)
+
+	var head, chain []int32
+	if e.maxChain > 0 {
+		head = make([]int32, 1<<vp8lHashBits)
+		for i := range head {
+			head[i] = -1
+		}
+		chain = make([]int32, n)
+	}
+	hash := func(i int) uint32 {
+		return (argb[i]*vp8lColorCacheMultiplier ^ argb[i+1]*0x9e3779b1) >> (32 - vp8lHashBits)
+	}
+	insert := func(i int) {
+		if head != nil && i+1 < n {
+			h := hash(i)
+			chain[i] = head[h]
+			head[h] = int32(i)
+		}
+	}
+
+	for i := 0; i < n; {
+		maxLen := n - i
+		if maxLen > vp8lMaxMatch {
+			maxLen = vp8lMaxMatch
+		}
+
+		bestLen, bestDist := 0, 0
+		if maxLen >= vp8lMinMatch {
+			// The pixel on the left and the pixel above are the most likely matches.
+			for _, d := range [2]int{1, width} {
+				if d <= i {
+					if l := vp8lMatchLength(argb, i-d, i, maxLen); l > bestLen {
+						bestLen, bestDist = l, d
+					}
+				}
+			}
+			if head != nil && bestLen < maxLen {
+				cand := head[hash(i)]
+				for steps := 0; cand >= 0 && steps < e.maxChain; steps++ {
+					d := i - int(cand)
+					if d > vp8lWindowSize {
+						break
+					}
+					if l := vp8lMatchLength(argb, int(cand), i, maxLen); l > bestLen {
+						bestLen, bestDist = l, d
+						if l == maxLen {
+							break
+						}
+					}
+					cand = chain[cand]
+				}
+			}
+		}
+
+		if bestLen >= vp8lMinMatch {
+			dist := uint32(bestDist + 120)
+			if bestDist < len(distCodes) && distCodes[bestDist] != 0 {
+				dist = uint32(distCodes[bestDist])
+			}
+			tokens = append(tokens, vp8lToken{kind: vp8lTokenCopy, value: uint32(bestLen), dist: dist})
+			for k := 0; k < bestLen; k++ {
+				insert(i + k)
+			}
+			i += bestLen
+			continue
+		}
+
+		tokens = append(tokens, vp8lToken{kind: vp8lTokenLiteral, value: argb[i]})
+		insert(i)
+		i++
+	}
+	return tokens
+}
+
+// vp8lMatchLength returns the number of equal pixels (up to maxLen) starting at indices a and b.
+func vp8lMatchLength(argb []uint32, a, b, maxLen int) int {
+	n := 0
+	for n < maxLen && argb[a+n] == argb[b+n] {
+		n++
+	}
+	return n
+}
+
+// vp8lDistanceCodes returns a table mapping small backward reference distances
+// to the short distance codes of the given image width. Zero means that the
+// distance has no short code.
+func vp8lDistanceCodes(width int) []uint16 {
+	codes := make([]uint16, 8*width+9)
+	for i := len(vp8lDistanceMap) - 1; i >= 0; i-- {
+		c := vp8lDistanceMap[i]
+		d := int(c>>4)*width + 8 - int(c&0xf)
+		if d < 1 {
+			d = 1
+		}
+		codes[d] = uint16(i + 1)
+	}
+	return codes
+}
+
+// vp8lApplyCache replaces the literals that hit a color cache of the given size
+// with cache indices.
+func vp8lApplyCache(refs []vp8lToken, argb []uint32, cacheBits int) []vp8lToken {
+	if cacheBits == 0 {
+		return refs
+	}
+	tokens := make([]vp8lToken, len(refs))
+	cache := make([]uint32, 1<<uint(cacheBits))
+	shift := uint(32 - cacheBits)
+	p := 0
+	for i, t := range refs {
+		tokens[i] = t
+		if t.kind == vp8lTokenCopy {
+			for k := 0; k < int(t.value); k++ {
+				c := argb[p+k]
+				cache[(c*vp8lColorCacheMultiplier)>>shift] = c
+			}
+			p += int(t.value)
+			continue
+		}
+		c := argb[p]
+		key := (c * vp8lColorCacheMultiplier) >> shift
+		if cache[key] == c {
+			tokens[i] = vp8lToken{kind: vp8lTokenCache, value: key}
+		} else {
+			cache[key] = c
+		}
+		p++
+	}
+	return tokens
+}
+
+// vp8lHistograms returns the symbol histograms of the five prefix codes
+// (green/length/cache, red, blue, alpha and distance).
+func vp8lHistograms(tokens []vp8lToken, cacheBits int) [5][]uint32 {
+	var h [5][]uint32
+	h[0] = make([]uint32, vp8lNumLiterals+vp8lNumLengthCodes+vp8lCacheSize(cacheBits))
+	h[1] = make([]uint32, vp8lNumLiterals)
+	h[2] = make([]uint32, vp8lNumLiterals)
+	h[3] = make([]uint32, vp8lNumLiterals)
+	h[4] = make([]uint32, vp8lNumDistanceCodes)
+	for _, t := range tokens {
+		switch t.kind {
+		case vp8lTokenLiteral:
+			h[0][t.value>>8&0xff]++
+			h[1][t.value>>16&0xff]++
+			h[2][t.value&0xff]++
+			h[3][t.value>>24]++
+		case vp8lTokenCache:
+			h[0][vp8lNumLiterals+vp8lNumLengthCodes+int(t.value)]++
+		case vp8lTokenCopy:
+			symbol, _, _ := vp8lPrefixEncode(t.value)
+			h[0][vp8lNumLiterals+symbol]++
+			symbol, _, _ = vp8lPrefixEncode(t.dist)
+			h[4][symbol]++
+		}
+	}
+	return h
+}
+
+// vp8lCacheSize returns the number of color cache entries for the given cache bits.
+func vp8lCacheSize(cacheBits int) int {
+	if cacheBits == 0 {
+		return 0
+	}
+	return 1 << uint(cacheBits)
+}
+
+// vp8lEstimateCost estimates the number of bits needed to code the histograms,
+// including a rough approximation of the prefix code headers.
+func vp8lEstimateCost(histograms [5][]uint32) float64 {
+	const headerBitsPerSymbol = 5
+	var cost float64
+	for _, h := range histograms {
+		var total float64
+		for _, c := range h {
+			total += float64(c)
+		}
+		for _, c := range h {
+			if c > 0 {
+				f := float64(c)
+				cost += f*math.Log2(total/f) + headerBitsPerSymbol
+			}
+		}
+	}
+	return cost
+}
+
+// vp8lPrefixEncode splits a backward reference length or distance code
+// into a prefix symbol and extra bits.
+func vp8lPrefixEncode(v uint32) (symbol int, nBits uint, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return int(d), 0, 0
+	}
+	hb := uint(bits.Len32(d) - 1)
+	second := int(d>>(hb-1)) & 1
+	nBits = hb - 1
+	return 2*int(hb) + second, nBits, d & (1<<nBits - 1)
+}
+
+// huffmanCode is a canonical prefix code.
+type huffmanCode struct {
+	// lengths are the code lengths stored in the bitstream.
+	lengths []uint8
+	// codes are the bit-reversed codes, ready to be written LSB first.
+	codes []uint16
+	// nBits are the number of bits written per symbol. A code with
+	// a single symbol is written with zero bits.
+	nBits []uint8
+}
+
+// newHuffmanCode builds a length-limited canonical prefix code for the histogram.
+func newHuffmanCode(histogram []uint32, maxLength int) *huffmanCode {
+	c := &huffmanCode{
+		lengths: huffmanLengths(histogram, maxLength),
+		codes:   make([]uint16, len(histogram)),
+		nBits:   make([]uint8, len(histogram)),
+	}
+
+	var count [vp8lMaxCodeLength + 1]int
+	used := 0
+	for _, l := range c.lengths {
+		if l > 0 {
+			count[l]++
+			used++
+		}
+	}
+	if used <= 1 {
+		return c
+	}
+
+	var next [vp8lMaxCodeLength + 1]int
+	code := 0
+	for l := 1; l <= vp8lMaxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	for s, l := range c.lengths {
+		if l > 0 {
+			c.codes[s] = bits.Reverse16(uint16(next[l])) >> (16 - l)
+			c.nBits[s] = l
+			next[l]++
+		}
+	}
+	return c
+}
+
+// write writes the code of the symbol.
+func (c *huffmanCode) write(w *bitWriter, symbol int) {
+	w.writeBits(uint32(c.codes[symbol]), uint(c.nBits[symbol]))
+}
+
+// huffmanLengths computes the code lengths of a prefix code for the histogram,
+// limited to maxLength bits. The limit is enforced by flattening the histogram
+// until the resulting tree is shallow enough.
+func huffmanLengths(histogram []uint32, maxLength int) []uint8 {
+	lengths := make([]uint8, len(histogram))
+	used := 0
+	for s, c := range histogram {
+		if c > 0 {
+			used++
+			lengths[s] = 1
+		}
+	}
+	if used <= 1 {
+		return lengths
+	}
+	for minCount := uint32(1); ; minCount *= 2 {
+		if huffmanTree(histogram, minCount, lengths) <= maxLength {
+			return lengths
+		}
+	}
+}
+
+// huffmanTree builds a Huffman tree for the histogram, in which every used
+// symbol counts at least minCount, stores the code lengths into lengths
+// and returns the maximum code length.
+func huffmanTree(histogram []uint32, minCount uint32, lengths []uint8) int {
+	type leaf struct {
+		symbol int
+		count  uint32
+	}
+	var leaves []leaf
+	for s, c := range histogram {
+		if c > 0 {
+			if c < minCount {
+				c = minCount
+			}
+			leaves = append(leaves, leaf{symbol: s, count: c})
+		}
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		if leaves[i].count != leaves[j].count {
+			return leaves[i].count < leaves[j].count
+		}
+		return leaves[i].symbol < leaves[j].symbol
+	})
+
+	// Nodes [0, n) are the sorted leaves, nodes [n, 2n-1) are the internal
+	// nodes in the order they are created, which is also sorted by count.
+	n := len(leaves)
+	counts := make([]uint64, 2*n-1)
+	parents := make([]int, 2*n-1)
+	for i, l := range leaves {
+		counts[i] = uint64(l.count)
+	}
+	nextLeaf, nextNode, end := 0, n, n
+	pick := func() int {
+		if nextLeaf < n && (nextNode >= end || counts[nextLeaf] <= counts[nextNode]) {
+			nextLeaf++
+			return nextLeaf - 1
+		}
+		nextNode++
+		return nextNode - 1
+	}
+	for end < 2*n-1 {
+		a := pick()
+		b := pick()
+		counts[end] = counts[a] + counts[b]
+		parents[a] = end
+		parents[b] = end
+		end++
+	}
+
+	depths := make([]int, 2*n-1)
+	for i := 2*n - 3; i >= 0; i-- {
+		depths[i] = depths[parents[i]] + 1
+	}
+	maxDepth := 0
+	for i, l := range leaves {
+		lengths[l.symbol] = uint8(depths[i])
+		if depths[i] > maxDepth {
+			maxDepth = depths[i]
+		}
+	}
+	return maxDepth
+}
+
+// writeHuffmanCode writes the prefix code c to w, using the simple code
+// form when possible.
+func writeHuffmanCode(w *bitWriter, c *huffmanCode) {
+	var symbols [2]int
+	used := 0
+	for s, l := range c.lengths {
+		if l > 0 {
+			if used < 2 {
+				symbols[used] = s
+			}
+			used++
+		}
+	}
+
+	if used == 0 {
+		// An unused code is written as a simple code with the single symbol 0.
+		used = 1
+	}
+	if used <= 2 && symbols[used-1] < vp8lNumLiterals {
+		// Simple code with one or two 8-bit symbols.
+		w.writeBits(1, 1)
+		w.writeBits(uint32(used-1), 1)
+		if symbols[0] <= 1 {
+			w.writeBits(0, 1)
+			w.writeBits(uint32(symbols[0]), 1)
+		} else {
+			w.writeBits(1, 1)
+			w.writeBits(uint32(symbols[0]), 8)
+		}
+		if used == 2 {
+			w.writeBits(uint32(symbols[1]), 8)
+		}
+		return
+	}
+
+	// Normal code: the code lengths are run-length encoded and
+	// compressed with the code length code.
+	tokens := codeLengthTokens(c.lengths)
+	histogram := make([]uint32, vp8lNumCodeLengthCodes)
+	for _, t := range tokens {
+		histogram[t.code]++
+	}
+	clc := newHuffmanCode(histogram, vp8lMaxCodeLengthCodeLength)
+
+	n := vp8lNumCodeLengthCodes
+	for n > 4 && clc.lengths[vp8lCodeLengthCodeOrder[n-1]] == 0 {
+		n--
+	}
+	w.writeBits(0, 1)
+	w.writeBits(uint32(n-4), 4)
+	for i := 0; i < n; i++ {
+		w.writeBits(uint32(clc.lengths[vp8lCodeLengthCodeOrder[i]]), 3)
+	}
+	w.writeBits(0, 1) // the code lengths cover the whole alphabet
+
+	for _, t := range tokens {
+		clc.write(w, int(t.code))
+		switch t.code {
+		case 16:
+			w.writeBits(uint32(t.extra), 2)
+		case 17:
+			w.writeBits(uint32(t.extra), 3)
+		case 18:
+			w.writeBits(uint32(t.extra), 7)
+		}
+	}
+}
+
+// codeLengthToken is a code length or a run of repeated code lengths.
+type codeLengthToken struct {
+	code  uint8
+	extra uint8
+}
+
+// codeLengthTokens run-length encodes the code lengths. Code 16 repeats the
+// previous non-zero length 3-6 times, codes 17 and 18 repeat zero 3-10 and
+// 11-138 times.
+func codeLengthTokens(lengths []uint8) []codeLengthToken {
+	var tokens []codeLengthToken
+	prev := uint8(8)
+	for i := 0; i < len(lengths); {
+		l := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == l {
+			run++
+		}
+		i += run
+
+		if l == 0 {
+			for run >= 11 {
+				r := run
+				if r > 138 {
+					r = 138
+				}
+				tokens = append(tokens, codeLengthToken{code: 18, extra: uint8(r - 11)})
+				run -= r
+			}
+			if run >= 3 {
+				tokens = append(tokens, codeLengthToken{code: 17, extra: uint8(run - 3)})
+				run = 0
+			}
+			for ; run > 0; run-- {
+				tokens = append(tokens, codeLengthToken{code: 0})
+			}
+			continue
+		}
+
+		if l != prev {
+			tokens = append(tokens, codeLengthToken{code: l})
+			prev = l
+			run--
+		}
+		for run >= 3 {
+			r := run
+			if r > 6 {
+				r = 6
+			}
+			tokens = append(tokens, codeLengthToken{code: 16, extra: uint8(r - 3)})
+			run -= r
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, codeLengthToken{code: l})
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,203 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func makeWebPTestImages() map[string]image.Image {
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+
+	noise := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+	rnd.Read(noise.Pix)
+
+	gradient := image.NewNRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{uint8(x * 4), uint8(y * 5), uint8(x + y), 0xff})
+		}
+	}
+
+	palettes := map[string]image.Image{}
+	for _, n := range []int{1, 2, 3, 11, 200} {
+		img := image.NewNRGBA(image.Rect(0, 0, 29, 17))
+		colors := make([]color.NRGBA, n)
+		for i := range colors {
+			colors[i] = color.NRGBA{uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256))}
+		}
+		for y := 0; y < 17; y++ {
+			for x := 0; x < 29; x++ {
+				img.SetNRGBA(x, y, colors[rnd.Intn(n)])
+			}
+		}
+		palettes[fmt.Sprintf("palette %d", n)] = img
+	}
+
+	images := map[string]image.Image{
+		"1x1":      image.NewNRGBA(image.Rect(0, 0, 1, 1)),
+		"noise":    noise,
+		"gradient": gradient,
+		"uniform":  New(300, 200, color.NRGBA{10, 20, 30, 40}),
+		"sub":      gradient.SubImage(image.Rect(5, 7, 40, 31)),
+		"branches": testdataBranchesPNG,
+		"flowers":  testdataFlowersSmallPNG,
+	}
+	for name, img := range palettes {
+		images[name] = img
+	}
+	return images
+}
+
+func TestEncodeWebP(t *testing.T) {
+	t.Parallel()
+
+	for name, img := range makeWebPTestImages() {
+		name, img := name, img
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			want := Clone(img)
+			for effort := 0; effort <= 9; effort++ {
+				buf := &bytes.Buffer{}
+				if err := Encode(buf, img, WEBP, WebPEffort(effort), WebPExact(true)); err != nil {
+					t.Fatalf("effort %d: failed to encode: %v", effort, err)
+				}
+				decoded, err := Decode(buf)
+				if err != nil {
+					t.Fatalf("effort %d: failed to decode: %v", effort, err)
+				}
+				if got := Clone(decoded); !compareNRGBA(got, want, 0) {
+					t.Fatalf("effort %d: decoded image differs from the original", effort)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPInexact(t *testing.T) {
+	t.Parallel()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.Pix = []uint8{
+		0xff, 0x00, 0x00, 0xff,
+		0x00, 0xff, 0x00, 0x00,
+		0x00, 0x00, 0xff, 0x80,
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, img, WEBP); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	decoded, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	want := []uint8{
+		0xff, 0x00, 0x00, 0xff,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0xff, 0x80,
+	}
+	if got := Clone(decoded); !compareBytes(got.Pix, want, 0) {
+		t.Fatalf("got pix %v want %v", got.Pix, want)
+	}
+}
+
+func TestEncodeWebPInvalidSize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		img  image.Image
+	}{
+		{
+			name: "empty",
+			img:  &image.NRGBA{},
+		},
+		{
+			name: "too wide",
+			img:  image.NewGray(image.Rect(0, 0, vp8lMaxDimension+1, 1)),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := Encode(&bytes.Buffer{}, tc.img, WEBP); err == nil {
+				t.Fatal("expected error got nil")
+			}
+		})
+	}
+}
+
+func TestHuffmanLengthsLimit(t *testing.T) {
+	t.Parallel()
+
+	// A Fibonacci histogram produces the deepest possible Huffman tree.
+	histogram := make([]uint32, 40)
+	a, b := uint32(1), uint32(1)
+	for i := range histogram {
+		histogram[i] = a
+		a, b = b, a+b
+	}
+
+	lengths := huffmanLengths(histogram, vp8lMaxCodeLength)
+	var kraft float64
+	for _, l := range lengths {
+		if l == 0 || l > vp8lMaxCodeLength {
+			t.Fatalf("got invalid code length %d", l)
+		}
+		kraft += 1 / float64(uint(1)<<l)
+	}
+	if kraft != 1 {
+		t.Fatalf("got incomplete prefix code (Kraft sum %v)", kraft)
+	}
+}
+
+func TestCodeLengthTokens(t *testing.T) {
+	t.Parallel()
+
+	lengths := []uint8{8, 8, 8, 8, 3, 3, 3, 3, 3, 3, 3, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5, 0, 0}
+	var got []uint8
+	prev := uint8(8)
+	for _, tok := range codeLengthTokens(lengths) {
+		switch tok.code {
+		case 16:
+			for i := 0; i < int(tok.extra)+3; i++ {
+				got = append(got, prev)
+			}
+		case 17:
+			got = append(got, make([]uint8, int(tok.extra)+3)...)
+		case 18:
+			got = append(got, make([]uint8, int(tok.extra)+11)...)
+		default:
+			got = append(got, tok.code)
+			if tok.code != 0 {
+				prev = tok.code
+			}
+		}
+	}
+	if !bytes.Equal(got, lengths) {
+		t.Fatalf("got lengths %v want %v", got, lengths)
+	}
+}
+
+func BenchmarkEncodeWebP(b *testing.B) {
+	for _, effort := range []int{0, 5, 9} {
+		effort := effort
+		b.Run(fmt.Sprintf("effort %d", effort), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := Encode(&bytes.Buffer{}, testdataBranchesPNG, WEBP, WebPEffort(effort)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}